@@ -0,0 +1,459 @@
+package httplib
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownField is used as FieldError.Err when the request has a key that does not match any field.
+	ErrUnknownField = errors.New("unknown field")
+
+	// ErrRequired is used as FieldError.Err when the request does not have a value for a required field.
+	ErrRequired = errors.New("required")
+)
+
+// Sources of the values bound to struct fields, which are also the names of the struct tags.
+const (
+	SourceForm   = "form"
+	SourceQuery  = "query"
+	SourcePath   = "path"
+	SourceHeader = "header"
+	SourceCSV    = "csv"
+)
+
+// FieldError is an error that occurred while binding a request value to a struct field.
+type FieldError struct {
+	// Source is where the value comes from, such as SourceQuery or SourceHeader.
+	Source string
+
+	// Field is the key of the value in the request (e.g. the form field name).
+	Field string
+
+	// Value is the raw value that failed to bind. It is empty if the error is not related to a specific value.
+	Value string
+
+	// Err is the cause of the error.
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	source := e.Source
+	if source == "" {
+		source = "field"
+	}
+
+	if e.Value == "" {
+		return fmt.Sprintf("%s %q: %v", source, e.Field, e.Err)
+	}
+	return fmt.Sprintf("%s %q: invalid value %q: %v", source, e.Field, e.Value, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the FieldError as a JSON object with "source", "field", "value" and "message",
+// so that FieldErrors can be rendered by JSONResponse. Empty "source" and "value" are omitted.
+func (e *FieldError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Source  string `json:"source,omitempty"`
+		Field   string `json:"field"`
+		Value   string `json:"value,omitempty"`
+		Message string `json:"message"`
+	}{
+		Source:  e.Source,
+		Field:   e.Field,
+		Value:   e.Value,
+		Message: e.Err.Error(),
+	})
+}
+
+// FieldErrors is a list of FieldError returned by binders that report every invalid field at once.
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e FieldErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	formFileType        = reflect.TypeFor[*FormFile]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// bindField is a struct field that values are bound to.
+type bindField struct {
+	index        []int
+	key          string
+	layout       string
+	defaultValue string
+	hasDefault   bool
+	required     bool
+	comma        bool
+	file         bool
+}
+
+type bindFieldsCacheKey struct {
+	t   reflect.Type
+	tag string
+}
+
+var bindFieldsCache sync.Map // bindFieldsCacheKey -> []bindField
+
+// getBindFields returns fields of the struct type t that have the given tag.
+//
+// The tag value is the key of the field, optionally followed by comma-separated options:
+//   - required: the request must have a non-empty value for the field
+//   - comma: each value is split by commas before binding to a slice
+//
+// A field tagged with "-" is ignored. Fields of untagged embedded structs are treated as fields of t.
+// The optional "default" tag specifies the value used when the request does not have one,
+// and the optional "layout" tag specifies the layout of time.Time fields, which defaults to time.RFC3339.
+func getBindFields(t reflect.Type, tag string) ([]bindField, error) {
+	key := bindFieldsCacheKey{t: t, tag: tag}
+	if cached, ok := bindFieldsCache.Load(key); ok {
+		return cached.([]bindField), nil
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("httplib: cannot bind to non-struct type %s", t)
+	}
+
+	fields, err := appendBindFields(nil, t, tag, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	bindFieldsCache.Store(key, fields)
+	return fields, nil
+}
+
+func appendBindFields(fields []bindField, t reflect.Type, tag string, parentIndex []int) ([]bindField, error) {
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		value, ok := sf.Tag.Lookup(tag)
+		index := append(slices.Clone(parentIndex), sf.Index...)
+
+		if !ok && sf.Anonymous {
+			embedded := sf.Type
+			if embedded.Kind() == reflect.Pointer && sf.IsExported() {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				var err error
+				if fields, err = appendBindFields(fields, embedded, tag, index); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		if !ok || value == "-" || !sf.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(value, ",")
+		if name == "" {
+			name = sf.Name
+		}
+
+		defaultValue, hasDefault := sf.Tag.Lookup("default")
+
+		field := bindField{
+			index:        index,
+			key:          name,
+			layout:       sf.Tag.Get("layout"),
+			defaultValue: defaultValue,
+			hasDefault:   hasDefault,
+			file:         isFormFileType(sf.Type),
+		}
+
+		for option := range strings.SplitSeq(options, ",") {
+			switch option {
+			case "required":
+				field.required = true
+			case "comma":
+				field.comma = true
+			case "":
+			default:
+				return nil, fmt.Errorf("httplib: unknown option %q of field %s", option, sf.Name)
+			}
+		}
+
+		if !field.file && !isBindableType(sf.Type) {
+			return nil, fmt.Errorf("httplib: unsupported type %s of field %s", sf.Type, sf.Name)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func isFormFileType(t reflect.Type) bool {
+	return t == formFileType || (t.Kind() == reflect.Slice && t.Elem() == formFileType)
+}
+
+func isBindableType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// bindValues binds values to the fields of the struct pointed to by dst.
+//
+// The source is set to FieldError.Source. The used keys are added to used if it is not nil.
+func bindValues(dst reflect.Value, fields []bindField, source string, values map[string][]string, used map[string]struct{}) FieldErrors {
+	var errs FieldErrors
+
+	for _, field := range fields {
+		if field.file {
+			continue
+		}
+
+		raw, ok := values[field.key]
+		if ok && used != nil {
+			used[field.key] = struct{}{}
+		}
+
+		if !hasNonEmptyValue(raw) {
+			switch {
+			case field.hasDefault:
+				raw = []string{field.defaultValue}
+			case field.required:
+				errs = append(errs, &FieldError{Source: source, Field: field.key, Err: ErrRequired})
+				continue
+			case !ok:
+				continue
+			}
+		}
+
+		if field.comma {
+			raw = splitComma(raw)
+		}
+
+		if err := setFieldValues(fieldByIndex(dst, field.index), raw, field.layout); err != nil {
+			errs = append(errs, err.toFieldError(source, field.key))
+		}
+	}
+
+	return errs
+}
+
+func hasNonEmptyValue(values []string) bool {
+	return slices.ContainsFunc(values, func(value string) bool {
+		return value != ""
+	})
+}
+
+func splitComma(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		for part := range strings.SplitSeq(value, ",") {
+			result = append(result, strings.TrimSpace(part))
+		}
+	}
+	return result
+}
+
+// fieldByIndex returns the nested field of v, allocating nil embedded struct pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// valueError is a FieldError without the source and the field name.
+type valueError struct {
+	value string
+	err   error
+}
+
+func (e *valueError) toFieldError(source string, field string) *FieldError {
+	return &FieldError{Source: source, Field: field, Value: e.value, Err: e.err}
+}
+
+func setFieldValues(v reflect.Value, values []string, layout string) *valueError {
+	if v.Kind() != reflect.Slice {
+		if len(values) == 0 {
+			return nil
+		}
+		return setFieldValue(v, values[0], layout)
+	}
+
+	slice := reflect.MakeSlice(v.Type(), 0, len(values))
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := setFieldValue(elem, value, layout); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem)
+	}
+
+	v.Set(slice)
+	return nil
+}
+
+// setFieldValue converts s and sets it to v.
+//
+// An empty string is treated as an absent value except for strings, so v is left unchanged.
+func setFieldValue(v reflect.Value, s string, layout string) *valueError {
+	if s == "" && v.Kind() != reflect.String {
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setFieldValue(ptr.Elem(), s, layout); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	if err := convertValue(v, s, layout); err != nil {
+		return &valueError{value: s, err: err}
+	}
+
+	return nil
+}
+
+func convertValue(v reflect.Value, s string, layout string) error {
+	if v.Type() == timeType {
+		if layout == "" {
+			layout = time.RFC3339
+		}
+
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return fmt.Errorf("expected time in layout %q", layout)
+		}
+
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.New("invalid duration")
+		}
+
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	if v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := parseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return unwrapNumError(err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return unwrapNumError(err)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return unwrapNumError(err)
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// parseBool parses s as strconv.ParseBool does, and also accepts "on" and "off" sent by HTML checkboxes.
+func parseBool(s string) (bool, error) {
+	switch s {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, unwrapNumError(err)
+	}
+	return b, nil
+}
+
+// unwrapNumError returns the cause of strconv.NumError to avoid exposing the function name in the error message.
+func unwrapNumError(err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return numErr.Err
+	}
+	return err
+}
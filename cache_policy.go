@@ -0,0 +1,328 @@
+package httplib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCachePolicy is returned by NewCachePolicy when the directives conflict with each other.
+var ErrInvalidCachePolicy = errors.New("httplib: invalid cache policy")
+
+// CacheOption configures a CachePolicy created by NewCachePolicy.
+type CacheOption func(*CachePolicy)
+
+// CachePolicy is a set of caching headers of a response: Cache-Control, Vary and Expires.
+//
+// A CachePolicy is immutable once created by NewCachePolicy, and can be shared by responses.
+type CachePolicy struct {
+	public               bool
+	private              bool
+	noCache              bool
+	noStore              bool
+	mustRevalidate       bool
+	immutable            bool
+	maxAge               time.Duration
+	sharedMaxAge         time.Duration
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	hasMaxAge            bool
+	hasSharedMaxAge      bool
+	vary                 []string
+	expires              time.Time
+}
+
+// WithCachePublic adds the "public" directive, which allows shared caches to store the response.
+func WithCachePublic() CacheOption {
+	return func(p *CachePolicy) {
+		p.public = true
+	}
+}
+
+// WithCachePrivate adds the "private" directive, which allows only the browser cache to store the response.
+func WithCachePrivate() CacheOption {
+	return func(p *CachePolicy) {
+		p.private = true
+	}
+}
+
+// WithCacheNoCache adds the "no-cache" directive, which requires caches to revalidate the response before reuse.
+func WithCacheNoCache() CacheOption {
+	return func(p *CachePolicy) {
+		p.noCache = true
+	}
+}
+
+// WithCacheNoStore adds the "no-store" directive, which prohibits caches from storing the response.
+func WithCacheNoStore() CacheOption {
+	return func(p *CachePolicy) {
+		p.noStore = true
+	}
+}
+
+// WithCacheMustRevalidate adds the "must-revalidate" directive, which prohibits caches from reusing stale responses.
+func WithCacheMustRevalidate() CacheOption {
+	return func(p *CachePolicy) {
+		p.mustRevalidate = true
+	}
+}
+
+// WithCacheImmutable adds the "immutable" directive, which tells that the response never changes while it is fresh.
+// It requires WithCacheMaxAge.
+func WithCacheImmutable() CacheOption {
+	return func(p *CachePolicy) {
+		p.immutable = true
+	}
+}
+
+// WithCacheMaxAge adds the "max-age" directive. The duration is truncated to seconds.
+func WithCacheMaxAge(maxAge time.Duration) CacheOption {
+	return func(p *CachePolicy) {
+		p.maxAge, p.hasMaxAge = maxAge, true
+	}
+}
+
+// WithCacheSharedMaxAge adds the "s-maxage" directive, which overrides max-age for shared caches.
+// The duration is truncated to seconds.
+func WithCacheSharedMaxAge(maxAge time.Duration) CacheOption {
+	return func(p *CachePolicy) {
+		p.sharedMaxAge, p.hasSharedMaxAge = maxAge, true
+	}
+}
+
+// WithCacheStaleWhileRevalidate adds the "stale-while-revalidate" directive (RFC 5861).
+// The duration is truncated to seconds.
+func WithCacheStaleWhileRevalidate(d time.Duration) CacheOption {
+	return func(p *CachePolicy) {
+		p.staleWhileRevalidate = d
+	}
+}
+
+// WithCacheStaleIfError adds the "stale-if-error" directive (RFC 5861). The duration is truncated to seconds.
+func WithCacheStaleIfError(d time.Duration) CacheOption {
+	return func(p *CachePolicy) {
+		p.staleIfError = d
+	}
+}
+
+// WithCacheVary adds the request header names to the Vary header, which the response depends on.
+func WithCacheVary(fields ...string) CacheOption {
+	return func(p *CachePolicy) {
+		p.vary = append(p.vary, fields...)
+	}
+}
+
+// WithCacheExpires sets the Expires header. Caches prefer max-age to Expires if both are present.
+func WithCacheExpires(expires time.Time) CacheOption {
+	return func(p *CachePolicy) {
+		p.expires = expires
+	}
+}
+
+// NewCachePolicy returns a CachePolicy of the options.
+//
+// If the directives conflict with each other, it returns ErrInvalidCachePolicy:
+//   - "public" and "private" are exclusive
+//   - "no-store" cannot be combined with the directives that allow storing or reusing the response
+//   - "immutable" requires "max-age", and cannot be combined with "no-cache"
+//   - "s-maxage" cannot be combined with "private", which prohibits shared caches
+//   - The durations cannot be negative
+func NewCachePolicy(opts ...CacheOption) (*CachePolicy, error) {
+	p := &CachePolicy{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(p)
+		}
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCachePolicy, err)
+	}
+	return p, nil
+}
+
+// MustNewCachePolicy is like NewCachePolicy but panics if the directives conflict.
+//
+// It simplifies safe initialization of global variables holding cache policies.
+func MustNewCachePolicy(opts ...CacheOption) *CachePolicy {
+	p, err := NewCachePolicy(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *CachePolicy) validate() error {
+	switch {
+	case p.public && p.private:
+		return errors.New("public and private are exclusive")
+	case p.noStore && (p.public || p.hasMaxAge || p.hasSharedMaxAge || p.immutable || p.staleWhileRevalidate != 0 || p.staleIfError != 0 || !p.expires.IsZero()):
+		return errors.New("no-store cannot be combined with the directives for storing the response")
+	case p.immutable && !p.hasMaxAge:
+		return errors.New("immutable requires max-age")
+	case p.immutable && p.noCache:
+		return errors.New("immutable and no-cache are exclusive")
+	case p.private && p.hasSharedMaxAge:
+		return errors.New("s-maxage cannot be combined with private")
+	case p.maxAge < 0 || p.sharedMaxAge < 0 || p.staleWhileRevalidate < 0 || p.staleIfError < 0:
+		return errors.New("durations cannot be negative")
+	}
+	return nil
+}
+
+// String returns the value of the Cache-Control header.
+func (p *CachePolicy) String() string {
+	var directives []string
+	if p.public {
+		directives = append(directives, "public")
+	}
+	if p.private {
+		directives = append(directives, "private")
+	}
+	if p.noCache {
+		directives = append(directives, "no-cache")
+	}
+	if p.noStore {
+		directives = append(directives, "no-store")
+	}
+	if p.hasMaxAge {
+		directives = append(directives, "max-age="+formatDeltaSeconds(p.maxAge))
+	}
+	if p.hasSharedMaxAge {
+		directives = append(directives, "s-maxage="+formatDeltaSeconds(p.sharedMaxAge))
+	}
+	if p.mustRevalidate {
+		directives = append(directives, "must-revalidate")
+	}
+	if p.staleWhileRevalidate != 0 {
+		directives = append(directives, "stale-while-revalidate="+formatDeltaSeconds(p.staleWhileRevalidate))
+	}
+	if p.staleIfError != 0 {
+		directives = append(directives, "stale-if-error="+formatDeltaSeconds(p.staleIfError))
+	}
+	if p.immutable {
+		directives = append(directives, "immutable")
+	}
+	return strings.Join(directives, ", ")
+}
+
+func formatDeltaSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+// Apply sets the Cache-Control and Expires headers, and adds the fields to the Vary header.
+// The Cache-Control header is not set if the policy has no directive.
+func (p *CachePolicy) Apply(header http.Header) {
+	if cacheControl := p.String(); cacheControl != "" {
+		header.Set("Cache-Control", cacheControl)
+	}
+	for _, field := range p.vary {
+		addVary(header, field)
+	}
+	if !p.expires.IsZero() {
+		header.Set("Expires", p.expires.UTC().Format(http.TimeFormat))
+	}
+}
+
+// CachedResponse returns a ResponseBodyRenderer that applies the policy to the headers after RenderHeader of the renderer.
+//
+// The applied Cache-Control header is recorded in ResponseLog.CacheControl.
+func CachedResponse(renderer ResponseBodyRenderer, policy *CachePolicy) ResponseBodyRenderer {
+	return &cachedResponseBodyRenderer{renderer: renderer, policy: policy}
+}
+
+type cachedResponseBodyRenderer struct {
+	renderer ResponseBodyRenderer
+	policy   *CachePolicy
+}
+
+func (r *cachedResponseBodyRenderer) RenderHeader(ctx context.Context, header http.Header) error {
+	if err := r.renderer.RenderHeader(ctx, header); err != nil {
+		return err
+	}
+
+	r.policy.Apply(header)
+	return nil
+}
+
+func (r *cachedResponseBodyRenderer) RenderHead(ctx context.Context) error {
+	return renderHead(ctx, r.renderer)
+}
+
+func (r *cachedResponseBodyRenderer) RenderBody(ctx context.Context, w io.Writer) error {
+	return r.renderer.RenderBody(ctx, w)
+}
+
+func (r *cachedResponseBodyRenderer) bufferedBody() ([]byte, bool) {
+	if buffered, ok := r.renderer.(bufferedBodyRenderer); ok {
+		return buffered.bufferedBody()
+	}
+	return nil, false
+}
+
+func (r *cachedResponseBodyRenderer) TrailerNames() []string {
+	if trailerRenderer, ok := r.renderer.(TrailerResponseBodyRenderer); ok {
+		return trailerRenderer.TrailerNames()
+	}
+	return nil
+}
+
+func (r *cachedResponseBodyRenderer) RenderTrailer(ctx context.Context, trailer http.Header) error {
+	if trailerRenderer, ok := r.renderer.(TrailerResponseBodyRenderer); ok {
+		return trailerRenderer.RenderTrailer(ctx, trailer)
+	}
+	return nil
+}
+
+// CacheMiddleware returns a middleware that applies the policy to the responses of the handler, for example per route.
+//
+// The policy is applied when the status code is written, only if it is less than 400
+// and the handler has not set the Cache-Control header, so that errors are not cached
+// and the handler can override the policy.
+func CacheMiddleware(policy *CachePolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&cacheResponseWriter{ResponseWriter: w, policy: policy}, r)
+		})
+	}
+}
+
+type cacheResponseWriter struct {
+	http.ResponseWriter
+	policy      *CachePolicy
+	wroteHeader bool
+}
+
+func (w *cacheResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader && statusCode >= 200 {
+		w.wroteHeader = true
+		if statusCode < 400 && w.Header().Get("Cache-Control") == "" {
+			w.policy.Apply(w.Header())
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *cacheResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cacheResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *cacheResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,223 @@
+package httplib_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCachePolicy(t *testing.T) {
+	expires := time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("JST", 9*60*60))
+
+	tests := []struct {
+		name         string
+		opts         []httplib.CacheOption
+		wantHeader   http.Header
+		wantErr      bool
+		wantErrMatch string
+	}{
+		{
+			name:       "empty",
+			opts:       nil,
+			wantHeader: http.Header{},
+		},
+		{
+			name: "public with max-age and stale directives",
+			opts: []httplib.CacheOption{
+				httplib.WithCachePublic(),
+				httplib.WithCacheMaxAge(90 * time.Second),
+				httplib.WithCacheSharedMaxAge(10 * time.Minute),
+				httplib.WithCacheStaleWhileRevalidate(30 * time.Second),
+				httplib.WithCacheStaleIfError(time.Hour),
+				nil,
+			},
+			wantHeader: http.Header{
+				"Cache-Control": {"public, max-age=90, s-maxage=600, stale-while-revalidate=30, stale-if-error=3600"},
+			},
+		},
+		{
+			name: "private with max-age=0 and must-revalidate",
+			opts: []httplib.CacheOption{
+				httplib.WithCachePrivate(),
+				httplib.WithCacheMaxAge(0),
+				httplib.WithCacheMustRevalidate(),
+			},
+			wantHeader: http.Header{
+				"Cache-Control": {"private, max-age=0, must-revalidate"},
+			},
+		},
+		{
+			name: "immutable with Vary and Expires",
+			opts: []httplib.CacheOption{
+				httplib.WithCachePublic(),
+				httplib.WithCacheMaxAge(365 * 24 * time.Hour),
+				httplib.WithCacheImmutable(),
+				httplib.WithCacheVary("Accept-Encoding", "Accept-Language"),
+				httplib.WithCacheExpires(expires),
+			},
+			wantHeader: http.Header{
+				"Cache-Control": {"public, max-age=31536000, immutable"},
+				"Vary":          {"Accept-Encoding", "Accept-Language"},
+				"Expires":       {"Wed, 01 Jan 2025 18:04:05 GMT"},
+			},
+		},
+		{
+			name:       "no-store",
+			opts:       []httplib.CacheOption{httplib.WithCacheNoStore(), httplib.WithCachePrivate()},
+			wantHeader: http.Header{"Cache-Control": {"private, no-store"}},
+		},
+		{
+			name:         "public and private",
+			opts:         []httplib.CacheOption{httplib.WithCachePublic(), httplib.WithCachePrivate()},
+			wantErr:      true,
+			wantErrMatch: "public and private",
+		},
+		{
+			name:         "no-store and max-age",
+			opts:         []httplib.CacheOption{httplib.WithCacheNoStore(), httplib.WithCacheMaxAge(time.Minute)},
+			wantErr:      true,
+			wantErrMatch: "no-store",
+		},
+		{
+			name:         "no-store and Expires",
+			opts:         []httplib.CacheOption{httplib.WithCacheNoStore(), httplib.WithCacheExpires(expires)},
+			wantErr:      true,
+			wantErrMatch: "no-store",
+		},
+		{
+			name:         "immutable without max-age",
+			opts:         []httplib.CacheOption{httplib.WithCachePublic(), httplib.WithCacheImmutable()},
+			wantErr:      true,
+			wantErrMatch: "immutable requires max-age",
+		},
+		{
+			name:         "immutable and no-cache",
+			opts:         []httplib.CacheOption{httplib.WithCacheMaxAge(time.Minute), httplib.WithCacheImmutable(), httplib.WithCacheNoCache()},
+			wantErr:      true,
+			wantErrMatch: "immutable and no-cache",
+		},
+		{
+			name:         "private and s-maxage",
+			opts:         []httplib.CacheOption{httplib.WithCachePrivate(), httplib.WithCacheSharedMaxAge(time.Minute)},
+			wantErr:      true,
+			wantErrMatch: "s-maxage",
+		},
+		{
+			name:         "negative duration",
+			opts:         []httplib.CacheOption{httplib.WithCacheMaxAge(-time.Second)},
+			wantErr:      true,
+			wantErrMatch: "negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := httplib.NewCachePolicy(tt.opts...)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, httplib.ErrInvalidCachePolicy)
+				assert.ErrorContains(t, err, tt.wantErrMatch)
+				assert.Nil(t, policy)
+				assert.Panics(t, func() { httplib.MustNewCachePolicy(tt.opts...) })
+				return
+			}
+
+			require.NoError(t, err)
+
+			header := http.Header{}
+			policy.Apply(header)
+			assert.Equal(t, tt.wantHeader, header)
+			assert.Equal(t, tt.wantHeader.Get("Cache-Control"), policy.String())
+		})
+	}
+}
+
+func TestCachePolicy_Apply_MergesVary(t *testing.T) {
+	policy := httplib.MustNewCachePolicy(httplib.WithCacheNoCache(), httplib.WithCacheVary("accept-encoding", "Cookie"))
+
+	header := http.Header{"Vary": {"Accept-Encoding"}}
+	policy.Apply(header)
+
+	assert.Equal(t, []string{"Accept-Encoding", "Cookie"}, header.Values("Vary"))
+}
+
+func TestCachedResponse(t *testing.T) {
+	policy := httplib.MustNewCachePolicy(httplib.WithCachePublic(), httplib.WithCacheMaxAge(time.Minute), httplib.WithCacheVary("Accept"))
+
+	var res httplib.ResponseLog
+	ctx := httplib.WithResponseLogPtr(t.Context(), &res)
+	w := httptest.NewRecorder()
+	err := httplib.RenderOKWithBody(ctx, w, httplib.CachedResponse(httplib.RawResponseWithContentType([]byte("hello"), httplib.ContentTypeTextPlain), policy))
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	assert.Equal(t, "5", w.Header().Get("Content-Length"))
+	assert.Equal(t, "public, max-age=60", res.CacheControl)
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	policy := httplib.MustNewCachePolicy(httplib.WithCachePrivate(), httplib.WithCacheMaxAge(time.Minute), httplib.WithCacheVary("Cookie"))
+
+	tests := []struct {
+		name             string
+		handler          func(r *http.Request, w http.ResponseWriter)
+		wantCacheControl string
+		wantVary         string
+	}{
+		{
+			name: "rendered response",
+			handler: func(r *http.Request, w http.ResponseWriter) {
+				_ = httplib.RenderOKWithBody(r.Context(), w, httplib.RawResponseWithContentType([]byte("ok"), httplib.ContentTypeTextPlain))
+			},
+			wantCacheControl: "private, max-age=60",
+			wantVary:         "Cookie",
+		},
+		{
+			name: "implicit status by Write",
+			handler: func(_ *http.Request, w http.ResponseWriter) {
+				_, _ = w.Write([]byte("ok"))
+			},
+			wantCacheControl: "private, max-age=60",
+			wantVary:         "Cookie",
+		},
+		{
+			name: "Cache-Control set by the handler",
+			handler: func(_ *http.Request, w http.ResponseWriter) {
+				w.Header().Set("Cache-Control", "no-store")
+				w.WriteHeader(http.StatusOK)
+			},
+			wantCacheControl: "no-store",
+		},
+		{
+			name: "error response",
+			handler: func(r *http.Request, w http.ResponseWriter) {
+				httplib.RenderInternalServerError(r.Context(), w, nil)
+			},
+			wantCacheControl: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res httplib.ResponseLog
+			ctx := httplib.WithResponseLogPtr(t.Context(), &res)
+			handler := httplib.CacheMiddleware(policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(r, w)
+			}))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil))
+
+			assert.Equal(t, tt.wantCacheControl, w.Header().Get("Cache-Control"))
+			assert.Equal(t, tt.wantVary, w.Header().Get("Vary"))
+			if res.StatusCode != 0 {
+				assert.Equal(t, tt.wantCacheControl, res.CacheControl)
+			}
+		})
+	}
+}
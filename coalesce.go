@@ -0,0 +1,166 @@
+package httplib
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrCoalescedHandlerPanicked is the cause of 500 Internal Server Error rendered by CoalesceMiddleware
+// to the requests waiting for a handler execution that panicked.
+var ErrCoalescedHandlerPanicked = errors.New("httplib: handler of the coalesced request panicked")
+
+// CoalesceKeyFunc returns the key of the request for CoalesceMiddleware.
+// Requests with the same key share a handler execution. If it returns false, the request is not coalesced.
+type CoalesceKeyFunc func(r *http.Request) (string, bool)
+
+// defaultCoalesceMaxResponseBytes is the default size limit of the response bodies shared by CoalesceMiddleware.
+const defaultCoalesceMaxResponseBytes = 1 << 20
+
+// CoalesceOption configures CoalesceMiddleware.
+type CoalesceOption func(*coalesceConfig)
+
+type coalesceConfig struct {
+	maxResponseBytes int64
+}
+
+// WithCoalesceMaxResponseBytes sets the size limit of the response bodies shared with the waiting requests,
+// which are held in memory until the handler returns. Responses larger than the limit are sent to the first request
+// but not shared, and the waiting requests invoke the handler by themselves. The default is 1 MiB.
+func WithCoalesceMaxResponseBytes(maxBytes int64) CoalesceOption {
+	return func(cfg *coalesceConfig) {
+		cfg.maxResponseBytes = maxBytes
+	}
+}
+
+// CoalesceByURL returns a CoalesceKeyFunc that coalesces requests with the same method, host, URL,
+// and the values of the request headers.
+//
+// The response is shared by the requests regardless of the other headers, such as Authorization and Cookie.
+// Use it only for public resources, or list the headers that the response depends on,
+// so that per-user responses are not leaked to the other users.
+func CoalesceByURL(headers ...string) CoalesceKeyFunc {
+	return func(r *http.Request) (string, bool) {
+		var b strings.Builder
+		b.WriteString(r.Method + " " + r.Host + r.URL.RequestURI() + "\n")
+		for _, name := range headers {
+			b.WriteString(name + ":" + strings.Join(r.Header.Values(name), ",") + "\n")
+		}
+		return b.String(), true
+	}
+}
+
+// CoalesceMiddleware returns a middleware that collapses concurrent GET and HEAD requests of the same key
+// into one execution of the handler, and shares its response with the waiting requests.
+//
+// The key must identify everything that the response depends on, including the principal of per-user responses,
+// since the response is shared by every request of the key. There is no default key for this reason.
+// If key is nil, no request is coalesced. GET and HEAD requests are never coalesced with each other,
+// even if their keys are the same.
+//
+// The first request of a key invokes the handler and receives the response as usual, while the others
+// wait for it and receive a copy of its status code, headers and body rendered by the Render functions of this package.
+// The shared responses are recorded with ResponseLog.Coalesced. If the first request's handler panics,
+// the waiting requests receive 500 Internal Server Error with ErrCoalescedHandlerPanicked.
+// If a waiting request is canceled, it returns without writing a response.
+//
+// Responses with the Set-Cookie header, which belong to the first request, responses to a first request
+// canceled by its client, which may be incomplete, and responses larger than the limit set by
+// WithCoalesceMaxResponseBytes are not shared: the waiting requests invoke the handler by themselves.
+//
+// Unlike ResponseCache, responses are not kept after the handler returns.
+func CoalesceMiddleware(key CoalesceKeyFunc, opts ...CoalesceOption) func(http.Handler) http.Handler {
+	cfg := coalesceConfig{maxResponseBytes: defaultCoalesceMaxResponseBytes}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	var mu sync.Mutex
+	calls := make(map[string]*coalescedCall)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			k, ok := key(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			k = r.Method + " " + k // the responses to HEAD requests have no body to share with GET requests
+			r = r.WithContext(WithRequestMethod(r.Context(), r.Method))
+
+			mu.Lock()
+			if call, ok := calls[k]; ok {
+				mu.Unlock()
+				call.wait(next, w, r)
+				return
+			}
+
+			call := &coalescedCall{done: make(chan struct{})}
+			calls[k] = call
+			mu.Unlock()
+
+			defer func() {
+				mu.Lock()
+				delete(calls, k)
+				mu.Unlock()
+				close(call.done)
+			}()
+
+			cw := &responseCaptureWriter{ResponseWriter: w, maxBytes: cfg.maxResponseBytes}
+			next.ServeHTTP(cw, r)
+
+			cw.finish()
+			call.statusCode, call.header, call.body = cw.statusCode, cw.header, cw.body.Bytes()
+			call.bodyOmitted = r.Method == http.MethodHead
+			call.completed = true
+			call.shared = cw.header.Get("Set-Cookie") == "" && r.Context().Err() == nil && !cw.overflow
+		})
+	}
+}
+
+// coalescedCall is an execution of the handler shared by the requests of a key.
+// The fields are written before done is closed, and read after it.
+type coalescedCall struct {
+	done chan struct{}
+
+	completed   bool // false if the handler panicked
+	shared      bool // false if the response cannot be shared with the waiting requests
+	statusCode  int
+	header      http.Header
+	body        []byte
+	bodyOmitted bool // true if the response is to a HEAD request
+}
+
+func (c *coalescedCall) wait(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		return
+	}
+
+	if !c.completed {
+		RenderInternalServerError(ctx, w, ErrCoalescedHandlerPanicked)
+		return
+	}
+	if !c.shared {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	renderer := &capturedResponseRenderer{header: c.header, body: c.body, bodyOmitted: c.bodyOmitted}
+	// The error can only be the failure of writing the body to the client, which cannot be reported anymore.
+	_ = renderWithBody(ctx, w, c.statusCode, renderer, nil)
+	if resPtr := GetResponseLogPtrFromContext(ctx); resPtr != nil {
+		resPtr.Coalesced = true
+	}
+}
@@ -0,0 +1,337 @@
+package httplib_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingHandler renders the number of the calls after release is closed, or panics if panics is true.
+// If cookie is true, the response has a cookie of the number of the calls.
+type blockingHandler struct {
+	calls   atomic.Int32
+	release chan struct{}
+	panics  bool
+	cookie  bool
+}
+
+func (h *blockingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := h.calls.Add(1)
+	<-h.release
+
+	if h.panics {
+		panic("boom")
+	}
+
+	w.Header().Set("X-Call", strconv.Itoa(int(n)))
+	if h.cookie {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: strconv.Itoa(int(n))})
+	}
+	_ = httplib.RenderOKWithBody(r.Context(), w, httplib.RawResponseWithContentType([]byte("call "+strconv.Itoa(int(n))), httplib.ContentTypeTextPlain))
+}
+
+type coalescedResult struct {
+	w   *httptest.ResponseRecorder
+	res *httplib.ResponseLog
+}
+
+// serveCoalesced serves the requests concurrently, and releases the handler after every request has called the key function.
+func serveCoalesced(t *testing.T, h *blockingHandler, key httplib.CoalesceKeyFunc, requests ...*http.Request) []coalescedResult {
+	t.Helper()
+	return serveCoalescedWithOptions(t, h, key, nil, requests...)
+}
+
+func serveCoalescedWithOptions(t *testing.T, h *blockingHandler, key httplib.CoalesceKeyFunc, opts []httplib.CoalesceOption, requests ...*http.Request) []coalescedResult {
+	t.Helper()
+
+	var keyCalls atomic.Int32
+	handler := httplib.CoalesceMiddleware(func(r *http.Request) (string, bool) {
+		defer keyCalls.Add(1)
+		return key(r)
+	}, opts...)(h)
+
+	results := make([]coalescedResult, len(requests))
+	var wg sync.WaitGroup
+	for i, r := range requests {
+		var res httplib.ResponseLog
+		ctx, _ := newHeadContext(r.Context(), r.Method)
+		r = r.WithContext(httplib.WithResponseLogPtr(ctx, &res))
+
+		results[i] = coalescedResult{w: httptest.NewRecorder(), res: &res}
+		wg.Go(func() {
+			defer func() { _ = recover() }()
+			handler.ServeHTTP(results[i].w, r)
+		})
+	}
+
+	require.Eventually(t, func() bool { return keyCalls.Load() == int32(len(requests)) }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond) // wait for the requests to join the handler execution
+	close(h.release)
+	wg.Wait()
+
+	return results
+}
+
+func TestCoalesceMiddleware(t *testing.T) {
+	h := &blockingHandler{release: make(chan struct{})}
+	results := serveCoalesced(t, h, httplib.CoalesceByURL(),
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+	)
+
+	assert.Equal(t, int32(1), h.calls.Load())
+
+	coalesced := 0
+	for _, result := range results {
+		assert.Equal(t, http.StatusOK, result.w.Code)
+		assert.Equal(t, "call 1", result.w.Body.String())
+		assert.Equal(t, "1", result.w.Header().Get("X-Call"))
+		assert.Equal(t, "text/plain", result.w.Header().Get("Content-Type"))
+		assert.Equal(t, "6", result.w.Header().Get("Content-Length"))
+		assert.Equal(t, http.StatusOK, result.res.StatusCode)
+		assert.Equal(t, int64(6), result.res.ResponseSize)
+		if result.res.Coalesced {
+			coalesced++
+		}
+	}
+	assert.Equal(t, 2, coalesced)
+}
+
+func TestCoalesceMiddleware_Head(t *testing.T) {
+	h := &blockingHandler{release: make(chan struct{})}
+	results := serveCoalesced(t, h, httplib.CoalesceByURL(),
+		httptest.NewRequest(http.MethodHead, "/items", nil),
+		httptest.NewRequest(http.MethodHead, "/items", nil),
+	)
+
+	assert.Equal(t, int32(1), h.calls.Load())
+	for _, result := range results {
+		assert.Equal(t, http.StatusOK, result.w.Code)
+		assert.Empty(t, result.w.Body.String())
+		assert.Equal(t, "6", result.w.Header().Get("Content-Length"))
+		assert.True(t, result.res.BodyOmitted)
+	}
+}
+
+func TestCoalesceMiddleware_NotCoalesced(t *testing.T) {
+	withUser := httptest.NewRequest(http.MethodGet, "/me", nil)
+	withUser.Header.Set("X-User", "alice")
+	withOtherUser := httptest.NewRequest(http.MethodGet, "/me", nil)
+	withOtherUser.Header.Set("X-User", "bob")
+
+	tests := []struct {
+		name     string
+		key      httplib.CoalesceKeyFunc
+		requests []*http.Request
+	}{
+		{
+			name: "different URLs",
+			key:  httplib.CoalesceByURL(),
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/items?page=1", nil),
+				httptest.NewRequest(http.MethodGet, "/items?page=2", nil),
+			},
+		},
+		{
+			name:     "different header values",
+			key:      httplib.CoalesceByURL("X-User"),
+			requests: []*http.Request{withUser, withOtherUser},
+		},
+		{
+			name: "different methods",
+			key:  httplib.CoalesceByURL(),
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/items", nil),
+				httptest.NewRequest(http.MethodHead, "/items", nil),
+			},
+		},
+		{
+			name: "key is not available",
+			key:  func(*http.Request) (string, bool) { return "", false },
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/items", nil),
+				httptest.NewRequest(http.MethodGet, "/items", nil),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &blockingHandler{release: make(chan struct{})}
+			results := serveCoalesced(t, h, tt.key, tt.requests...)
+
+			assert.Equal(t, int32(len(tt.requests)), h.calls.Load())
+			for _, result := range results {
+				assert.False(t, result.res.Coalesced)
+			}
+		})
+	}
+}
+
+func TestCoalesceMiddleware_PassThrough(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    httplib.CoalesceKeyFunc
+		method string
+	}{
+		{name: "POST", key: httplib.CoalesceByURL(), method: http.MethodPost},
+		{name: "nil key", key: nil, method: http.MethodGet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &blockingHandler{release: make(chan struct{})}
+			close(h.release)
+			handler := httplib.CoalesceMiddleware(tt.key)(h)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, "/items", nil))
+			assert.Equal(t, "call 1", w.Body.String())
+		})
+	}
+}
+
+func TestCoalesceMiddleware_Panic(t *testing.T) {
+	h := &blockingHandler{release: make(chan struct{}), panics: true}
+	results := serveCoalesced(t, h, httplib.CoalesceByURL(),
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+	)
+
+	assert.Equal(t, int32(1), h.calls.Load())
+
+	waiters := 0
+	for _, result := range results {
+		if result.res.StatusCode != 0 {
+			waiters++
+			assert.Equal(t, http.StatusInternalServerError, result.w.Code)
+			assert.ErrorIs(t, result.res.Error, httplib.ErrCoalescedHandlerPanicked)
+		}
+	}
+	assert.Equal(t, 1, waiters)
+}
+
+func TestCoalesceMiddleware_WaiterCanceled(t *testing.T) {
+	h := &blockingHandler{release: make(chan struct{})}
+	handler := httplib.CoalesceMiddleware(httplib.CoalesceByURL())(h)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+	}()
+	require.Eventually(t, func() bool { return h.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	var res httplib.ResponseLog
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequestWithContext(httplib.WithResponseLogPtr(ctx, &res), http.MethodGet, "/items", nil))
+	assert.Empty(t, w.Body.String())
+	assert.Zero(t, res.StatusCode)
+
+	close(h.release)
+	<-done
+}
+
+func TestCoalesceMiddleware_SetCookieIsNotShared(t *testing.T) {
+	h := &blockingHandler{release: make(chan struct{}), cookie: true}
+	results := serveCoalesced(t, h, httplib.CoalesceByURL(),
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+	)
+
+	assert.Equal(t, int32(3), h.calls.Load())
+
+	cookies := make(map[string]bool)
+	for _, result := range results {
+		assert.Equal(t, http.StatusOK, result.w.Code)
+		assert.Equal(t, "call "+result.w.Header().Get("X-Call"), result.w.Body.String())
+		assert.Equal(t, "session="+result.w.Header().Get("X-Call"), result.w.Header().Get("Set-Cookie"))
+		assert.False(t, result.res.Coalesced)
+		cookies[result.w.Header().Get("Set-Cookie")] = true
+	}
+	assert.Len(t, cookies, 3)
+}
+
+func TestCoalesceMiddleware_CanceledIsNotShared(t *testing.T) {
+	h := &blockingHandler{release: make(chan struct{})}
+	var keyCalls atomic.Int32
+	handler := httplib.CoalesceMiddleware(func(r *http.Request) (string, bool) {
+		defer keyCalls.Add(1)
+		return "items", true
+	})(h)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequestWithContext(ctx, http.MethodGet, "/items", nil))
+	}()
+	require.Eventually(t, func() bool { return h.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	var res httplib.ResponseLog
+	w := httptest.NewRecorder()
+	waited := make(chan struct{})
+	go func() {
+		defer close(waited)
+		handler.ServeHTTP(w, httptest.NewRequestWithContext(httplib.WithResponseLogPtr(t.Context(), &res), http.MethodGet, "/items", nil))
+	}()
+	require.Eventually(t, func() bool { return keyCalls.Load() == 2 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond) // wait for the request to join the handler execution
+
+	cancel()
+	close(h.release)
+	<-done
+	<-waited
+
+	assert.Equal(t, int32(2), h.calls.Load())
+	assert.Equal(t, "call 2", w.Body.String())
+	assert.False(t, res.Coalesced)
+}
+
+func TestCoalesceMiddleware_TooLargeResponseIsNotShared(t *testing.T) {
+	h := &blockingHandler{release: make(chan struct{})}
+	results := serveCoalescedWithOptions(t, h, httplib.CoalesceByURL(), []httplib.CoalesceOption{httplib.WithCoalesceMaxResponseBytes(5)},
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+	)
+
+	assert.Equal(t, int32(3), h.calls.Load())
+	for _, result := range results {
+		assert.Equal(t, http.StatusOK, result.w.Code)
+		assert.Equal(t, "call "+result.w.Header().Get("X-Call"), result.w.Body.String())
+		assert.False(t, result.res.Coalesced)
+	}
+}
+
+func TestCoalesceMiddleware_MethodsAreNotCoalesced(t *testing.T) {
+	h := &blockingHandler{release: make(chan struct{})}
+	byPath := func(r *http.Request) (string, bool) { return r.URL.Path, true }
+	results := serveCoalesced(t, h, byPath,
+		httptest.NewRequest(http.MethodHead, "/items", nil),
+		httptest.NewRequest(http.MethodGet, "/items", nil),
+	)
+
+	assert.Equal(t, int32(2), h.calls.Load())
+
+	head, get := results[0], results[1]
+	assert.Empty(t, head.w.Body.String())
+	assert.Equal(t, "6", head.w.Header().Get("Content-Length"))
+	assert.False(t, head.res.Coalesced)
+
+	assert.Equal(t, "call "+get.w.Header().Get("X-Call"), get.w.Body.String())
+	assert.Equal(t, "6", get.w.Header().Get("Content-Length"))
+	assert.False(t, get.res.Coalesced)
+}
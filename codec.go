@@ -0,0 +1,129 @@
+package httplib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// Codec encodes and decodes JSON values.
+//
+// Every JSON request decoder and JSONResponse use the Codec stored in the request context by WithCodec,
+// or JSONCodec if the context does not carry one. Implementations must be safe for concurrent use.
+type Codec interface {
+	// Marshal returns the JSON encoding of v without a trailing newline.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal parses the JSON-encoded data and stores the result in the value pointed to by v.
+	Unmarshal(data []byte, v any, opts UnmarshalOptions) error
+	// NewEncoder returns an Encoder that writes JSON values to w.
+	NewEncoder(w io.Writer) Encoder
+	// NewDecoder returns a Decoder that reads JSON values from r.
+	NewDecoder(r io.Reader, opts UnmarshalOptions) Decoder
+}
+
+// UnmarshalOptions are the options given by httplib to Codec.Unmarshal and Codec.NewDecoder for each call.
+type UnmarshalOptions struct {
+	// DisallowUnknownFields reports an error if an object contains a key that does not match any field of the destination.
+	DisallowUnknownFields bool
+}
+
+// Encoder writes JSON values to an output stream.
+type Encoder interface {
+	// Encode writes the JSON encoding of v followed by a newline.
+	Encode(v any) error
+}
+
+// Decoder reads JSON values from an input stream.
+type Decoder interface {
+	// Decode reads the next JSON value and stores it in the value pointed to by v.
+	Decode(v any) error
+	// InputOffset returns the offset in the input stream just after the last decoded value.
+	InputOffset() int64
+}
+
+// JSONCodec is a Codec based on encoding/json.
+//
+// The zero value encodes and decodes values as json.Marshal and json.Unmarshal do, and is the default Codec.
+type JSONCodec struct {
+	// DisableHTMLEscape disables escaping of <, > and & in JSON strings.
+	DisableHTMLEscape bool
+
+	// UseNumber decodes numbers into an interface value as json.Number instead of float64.
+	UseNumber bool
+}
+
+func (c JSONCodec) Marshal(v any) ([]byte, error) {
+	if !c.DisableHTMLEscape {
+		return json.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+	if err := c.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+func (c JSONCodec) Unmarshal(data []byte, v any, opts UnmarshalOptions) error {
+	decoder := c.NewDecoder(bytes.NewReader(data), opts)
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	if len(bytes.TrimSpace(data[decoder.InputOffset():])) != 0 {
+		return ErrTrailingData
+	}
+
+	return nil
+}
+
+func (c JSONCodec) NewEncoder(w io.Writer) Encoder {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(!c.DisableHTMLEscape)
+	return encoder
+}
+
+func (c JSONCodec) NewDecoder(r io.Reader, opts UnmarshalOptions) Decoder {
+	decoder := json.NewDecoder(r)
+	if opts.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if c.UseNumber {
+		decoder.UseNumber()
+	}
+	return decoder
+}
+
+// WithCodec returns a new context that carries the Codec used by JSON request decoders and JSONResponse.
+//
+// To use a Codec for every request of a server without touching handlers, set it to the base context of the server:
+//
+//	server := &http.Server{
+//		BaseContext: func(net.Listener) context.Context {
+//			return httplib.WithCodec(context.Background(), codec)
+//		},
+//	}
+func WithCodec(ctx context.Context, codec Codec) context.Context {
+	return context.WithValue(ctx, contextKeyCodec, codec)
+}
+
+// GetCodecFromContext returns the Codec stored in the context.
+//
+// If the context does not contain a Codec, or the stored value is nil, it returns the zero-value JSONCodec.
+func GetCodecFromContext(ctx context.Context) Codec {
+	codec, ok := ctx.Value(contextKeyCodec).(Codec)
+	if !ok || codec == nil {
+		return JSONCodec{}
+	}
+	return codec
+}
+
+// sameCodec reports whether a and b are the same Codec, without panicking on incomparable implementations.
+func sameCodec(a Codec, b Codec) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
@@ -0,0 +1,63 @@
+//go:build goexperiment.jsonv2
+
+package httplib
+
+import "io"
+
+// JSONv2Codec is a Codec based on encoding/json/v2.
+//
+// It is available only when built with GOEXPERIMENT=jsonv2, which is enabled by default since Go 1.27.
+type JSONv2Codec struct {
+	opts JSONv2Options
+}
+
+// NewJSONv2Codec returns a JSONv2Codec that applies the given options to every call,
+// for example jsonv2.MatchCaseInsensitiveNames(true) or jsonv2.OmitZeroStructFields(true).
+//
+// The unknown member handling is controlled by UnmarshalOptions, so jsonv2.RejectUnknownMembers in opts is overridden.
+func NewJSONv2Codec(opts ...JSONv2Options) *JSONv2Codec {
+	return &JSONv2Codec{opts: jsonv2JoinOptions(opts...)}
+}
+
+func (c *JSONv2Codec) Marshal(v any) ([]byte, error) {
+	return jsonv2Marshal(v, c.opts)
+}
+
+func (c *JSONv2Codec) Unmarshal(data []byte, v any, opts UnmarshalOptions) error {
+	return jsonv2Unmarshal(data, v, c.unmarshalOptions(opts))
+}
+
+func (c *JSONv2Codec) NewEncoder(w io.Writer) Encoder {
+	return &jsonv2Encoder{encoder: jsontextNewEncoder(w, c.opts), opts: c.opts}
+}
+
+func (c *JSONv2Codec) NewDecoder(r io.Reader, opts UnmarshalOptions) Decoder {
+	unmarshalOpts := c.unmarshalOptions(opts)
+	return &jsonv2Decoder{decoder: jsontextNewDecoder(r, unmarshalOpts), opts: unmarshalOpts}
+}
+
+func (c *JSONv2Codec) unmarshalOptions(opts UnmarshalOptions) JSONv2Options {
+	return jsonv2JoinOptions(c.opts, jsonv2RejectUnknownMembers(opts.DisallowUnknownFields))
+}
+
+type jsonv2Encoder struct {
+	encoder *jsontextEncoder
+	opts    JSONv2Options
+}
+
+func (e *jsonv2Encoder) Encode(v any) error {
+	return jsonv2MarshalEncode(e.encoder, v, e.opts)
+}
+
+type jsonv2Decoder struct {
+	decoder *jsontextDecoder
+	opts    JSONv2Options
+}
+
+func (d *jsonv2Decoder) Decode(v any) error {
+	return jsonv2UnmarshalDecode(d.decoder, v, d.opts)
+}
+
+func (d *jsonv2Decoder) InputOffset() int64 {
+	return d.decoder.InputOffset()
+}
@@ -0,0 +1,41 @@
+//go:build goexperiment.jsonv2 && go1.27
+
+//go:generate sh -c "{ echo '// Code generated from codec_jsonv2_std.go by go generate. DO NOT EDIT.'; echo; sed -e '1s/go1.27/!go1.27/' -e '/^\\/\\/go:generate/,+1d' codec_jsonv2_std.go; } > codec_jsonv2_std_go125.go"
+
+package httplib
+
+import (
+	"encoding/json/jsontext"
+	jsonv2 "encoding/json/v2"
+)
+
+// All references to encoding/json/v2 are kept in this file, and codec_jsonv2_std_go125.go is generated from it
+// for the toolchains before Go 1.27. The package is a part of the standard library since Go 1.27,
+// and is provided by GOEXPERIMENT=jsonv2 in earlier releases.
+//
+// The files cannot be merged into one with the "goexperiment.jsonv2 || go1.27" constraint:
+// go vet of Go 1.27 and later reports the references as too new for the go version of the module,
+// unless every branch of the constraint of the file requires go1.27.
+
+// JSONv2Options is an alias of jsonv2.Options, which configures JSONv2Codec.
+type JSONv2Options = jsonv2.Options
+
+type (
+	jsontextEncoder = jsontext.Encoder
+	jsontextDecoder = jsontext.Decoder
+)
+
+var (
+	jsonv2JoinOptions          = jsonv2.JoinOptions
+	jsonv2Marshal              = jsonv2.Marshal
+	jsonv2Unmarshal            = jsonv2.Unmarshal
+	jsonv2MarshalEncode        = jsonv2.MarshalEncode
+	jsonv2UnmarshalDecode      = jsonv2.UnmarshalDecode
+	jsonv2RejectUnknownMembers = jsonv2.RejectUnknownMembers
+	jsontextNewEncoder         = jsontext.NewEncoder
+	jsontextNewDecoder         = jsontext.NewDecoder
+
+	// used by tests
+	jsonv2OmitZeroStructFields      = jsonv2.OmitZeroStructFields
+	jsonv2MatchCaseInsensitiveNames = jsonv2.MatchCaseInsensitiveNames
+)
@@ -0,0 +1,119 @@
+//go:build goexperiment.jsonv2
+
+package httplib_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONv2Codec_Marshal(t *testing.T) {
+	type item struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	codec := httplib.NewJSONv2Codec(httplib.JSONv2OmitZeroStructFields(true))
+
+	got, err := codec.Marshal(item{Name: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"a"}`, string(got))
+
+	var buf bytes.Buffer
+	require.NoError(t, codec.NewEncoder(&buf).Encode(item{Count: 1}))
+	assert.Equal(t, "{\"count\":1}\n", buf.String())
+}
+
+func TestJSONv2Codec_Unmarshal(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name         string
+		codec        *httplib.JSONv2Codec
+		data         string
+		opts         httplib.UnmarshalOptions
+		want         item
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success",
+			codec:        httplib.NewJSONv2Codec(),
+			data:         `{"name":"a"}`,
+			want:         item{Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: case-insensitive names",
+			codec:        httplib.NewJSONv2Codec(httplib.JSONv2MatchCaseInsensitiveNames(true)),
+			data:         `{"NAME":"a"}`,
+			want:         item{Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: unknown members are allowed",
+			codec:        httplib.NewJSONv2Codec(httplib.JSONv2RejectUnknownMembers(true)),
+			data:         `{"name":"a","unknown":1}`,
+			want:         item{Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: unknown members are rejected",
+			codec:        httplib.NewJSONv2Codec(),
+			data:         `{"name":"a","unknown":1}`,
+			opts:         httplib.UnmarshalOptions{DisallowUnknownFields: true},
+			errAssertion: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got item
+			err := tt.codec.Unmarshal([]byte(tt.data), &got, tt.opts)
+			tt.errAssertion(t, err)
+			if err == nil {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJSONv2Codec_DecodeJSONRequestBody(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	ctx := httplib.WithCodec(t.Context(), httplib.NewJSONv2Codec(httplib.JSONv2MatchCaseInsensitiveNames(true)))
+
+	r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", strings.NewReader(`{"Name":"a"}`))
+	got, err := httplib.DecodeJSONRequestBody[item](r)
+	require.NoError(t, err)
+	assert.Equal(t, item{Name: "a"}, got)
+
+	r = httptest.NewRequestWithContext(ctx, http.MethodPost, "/", strings.NewReader("{\"name\":\"a\"} x\n"))
+	_, err = collectItems(httplib.DecodeJSONLines[item](r))
+	assert.ErrorIs(t, err, httplib.ErrTrailingData)
+}
+
+func TestJSONv2Codec_MaxBodySize(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	ctx := httplib.WithCodec(t.Context(), httplib.NewJSONv2Codec())
+
+	r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", strings.NewReader(`{"name":"abcdefghij"}`))
+	_, err := httplib.DecodeJSONRequestBody[item](r, httplib.WithMaxBodySize(10))
+	assertMaxBytesErrorFunc(10)(t, err)
+
+	r = httptest.NewRequestWithContext(ctx, http.MethodPost, "/", strings.NewReader(`[{"name":"abcdefghij"}]`))
+	_, err = collectItems(httplib.DecodeJSONArray[item](r, httplib.WithMaxBodySize(10)))
+	assertMaxBytesErrorFunc(10)(t, err)
+}
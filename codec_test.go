@@ -0,0 +1,250 @@
+package httplib_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type codecItem struct {
+	Name string `json:"name"`
+}
+
+func TestJSONCodec_Marshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		codec    httplib.JSONCodec
+		data     any
+		wantData string
+	}{
+		{
+			name:     "default",
+			codec:    httplib.JSONCodec{},
+			data:     codecItem{Name: "<a&b>"},
+			wantData: `{"name":"\u003ca\u0026b\u003e"}`,
+		},
+		{
+			name:     "disable HTML escape",
+			codec:    httplib.JSONCodec{DisableHTMLEscape: true},
+			data:     codecItem{Name: "<a&b>"},
+			wantData: `{"name":"<a&b>"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.codec.Marshal(tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantData, string(got))
+
+			var buf bytes.Buffer
+			require.NoError(t, tt.codec.NewEncoder(&buf).Encode(tt.data))
+			assert.Equal(t, tt.wantData+"\n", buf.String())
+		})
+	}
+}
+
+func TestJSONCodec_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name         string
+		codec        httplib.JSONCodec
+		data         string
+		opts         httplib.UnmarshalOptions
+		want         any
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success",
+			data:         `{"name":"a","unknown":1}`,
+			want:         map[string]any{"name": "a", "unknown": float64(1)},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: use number",
+			codec:        httplib.JSONCodec{UseNumber: true},
+			data:         `{"name":"a","unknown":1}`,
+			want:         map[string]any{"name": "a", "unknown": json.Number("1")},
+			errAssertion: assert.NoError,
+		},
+		{
+			name: "failure: trailing data",
+			data: `{"name":"a"} {}`,
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, httplib.ErrTrailingData, i...)
+			},
+		},
+		{
+			name:         "failure: invalid JSON",
+			data:         `{"name":`,
+			errAssertion: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got any
+			err := tt.codec.Unmarshal([]byte(tt.data), &got, tt.opts)
+			tt.errAssertion(t, err)
+			if err == nil {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJSONCodec_UnknownFields(t *testing.T) {
+	var got codecItem
+	err := httplib.JSONCodec{}.Unmarshal([]byte(`{"name":"a","unknown":1}`), &got, httplib.UnmarshalOptions{DisallowUnknownFields: true})
+	assert.ErrorContains(t, err, "unknown field")
+
+	require.NoError(t, httplib.JSONCodec{}.Unmarshal([]byte(`{"name":"a","unknown":1}`), &got, httplib.UnmarshalOptions{}))
+	assert.Equal(t, codecItem{Name: "a"}, got)
+}
+
+func TestGetCodecFromContext(t *testing.T) {
+	ctx := t.Context()
+	assert.Equal(t, httplib.JSONCodec{}, httplib.GetCodecFromContext(ctx))
+	assert.Equal(t, httplib.JSONCodec{}, httplib.GetCodecFromContext(httplib.WithCodec(ctx, nil)))
+
+	codec := httplib.JSONCodec{UseNumber: true}
+	assert.Equal(t, codec, httplib.GetCodecFromContext(httplib.WithCodec(ctx, codec)))
+}
+
+// upperCodec is a Codec that upper-cases the JSON documents, to check which Codec is used.
+type upperCodec struct {
+	httplib.JSONCodec
+}
+
+func (c upperCodec) Marshal(v any) ([]byte, error) {
+	data, err := c.JSONCodec.Marshal(v)
+	return bytes.ToUpper(data), err
+}
+
+func (c upperCodec) NewDecoder(r io.Reader, opts httplib.UnmarshalOptions) httplib.Decoder {
+	data, _ := io.ReadAll(r)
+	return c.JSONCodec.NewDecoder(bytes.NewReader(bytes.ToUpper(data)), opts)
+}
+
+func TestCodec_Context(t *testing.T) {
+	type upperItem struct {
+		Name string `json:"NAME"`
+	}
+
+	ctx := httplib.WithCodec(t.Context(), upperCodec{})
+
+	t.Run("DecodeJSONRequestBody", func(t *testing.T) {
+		r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", strings.NewReader(`{"name":"a"}`))
+		got, err := httplib.DecodeJSONRequestBody[upperItem](r)
+		require.NoError(t, err)
+		assert.Equal(t, upperItem{Name: "A"}, got)
+	})
+
+	t.Run("DecodeJSONLines", func(t *testing.T) {
+		r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", strings.NewReader("{\"name\":\"a\"}\n{\"name\":\"b\"}\n"))
+		got, err := collectItems(httplib.DecodeJSONLines[upperItem](r))
+		require.NoError(t, err)
+		assert.Equal(t, []upperItem{{Name: "A"}, {Name: "B"}}, got)
+	})
+
+	t.Run("DecodeJSONArray", func(t *testing.T) {
+		r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", strings.NewReader(`[{"name":"a"},{"name":"b"}]`))
+		got, err := collectItems(httplib.DecodeJSONArray[upperItem](r))
+		require.NoError(t, err)
+		assert.Equal(t, []upperItem{{Name: "A"}, {Name: "B"}}, got)
+	})
+
+	t.Run("JSONResponse", func(t *testing.T) {
+		renderer, err := httplib.JSONResponse(codecItem{Name: "a"})
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+		assert.Equal(t, `{"NAME":"A"}`, w.Body.String())
+		assert.Equal(t, "12", w.Header().Get("Content-Length"))
+	})
+
+	t.Run("JSONResponseWithCodec", func(t *testing.T) {
+		renderer, err := httplib.JSONResponseWithCodec(httplib.JSONCodec{}, codecItem{Name: "a"})
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+		assert.Equal(t, `{"name":"a"}`, w.Body.String())
+	})
+}
+
+// countedValue counts the calls of MarshalJSON.
+type countedValue struct {
+	calls *int
+}
+
+func (v countedValue) MarshalJSON() ([]byte, error) {
+	*v.calls++
+	return []byte(`"value"`), nil
+}
+
+func TestJSONResponse_MarshalOnce(t *testing.T) {
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		calls int
+	}{
+		{name: "default codec", ctx: t.Context(), calls: 1},
+		{name: "same codec in context", ctx: httplib.WithCodec(t.Context(), httplib.JSONCodec{}), calls: 1},
+		{name: "another codec in context", ctx: httplib.WithCodec(t.Context(), upperCodec{}), calls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			renderer, err := httplib.JSONResponse(countedValue{calls: &calls})
+			require.NoError(t, err)
+			assert.Equal(t, 1, calls)
+
+			w := httptest.NewRecorder()
+			require.NoError(t, httplib.RenderOKWithBody(tt.ctx, w, renderer))
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+}
+
+// incomparableCodec is a Codec that cannot be compared by ==.
+type incomparableCodec struct {
+	httplib.JSONCodec
+	_ []string
+}
+
+func TestJSONResponse_IncomparableCodec(t *testing.T) {
+	renderer, err := httplib.JSONResponse(codecItem{Name: "a"})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	ctx := httplib.WithCodec(context.Background(), incomparableCodec{})
+	require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+	assert.Equal(t, `{"name":"a"}`, w.Body.String())
+}
+
+func TestJSONv2StdGenerated(t *testing.T) {
+	src, err := os.ReadFile("codec_jsonv2_std.go")
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile("codec_jsonv2_std_go125.go")
+	require.NoError(t, err)
+
+	// the same as the go:generate directive of codec_jsonv2_std.go
+	lines := strings.Split(string(src), "\n")
+	require.True(t, strings.HasPrefix(lines[2], "//go:generate "))
+	lines = slices.Delete(lines, 2, 4)
+	lines[0] = strings.Replace(lines[0], "go1.27", "!go1.27", 1)
+	expected := "// Code generated from codec_jsonv2_std.go by go generate. DO NOT EDIT.\n\n" + strings.Join(lines, "\n")
+
+	assert.Equal(t, expected, string(generated), "run go generate")
+}
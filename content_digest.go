@@ -0,0 +1,252 @@
+package httplib
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidContentDigest is the error that ContentDigestError matches by errors.Is.
+var ErrInvalidContentDigest = errors.New("httplib: invalid content digest")
+
+// ContentDigestError is returned by DecodeJSONRequestBody with WithContentDigest or WithRequiredContentDigest
+// when the Content-Digest header of the request is missing, malformed, or does not match the body.
+// It should be rendered by RenderBadRequest.
+type ContentDigestError struct {
+	// Algorithm is the algorithm whose digest does not match the body. It is empty for the other reasons.
+	Algorithm string
+
+	// Reason describes why the verification failed.
+	Reason string
+}
+
+func (e *ContentDigestError) Error() string {
+	return ErrInvalidContentDigest.Error() + ": " + e.Reason
+}
+
+func (e *ContentDigestError) Is(target error) bool {
+	return target == ErrInvalidContentDigest
+}
+
+// DigestAlgorithm is a hash algorithm of the digest fields defined in RFC 9530.
+type DigestAlgorithm struct {
+	name    string
+	newHash func() hash.Hash
+}
+
+var (
+	// DigestSHA256 is the "sha-256" algorithm.
+	DigestSHA256 = DigestAlgorithm{name: "sha-256", newHash: sha256.New}
+	// DigestSHA512 is the "sha-512" algorithm.
+	DigestSHA512 = DigestAlgorithm{name: "sha-512", newHash: sha512.New}
+)
+
+// digestAlgorithms are the supported algorithms in the order of preference for verification.
+var digestAlgorithms = []DigestAlgorithm{DigestSHA512, DigestSHA256}
+
+// String returns the name of the algorithm in the digest fields, such as "sha-256".
+func (a DigestAlgorithm) String() string {
+	return a.name
+}
+
+// ContentDigestResponse returns a ResponseBodyRenderer that sends the Content-Digest field (RFC 9530)
+// of the body written by the renderer, computed by the algorithms. If no algorithm is given, DigestSHA256 is used.
+//
+// If the body is already known in RenderHeader, as with RawResponse, JSONResponse and BufferedResponse,
+// the digest is sent as a header. Otherwise, such as with streaming renderers, the digest is computed
+// while the body is written and sent as a trailer (see TrailerResponseBodyRenderer).
+func ContentDigestResponse(renderer ResponseBodyRenderer, algorithms ...DigestAlgorithm) ResponseBodyRenderer {
+	return newDigestResponseBodyRenderer(renderer, "Content-Digest", algorithms)
+}
+
+// ReprDigestResponse returns a ResponseBodyRenderer that sends the Repr-Digest field (RFC 9530)
+// in the same way as ContentDigestResponse.
+//
+// The renderers of this package send the selected representation without content coding or ranges,
+// so the representation digest is computed over the body written by the renderer.
+func ReprDigestResponse(renderer ResponseBodyRenderer, algorithms ...DigestAlgorithm) ResponseBodyRenderer {
+	return newDigestResponseBodyRenderer(renderer, "Repr-Digest", algorithms)
+}
+
+func newDigestResponseBodyRenderer(renderer ResponseBodyRenderer, field string, algorithms []DigestAlgorithm) *digestResponseBodyRenderer {
+	r := &digestResponseBodyRenderer{renderer: renderer, field: field}
+	for _, algorithm := range algorithms {
+		if algorithm.newHash != nil {
+			r.algorithms = append(r.algorithms, algorithm)
+		}
+	}
+	if len(r.algorithms) == 0 {
+		r.algorithms = []DigestAlgorithm{DigestSHA256}
+	}
+	return r
+}
+
+// bufferedBodyRenderer is implemented by renderers whose body is known after RenderHeader.
+type bufferedBodyRenderer interface {
+	bufferedBody() ([]byte, bool)
+}
+
+type digestResponseBodyRenderer struct {
+	renderer   ResponseBodyRenderer
+	field      string
+	algorithms []DigestAlgorithm
+
+	hashes  []hash.Hash // the hashes computed while the body is written, or nil if the digest is sent as a header
+	trailer bool
+}
+
+func (r *digestResponseBodyRenderer) RenderHeader(ctx context.Context, header http.Header) error {
+	if err := r.renderer.RenderHeader(ctx, header); err != nil {
+		return err
+	}
+
+	if buffered, ok := r.renderer.(bufferedBodyRenderer); ok {
+		if body, ok := buffered.bufferedBody(); ok {
+			hashes := r.newHashes()
+			for _, h := range hashes {
+				h.Write(body)
+			}
+			header.Set(r.field, r.formatDigests(hashes))
+			return nil
+		}
+	}
+
+	r.hashes = r.newHashes()
+	r.trailer = true
+	return nil
+}
+
+func (r *digestResponseBodyRenderer) newHashes() []hash.Hash {
+	hashes := make([]hash.Hash, len(r.algorithms))
+	for i, algorithm := range r.algorithms {
+		hashes[i] = algorithm.newHash()
+	}
+	return hashes
+}
+
+// formatDigests formats the digests as a Dictionary Structured Field (RFC 8941) of Byte Sequences.
+func (r *digestResponseBodyRenderer) formatDigests(hashes []hash.Hash) string {
+	digests := make([]string, len(hashes))
+	for i, h := range hashes {
+		digests[i] = r.algorithms[i].name + "=:" + base64.StdEncoding.EncodeToString(h.Sum(nil)) + ":"
+	}
+	return strings.Join(digests, ", ")
+}
+
+func (r *digestResponseBodyRenderer) RenderHead(ctx context.Context) error {
+	return renderHead(ctx, r.renderer)
+}
+
+func (r *digestResponseBodyRenderer) RenderBody(ctx context.Context, w io.Writer) error {
+	if !r.trailer {
+		return r.renderer.RenderBody(ctx, w)
+	}
+
+	writers := make([]io.Writer, len(r.hashes))
+	for i, h := range r.hashes {
+		writers[i] = h
+	}
+	return r.renderer.RenderBody(ctx, &hashWriter{w: w, hash: io.MultiWriter(writers...)})
+}
+
+func (r *digestResponseBodyRenderer) TrailerNames() []string {
+	var names []string
+	if r.trailer {
+		names = append(names, r.field)
+	}
+	if trailerRenderer, ok := r.renderer.(TrailerResponseBodyRenderer); ok {
+		names = append(names, trailerRenderer.TrailerNames()...)
+	}
+	return names
+}
+
+func (r *digestResponseBodyRenderer) RenderTrailer(ctx context.Context, trailer http.Header) error {
+	var err error
+	if trailerRenderer, ok := r.renderer.(TrailerResponseBodyRenderer); ok {
+		err = trailerRenderer.RenderTrailer(ctx, trailer)
+	}
+
+	if r.trailer {
+		trailer.Set(r.field, r.formatDigests(r.hashes))
+	}
+	return err
+}
+
+// parseDigestField parses a digest field value such as "sha-256=:base64:, sha-512=:base64:".
+// Parameters of the members are ignored.
+func parseDigestField(value string) (map[string][]byte, error) {
+	digests := make(map[string][]byte)
+	for _, member := range splitHeaderList(value) {
+		member, _, _ = strings.Cut(member, ";")
+		key, encoded, ok := strings.Cut(strings.TrimSpace(member), "=")
+		if !ok || key == "" || len(encoded) < 2 || encoded[0] != ':' || encoded[len(encoded)-1] != ':' {
+			return nil, fmt.Errorf("malformed member %q", member)
+		}
+
+		digest, err := base64.StdEncoding.DecodeString(encoded[1 : len(encoded)-1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed member %q", member)
+		}
+		digests[strings.ToLower(key)] = digest
+	}
+	return digests, nil
+}
+
+// newContentDigestReader returns a reader that verifies the body against the Content-Digest header of the request
+// when it reaches EOF. The strongest supported algorithm in the header is used, and the others are ignored.
+//
+// If the header is absent or has no supported algorithm, the body is returned as is unless required is true.
+func newContentDigestReader(body io.ReadCloser, header http.Header, required bool) (io.ReadCloser, error) {
+	values := header.Values("Content-Digest")
+	if len(values) == 0 {
+		if required {
+			return nil, &ContentDigestError{Reason: "missing Content-Digest header"}
+		}
+		return body, nil
+	}
+
+	digests, err := parseDigestField(strings.Join(values, ", "))
+	if err != nil {
+		return nil, &ContentDigestError{Reason: "malformed Content-Digest header: " + err.Error()}
+	}
+
+	for _, algorithm := range digestAlgorithms {
+		if expected, ok := digests[algorithm.name]; ok {
+			return &contentDigestReader{r: body, algorithm: algorithm, expected: expected, hash: algorithm.newHash()}, nil
+		}
+	}
+
+	if required {
+		return nil, &ContentDigestError{Reason: "no supported algorithm in Content-Digest header"}
+	}
+	return body, nil
+}
+
+type contentDigestReader struct {
+	r         io.ReadCloser
+	algorithm DigestAlgorithm
+	expected  []byte
+	hash      hash.Hash
+}
+
+func (r *contentDigestReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+
+	if errors.Is(err, io.EOF) && !bytes.Equal(r.hash.Sum(nil), r.expected) {
+		return n, &ContentDigestError{Algorithm: r.algorithm.name, Reason: r.algorithm.name + " digest does not match the body"}
+	}
+	return n, err
+}
+
+func (r *contentDigestReader) Close() error {
+	return r.r.Close()
+}
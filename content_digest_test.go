@@ -0,0 +1,240 @@
+package httplib_test
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha256Digest(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+func sha512Digest(data string) string {
+	sum := sha512.Sum512([]byte(data))
+	return "sha-512=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+func TestContentDigestResponse_Header(t *testing.T) {
+	jsonRenderer, err := httplib.JSONResponse(map[string]int{"hello": 1})
+	require.NoError(t, err)
+
+	negotiated, err := httplib.NegotiatedResponse(httptest.NewRequest(http.MethodGet, "/", nil), httplib.Representation{
+		MediaType: httplib.ContentTypeTextPlain,
+		Renderer: func() (httplib.ResponseBodyRenderer, error) {
+			return httplib.RawResponse([]byte("text")), nil
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		renderer   httplib.ResponseBodyRenderer
+		field      string
+		wantBody   string
+		wantDigest string
+	}{
+		{
+			name:       "raw with default algorithm",
+			renderer:   httplib.ContentDigestResponse(httplib.RawResponse([]byte("hello")), httplib.DigestAlgorithm{}),
+			field:      "Content-Digest",
+			wantBody:   "hello",
+			wantDigest: sha256Digest("hello"),
+		},
+		{
+			name:       "json with both algorithms",
+			renderer:   httplib.ContentDigestResponse(jsonRenderer, httplib.DigestSHA256, httplib.DigestSHA512),
+			field:      "Content-Digest",
+			wantBody:   `{"hello":1}`,
+			wantDigest: sha256Digest(`{"hello":1}`) + ", " + sha512Digest(`{"hello":1}`),
+		},
+		{
+			name:       "buffered streaming renderer",
+			renderer:   httplib.ReprDigestResponse(httplib.BufferedResponse(httplib.CSVResponse(slices.Values([][]string{{"a"}}))), httplib.DigestSHA512),
+			field:      "Repr-Digest",
+			wantBody:   "a\n",
+			wantDigest: sha512Digest("a\n"),
+		},
+		{
+			name:       "negotiated",
+			renderer:   httplib.ContentDigestResponse(negotiated),
+			field:      "Content-Digest",
+			wantBody:   "text",
+			wantDigest: sha256Digest("text"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			require.NoError(t, httplib.RenderOKWithBody(t.Context(), w, tt.renderer))
+
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.wantDigest, w.Header().Get(tt.field))
+			assert.Empty(t, w.Header().Get("Trailer"))
+			assert.Equal(t, strconv.Itoa(len(tt.wantBody)), w.Header().Get("Content-Length"))
+		})
+	}
+}
+
+func TestContentDigestResponse_Trailer(t *testing.T) {
+	body := "a,b\nc,d\n"
+	rows := slices.Values([][]string{{"a", "b"}, {"c", "d"}})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner := &statusTrailerRenderer{ResponseBodyRenderer: httplib.CSVResponse(rows), status: "done"}
+		assert.NoError(t, httplib.RenderOKWithBody(r.Context(), w, httplib.ContentDigestResponse(inner)))
+	}))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, body, string(data))
+	assert.Empty(t, resp.Header.Get("Content-Digest"))
+	assert.Equal(t, sha256Digest(body), resp.Trailer.Get("Content-Digest"))
+	assert.Equal(t, "done", resp.Trailer.Get("X-Status"))
+}
+
+func TestContentDigestResponse_HeaderError(t *testing.T) {
+	renderer := httplib.ContentDigestResponse(errorResponseBodyRenderer{headerErr: assert.AnError})
+
+	w := httptest.NewRecorder()
+	assert.ErrorIs(t, renderer.RenderHeader(t.Context(), w.Header()), assert.AnError)
+	assert.Empty(t, w.Header().Get("Content-Digest"))
+}
+
+func TestDigestAlgorithm_String(t *testing.T) {
+	assert.Equal(t, "sha-256", httplib.DigestSHA256.String())
+	assert.Equal(t, "sha-512", httplib.DigestSHA512.String())
+}
+
+func assertContentDigestErrorFunc(expectedAlgorithm string) assert.ErrorAssertionFunc {
+	return func(t assert.TestingT, err error, i ...interface{}) bool {
+		var digestErr *httplib.ContentDigestError
+		if !assert.ErrorAs(t, err, &digestErr) {
+			return false
+		}
+		return assert.ErrorIs(t, err, httplib.ErrInvalidContentDigest) && assert.Equal(t, expectedAlgorithm, digestErr.Algorithm)
+	}
+}
+
+func TestDecodeJSONRequestBody_ContentDigest(t *testing.T) {
+	const body = `{"id":1,"name":"a"}`
+
+	tests := []struct {
+		name         string
+		digests      []string
+		opts         []httplib.DecodeOption
+		want         streamItem
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: sha-256",
+			digests:      []string{sha256Digest(body)},
+			opts:         []httplib.DecodeOption{httplib.WithContentDigest()},
+			want:         streamItem{ID: 1, Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: sha-512 is preferred over wrong sha-256",
+			digests:      []string{sha256Digest("other") + ", " + sha512Digest(body) + ";param=1"},
+			opts:         []httplib.DecodeOption{httplib.WithRequiredContentDigest()},
+			want:         streamItem{ID: 1, Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: multiple header lines",
+			digests:      []string{"md5=:AAAA:", sha256Digest(body)},
+			opts:         []httplib.DecodeOption{httplib.WithRequiredContentDigest()},
+			want:         streamItem{ID: 1, Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: missing header is optional",
+			opts:         []httplib.DecodeOption{httplib.WithContentDigest()},
+			want:         streamItem{ID: 1, Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: unsupported algorithm is optional",
+			digests:      []string{"md5=:AAAA:"},
+			opts:         []httplib.DecodeOption{httplib.WithContentDigest()},
+			want:         streamItem{ID: 1, Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: not verified without option",
+			digests:      []string{sha256Digest("other")},
+			want:         streamItem{ID: 1, Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: mismatch",
+			digests:      []string{sha256Digest("other")},
+			opts:         []httplib.DecodeOption{httplib.WithContentDigest()},
+			errAssertion: assertContentDigestErrorFunc("sha-256"),
+		},
+		{
+			name:         "failure: missing header",
+			opts:         []httplib.DecodeOption{httplib.WithRequiredContentDigest()},
+			errAssertion: assertContentDigestErrorFunc(""),
+		},
+		{
+			name:         "failure: unsupported algorithm",
+			digests:      []string{"md5=:AAAA:"},
+			opts:         []httplib.DecodeOption{httplib.WithRequiredContentDigest()},
+			errAssertion: assertContentDigestErrorFunc(""),
+		},
+		{
+			name:         "failure: not a byte sequence",
+			digests:      []string{"sha-256=abc"},
+			opts:         []httplib.DecodeOption{httplib.WithContentDigest()},
+			errAssertion: assertContentDigestErrorFunc(""),
+		},
+		{
+			name:         "failure: invalid base64",
+			digests:      []string{"sha-256=:!!!:"},
+			opts:         []httplib.DecodeOption{httplib.WithContentDigest()},
+			errAssertion: assertContentDigestErrorFunc(""),
+		},
+		{
+			name:         "failure: body too large",
+			digests:      []string{sha256Digest(body)},
+			opts:         []httplib.DecodeOption{httplib.WithContentDigest(), httplib.WithMaxBodySize(5)},
+			errAssertion: assertMaxBytesErrorFunc(5),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			for _, digest := range tt.digests {
+				r.Header.Add("Content-Digest", digest)
+			}
+
+			got, err := httplib.DecodeJSONRequestBody[streamItem](r, tt.opts...)
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestContentDigestError_Error(t *testing.T) {
+	err := &httplib.ContentDigestError{Algorithm: "sha-256", Reason: "sha-256 digest does not match the body"}
+	assert.EqualError(t, err, "httplib: invalid content digest: sha-256 digest does not match the body")
+}
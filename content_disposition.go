@@ -0,0 +1,73 @@
+package httplib
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// formatContentDisposition formats a Content-Disposition header value as specified in RFC 6266.
+//
+// If filename contains characters other than printable ASCII, it is encoded in the "filename*" parameter
+// (RFC 8187), and the "filename" parameter has an ASCII fallback where such characters are replaced with '_'.
+// If filename is empty, only the disposition type is returned.
+func formatContentDisposition(dispositionType string, filename string) string {
+	if filename == "" {
+		return dispositionType
+	}
+
+	var b strings.Builder
+	b.WriteString(dispositionType)
+	b.WriteString(`; filename="`)
+
+	ascii := true
+	for _, r := range filename {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			ascii = false
+			b.WriteByte('_')
+		case r >= utf8.RuneSelf:
+			ascii = false
+			b.WriteByte('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+
+	if !ascii {
+		b.WriteString("; filename*=UTF-8''")
+		b.WriteString(encodeRFC8187(filename))
+	}
+
+	return b.String()
+}
+
+// encodeRFC8187 percent-encodes s except for the attr-char of RFC 8187.
+func encodeRFC8187(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isRFC8187AttrChar(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0f])
+	}
+	return b.String()
+}
+
+func isRFC8187AttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	default:
+		return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+	}
+}
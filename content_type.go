@@ -9,12 +9,38 @@ type ContentType = string
 const (
 	// ContentTypeTextPlain is a content type "text/plain"
 	ContentTypeTextPlain ContentType = "text/plain"
+	// ContentTypeTextPlainUTF8 is a content type "text/plain; charset=utf-8"
+	ContentTypeTextPlainUTF8 ContentType = "text/plain; charset=utf-8"
 
 	// ContentTypeJSON is a content type "application/json"
 	ContentTypeJSON ContentType = "application/json"
 	// ContentTypeJSONUTF8 is a content type "application/json; charset=utf-8"
 	ContentTypeJSONUTF8 ContentType = "application/json; charset=utf-8"
 
+	// ContentTypeHTML is a content type "text/html"
+	ContentTypeHTML ContentType = "text/html"
+	// ContentTypeHTMLUTF8 is a content type "text/html; charset=utf-8"
+	ContentTypeHTMLUTF8 ContentType = "text/html; charset=utf-8"
+
+	// ContentTypeXML is a content type "application/xml"
+	ContentTypeXML ContentType = "application/xml"
+	// ContentTypeXMLUTF8 is a content type "application/xml; charset=utf-8"
+	ContentTypeXMLUTF8 ContentType = "application/xml; charset=utf-8"
+	// ContentTypeTextXML is a content type "text/xml"
+	ContentTypeTextXML ContentType = "text/xml"
+	// ContentTypeTextXMLUTF8 is a content type "text/xml; charset=utf-8"
+	ContentTypeTextXMLUTF8 ContentType = "text/xml; charset=utf-8"
+
+	// ContentTypeCSV is a content type "text/csv"
+	ContentTypeCSV ContentType = "text/csv"
+	// ContentTypeCSVUTF8 is a content type "text/csv; charset=utf-8"
+	ContentTypeCSVUTF8 ContentType = "text/csv; charset=utf-8"
+
 	// ContentTypeOctetStream is a content type "application/octet-stream"
 	ContentTypeOctetStream ContentType = "application/octet-stream"
+
+	// ContentTypeFormURLEncoded is a content type "application/x-www-form-urlencoded"
+	ContentTypeFormURLEncoded ContentType = "application/x-www-form-urlencoded"
+	// ContentTypeMultipartFormData is a content type "multipart/form-data"
+	ContentTypeMultipartFormData ContentType = "multipart/form-data"
 )
@@ -11,6 +11,10 @@ const (
 	contextKeyRequestLog contextKey = iota
 	contextKeyResponseLog
 	contextKeyLatency
+	contextKeyCodec
+	contextKeyRenderFallback
+	contextKeySession
+	contextKeyRequestMethod
 )
 
 // GetRequestLogFromContext returns the RequestLog stored in the context.
@@ -33,6 +37,15 @@ func WithRequestLog(ctx context.Context, requestLog RequestLog) context.Context
 	return context.WithValue(ctx, contextKeyRequestLog, &requestLog)
 }
 
+// WithRequestMethod returns a new context that carries the method of the request.
+//
+// The Render functions use it to skip rendering the body of HEAD requests. If the context does not carry the method,
+// RequestLog.Method stored by WithRequestLog is used instead. The handlers and middlewares of this package,
+// such as NewStaticHandler and SessionManager.Middleware, store the method by themselves.
+func WithRequestMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, contextKeyRequestMethod, method)
+}
+
 // GetResponseLogPtrFromContext returns the ResponseLog pointer stored in the context.
 //
 // If the context does not contain a response log, or the stored value is nil,
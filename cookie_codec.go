@@ -0,0 +1,279 @@
+package httplib
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrCookieTampered is the error that CookieError matches by errors.Is when the cookie value is malformed,
+	// or is not signed or encrypted by any key of the CookieCodec.
+	ErrCookieTampered = errors.New("httplib: cookie is tampered")
+
+	// ErrCookieExpired is the error that CookieError matches by errors.Is when the expiry embedded in the cookie value has passed.
+	ErrCookieExpired = errors.New("httplib: cookie is expired")
+
+	// ErrCookieTooLarge is returned by CookieCodec.SetCookie when the encoded cookie exceeds the size that browsers accept.
+	ErrCookieTooLarge = errors.New("httplib: cookie is too large")
+)
+
+const (
+	// minCookieSigningKeySize is the minimum size of the keys of NewSignedCookieCodec, which is the size of HMAC-SHA256.
+	minCookieSigningKeySize = 32
+	// maxCookieSize is the maximum size of a cookie that browsers accept, including its name.
+	maxCookieSize = 4096
+	// cookieExpirySize is the size of the expiry embedded in the cookie value, in Unix seconds.
+	cookieExpirySize = 8
+)
+
+// CookieError is returned by CookieCodec.Decode and CookieCodec.ReadCookie when the cookie cannot be trusted.
+// It should be rendered by RenderUnauthorized.
+type CookieError struct {
+	// Name is the name of the cookie.
+	Name string
+
+	// Err is ErrCookieTampered or ErrCookieExpired.
+	Err error
+}
+
+func (e *CookieError) Error() string {
+	return fmt.Sprintf("%s: %q", e.Err.Error(), e.Name)
+}
+
+func (e *CookieError) Unwrap() error {
+	return e.Err
+}
+
+// CookieOption configures the cookie set by CookieCodec.SetCookie and CookieCodec.DeleteCookie.
+type CookieOption func(*http.Cookie)
+
+// WithCookiePath sets the Path attribute of the cookie. The default is "/".
+func WithCookiePath(path string) CookieOption {
+	return func(cookie *http.Cookie) {
+		cookie.Path = path
+	}
+}
+
+// WithCookieDomain sets the Domain attribute of the cookie. By default, the cookie is sent only to the host that set it.
+func WithCookieDomain(domain string) CookieOption {
+	return func(cookie *http.Cookie) {
+		cookie.Domain = domain
+	}
+}
+
+// WithCookieMaxAge sets the Max-Age attribute of the cookie, and embeds the same expiry in the cookie value,
+// so that the value is rejected after the expiry even if the client keeps the cookie.
+// By default, the cookie is a session cookie without expiry.
+func WithCookieMaxAge(maxAge time.Duration) CookieOption {
+	return func(cookie *http.Cookie) {
+		cookie.MaxAge = int(maxAge / time.Second)
+	}
+}
+
+// WithCookieSameSite sets the SameSite attribute of the cookie. The default is http.SameSiteLaxMode.
+func WithCookieSameSite(sameSite http.SameSite) CookieOption {
+	return func(cookie *http.Cookie) {
+		cookie.SameSite = sameSite
+	}
+}
+
+// WithCookieInsecure removes the Secure attribute of the cookie, so that it is sent over plain HTTP.
+//
+// This is intended for development on http://localhost, and should not be used in production.
+func WithCookieInsecure() CookieOption {
+	return func(cookie *http.Cookie) {
+		cookie.Secure = false
+	}
+}
+
+// CookieCodec signs or encrypts cookie values, so that clients cannot forge or read them.
+//
+// The value is encoded with the first key, and decoded with any of the keys, so that keys can be rotated
+// by adding a new key at the front and removing the old one after the cookies encoded with it have expired.
+// The name of the cookie is bound to the value, so that a value cannot be moved to another cookie.
+//
+// CookieCodec is safe for concurrent use.
+type CookieCodec struct {
+	sign    []hmacKey
+	encrypt []cipher.AEAD
+	now     func() time.Time
+}
+
+type hmacKey []byte
+
+// NewSignedCookieCodec returns a CookieCodec that signs the values with HMAC-SHA256.
+// The values are readable by clients. Each key must be at least 32 bytes of random data.
+func NewSignedCookieCodec(keys ...[]byte) (*CookieCodec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("httplib: no cookie signing key")
+	}
+
+	c := &CookieCodec{now: time.Now}
+	for _, key := range keys {
+		if len(key) < minCookieSigningKeySize {
+			return nil, fmt.Errorf("httplib: cookie signing key must be at least %d bytes", minCookieSigningKeySize)
+		}
+		c.sign = append(c.sign, hmacKey(key))
+	}
+	return c, nil
+}
+
+// NewEncryptedCookieCodec returns a CookieCodec that encrypts the values with AES-GCM,
+// which also prevents clients from reading them. Each key must be 16, 24 or 32 bytes of random data
+// to select AES-128, AES-192 or AES-256.
+func NewEncryptedCookieCodec(keys ...[]byte) (*CookieCodec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("httplib: no cookie encryption key")
+	}
+
+	c := &CookieCodec{now: time.Now}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("httplib: invalid cookie encryption key: %w", err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		c.encrypt = append(c.encrypt, aead)
+	}
+	return c, nil
+}
+
+// Encode returns the cookie value of the name that contains the value and the expiry.
+// If expiresAt is zero, the value never expires.
+func (c *CookieCodec) Encode(name string, value []byte, expiresAt time.Time) (string, error) {
+	payload := make([]byte, cookieExpirySize, cookieExpirySize+len(value))
+	if !expiresAt.IsZero() {
+		binary.BigEndian.PutUint64(payload, uint64(expiresAt.Unix()))
+	}
+	payload = append(payload, value...)
+
+	if len(c.encrypt) != 0 {
+		aead := c.encrypt[0]
+		nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(payload)+aead.Overhead())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, payload, []byte(name))), nil
+	}
+
+	return base64.RawURLEncoding.EncodeToString(append(payload, c.sign[0].mac(name, payload)...)), nil
+}
+
+func (k hmacKey) mac(name string, payload []byte) []byte {
+	h := hmac.New(sha256.New, k)
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// Decode returns the value in the cookie value of the name encoded by Encode.
+//
+// If the cookie value is not encoded by any key, it returns CookieError with ErrCookieTampered.
+// If the expiry has passed, it returns CookieError with ErrCookieExpired.
+func (c *CookieCodec) Decode(name, encoded string) ([]byte, error) {
+	payload, ok := c.open(name, encoded)
+	if !ok || len(payload) < cookieExpirySize {
+		return nil, &CookieError{Name: name, Err: ErrCookieTampered}
+	}
+
+	if expiry := int64(binary.BigEndian.Uint64(payload)); expiry != 0 && !c.now().Before(time.Unix(expiry, 0)) {
+		return nil, &CookieError{Name: name, Err: ErrCookieExpired}
+	}
+	return payload[cookieExpirySize:], nil
+}
+
+func (c *CookieCodec) open(name, encoded string) ([]byte, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, aead := range c.encrypt {
+		if len(data) < aead.NonceSize() {
+			return nil, false
+		}
+		if payload, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(name)); err == nil {
+			return payload, true
+		}
+	}
+
+	if len(data) < sha256.Size {
+		return nil, false
+	}
+	payload, mac := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	for _, key := range c.sign {
+		if hmac.Equal(key.mac(name, payload), mac) {
+			return payload, true
+		}
+	}
+	return nil, false
+}
+
+// SetCookie adds the Set-Cookie header of the encoded value to the response.
+//
+// The cookie has Path=/, HttpOnly, Secure and SameSite=Lax by default, which can be changed by the options.
+// If the encoded cookie exceeds 4096 bytes, it returns ErrCookieTooLarge without setting the cookie.
+func (c *CookieCodec) SetCookie(w http.ResponseWriter, name string, value []byte, opts ...CookieOption) error {
+	cookie := newCookie(name, opts)
+
+	var expiresAt time.Time
+	if cookie.MaxAge > 0 {
+		expiresAt = c.now().Add(time.Duration(cookie.MaxAge) * time.Second)
+	}
+
+	encoded, err := c.Encode(name, value, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	cookie.Value = encoded
+	if len(cookie.Name)+len(cookie.Value) > maxCookieSize {
+		return fmt.Errorf("%w: %q is %d bytes", ErrCookieTooLarge, name, len(cookie.Name)+len(cookie.Value))
+	}
+
+	http.SetCookie(w, cookie)
+	return nil
+}
+
+// ReadCookie returns the value of the cookie of the name in the request, decoded by Decode.
+//
+// If the cookie is not present, it returns http.ErrNoCookie.
+func (c *CookieCodec) ReadCookie(r *http.Request, name string) ([]byte, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decode(name, cookie.Value)
+}
+
+// DeleteCookie adds the Set-Cookie header that deletes the cookie of the name.
+// The options must give the same Path and Domain as SetCookie.
+func (c *CookieCodec) DeleteCookie(w http.ResponseWriter, name string, opts ...CookieOption) {
+	cookie := newCookie(name, opts)
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+}
+
+func newCookie(name string, opts []CookieOption) *http.Cookie {
+	cookie := &http.Cookie{Name: name, Path: "/", HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cookie)
+		}
+	}
+	return cookie
+}
@@ -0,0 +1,266 @@
+package httplib_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testSigningKey       = bytes.Repeat([]byte{1}, 32)
+	testOldSigningKey    = bytes.Repeat([]byte{2}, 32)
+	testEncryptionKey    = bytes.Repeat([]byte{3}, 32)
+	testOldEncryptionKey = bytes.Repeat([]byte{4}, 16)
+)
+
+func TestNewCookieCodec_InvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func() (*httplib.CookieCodec, error)
+	}{
+		{name: "signed without keys", new: func() (*httplib.CookieCodec, error) { return httplib.NewSignedCookieCodec() }},
+		{name: "signed with short key", new: func() (*httplib.CookieCodec, error) {
+			return httplib.NewSignedCookieCodec(testSigningKey, make([]byte, 31))
+		}},
+		{name: "encrypted without keys", new: func() (*httplib.CookieCodec, error) { return httplib.NewEncryptedCookieCodec() }},
+		{name: "encrypted with invalid key size", new: func() (*httplib.CookieCodec, error) {
+			return httplib.NewEncryptedCookieCodec(make([]byte, 20))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, err := tt.new()
+			assert.Error(t, err)
+			assert.Nil(t, codec)
+		})
+	}
+}
+
+func TestCookieCodec_EncodeDecode(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		codec    func(t *testing.T, keys ...[]byte) *httplib.CookieCodec
+		key      []byte
+		oldKey   []byte
+		readable bool
+	}{
+		{
+			name: "signed",
+			codec: func(t *testing.T, keys ...[]byte) *httplib.CookieCodec {
+				codec, err := httplib.NewSignedCookieCodec(keys...)
+				require.NoError(t, err)
+				return codec
+			},
+			key:      testSigningKey,
+			oldKey:   testOldSigningKey,
+			readable: true,
+		},
+		{
+			name: "encrypted",
+			codec: func(t *testing.T, keys ...[]byte) *httplib.CookieCodec {
+				codec, err := httplib.NewEncryptedCookieCodec(keys...)
+				require.NoError(t, err)
+				return codec
+			},
+			key:    testEncryptionKey,
+			oldKey: testOldEncryptionKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec := tt.codec(t, tt.key)
+			httplib.SetCookieCodecClock(codec, func() time.Time { return now })
+
+			encoded, err := codec.Encode("session", []byte("user=alice"), now.Add(time.Hour))
+			require.NoError(t, err)
+
+			raw, err := base64.RawURLEncoding.DecodeString(encoded)
+			require.NoError(t, err)
+			assert.Equal(t, tt.readable, bytes.Contains(raw, []byte("user=alice")))
+
+			value, err := codec.Decode("session", encoded)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("user=alice"), value)
+
+			t.Run("never expires", func(t *testing.T) {
+				encoded, err := codec.Encode("session", []byte("user=alice"), time.Time{})
+				require.NoError(t, err)
+
+				httplib.SetCookieCodecClock(codec, func() time.Time { return now.AddDate(100, 0, 0) })
+				t.Cleanup(func() { httplib.SetCookieCodecClock(codec, func() time.Time { return now }) })
+
+				value, err := codec.Decode("session", encoded)
+				require.NoError(t, err)
+				assert.Equal(t, []byte("user=alice"), value)
+			})
+
+			t.Run("expired", func(t *testing.T) {
+				encoded, err := codec.Encode("session", []byte("user=alice"), now)
+				require.NoError(t, err)
+
+				value, err := codec.Decode("session", encoded)
+				assert.ErrorIs(t, err, httplib.ErrCookieExpired)
+				assert.Nil(t, value)
+
+				var cookieErr *httplib.CookieError
+				require.ErrorAs(t, err, &cookieErr)
+				assert.Equal(t, "session", cookieErr.Name)
+			})
+
+			t.Run("key rotation", func(t *testing.T) {
+				oldCodec := tt.codec(t, tt.oldKey)
+				encoded, err := oldCodec.Encode("session", []byte("user=bob"), time.Time{})
+				require.NoError(t, err)
+
+				_, err = codec.Decode("session", encoded)
+				assert.ErrorIs(t, err, httplib.ErrCookieTampered)
+
+				rotated := tt.codec(t, tt.key, tt.oldKey)
+				value, err := rotated.Decode("session", encoded)
+				require.NoError(t, err)
+				assert.Equal(t, []byte("user=bob"), value)
+
+				reencoded, err := rotated.Encode("session", value, time.Time{})
+				require.NoError(t, err)
+				value, err = codec.Decode("session", reencoded)
+				require.NoError(t, err)
+				assert.Equal(t, []byte("user=bob"), value)
+			})
+
+			tampered := []struct {
+				name    string
+				cookie  string
+				encoded string
+			}{
+				{name: "another cookie name", cookie: "other", encoded: encoded},
+				{name: "modified", cookie: "session", encoded: flipLastByte(t, encoded)},
+				{name: "truncated", cookie: "session", encoded: encoded[:10]},
+				{name: "not base64", cookie: "session", encoded: "!!!"},
+				{name: "empty", cookie: "session", encoded: ""},
+			}
+			for _, tc := range tampered {
+				t.Run(tc.name, func(t *testing.T) {
+					value, err := codec.Decode(tc.cookie, tc.encoded)
+					assert.ErrorIs(t, err, httplib.ErrCookieTampered)
+					assert.Nil(t, value)
+				})
+			}
+		})
+	}
+}
+
+func flipLastByte(t *testing.T, encoded string) string {
+	t.Helper()
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	raw[len(raw)-1] ^= 1
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func TestCookieCodec_SetCookie(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	codec, err := httplib.NewEncryptedCookieCodec(testEncryptionKey)
+	require.NoError(t, err)
+	httplib.SetCookieCodecClock(codec, func() time.Time { return now })
+
+	t.Run("defaults", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		require.NoError(t, codec.SetCookie(w, "session", []byte("user=alice")))
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "session", cookies[0].Name)
+		assert.Equal(t, "/", cookies[0].Path)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.True(t, cookies[0].Secure)
+		assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+		assert.Zero(t, cookies[0].MaxAge)
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookies[0])
+		value, err := codec.ReadCookie(r, "session")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("user=alice"), value)
+	})
+
+	t.Run("options", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		require.NoError(t, codec.SetCookie(w, "session", []byte("user=alice"),
+			httplib.WithCookiePath("/app"),
+			httplib.WithCookieDomain("example.com"),
+			httplib.WithCookieMaxAge(time.Hour),
+			httplib.WithCookieSameSite(http.SameSiteStrictMode),
+			httplib.WithCookieInsecure(),
+			nil,
+		))
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "/app", cookies[0].Path)
+		assert.Equal(t, "example.com", cookies[0].Domain)
+		assert.Equal(t, 3600, cookies[0].MaxAge)
+		assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+		assert.False(t, cookies[0].Secure)
+
+		value, err := codec.Decode("session", cookies[0].Value)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("user=alice"), value)
+
+		// The expiry embedded in the value is the same as Max-Age.
+		httplib.SetCookieCodecClock(codec, func() time.Time { return now.Add(time.Hour) })
+		t.Cleanup(func() { httplib.SetCookieCodecClock(codec, func() time.Time { return now }) })
+
+		_, err = codec.Decode("session", cookies[0].Value)
+		assert.ErrorIs(t, err, httplib.ErrCookieExpired)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		err := codec.SetCookie(w, "session", []byte(strings.Repeat("a", 4096)))
+		assert.ErrorIs(t, err, httplib.ErrCookieTooLarge)
+		assert.Empty(t, w.Header().Values("Set-Cookie"))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		codec.DeleteCookie(w, "session", httplib.WithCookiePath("/app"))
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "/app", cookies[0].Path)
+		assert.Equal(t, -1, cookies[0].MaxAge)
+		assert.Empty(t, cookies[0].Value)
+	})
+}
+
+func TestCookieCodec_ReadCookie(t *testing.T) {
+	codec, err := httplib.NewSignedCookieCodec(testSigningKey)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err = codec.ReadCookie(r, "session")
+	assert.ErrorIs(t, err, http.ErrNoCookie)
+
+	r.AddCookie(&http.Cookie{Name: "session", Value: "forged"})
+	_, err = codec.ReadCookie(r, "session")
+	assert.ErrorIs(t, err, httplib.ErrCookieTampered)
+	assert.Equal(t, `httplib: cookie is tampered: "session"`, err.Error())
+
+	var res httplib.ResponseLog
+	ctx := httplib.WithResponseLogPtr(t.Context(), &res)
+	w := httptest.NewRecorder()
+	httplib.RenderUnauthorized(ctx, w, err)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.ErrorIs(t, res.Error, httplib.ErrCookieTampered)
+}
@@ -0,0 +1,9 @@
+//go:build goexperiment.jsonv2
+
+package httplib
+
+var (
+	JSONv2OmitZeroStructFields      = jsonv2OmitZeroStructFields
+	JSONv2MatchCaseInsensitiveNames = jsonv2MatchCaseInsensitiveNames
+	JSONv2RejectUnknownMembers      = jsonv2RejectUnknownMembers
+)
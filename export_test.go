@@ -2,6 +2,7 @@ package httplib
 
 import (
 	"net/http"
+	"time"
 )
 
 const (
@@ -25,3 +26,29 @@ func NewResponseBodyWriter(w http.ResponseWriter) *ResponseBodyWriter {
 func (w *ResponseBodyWriter) ResponseSize() int64 {
 	return w.responseSize
 }
+
+func SetResponseCacheClock(c *ResponseCache, now func() time.Time) {
+	c.now = now
+}
+
+func SetMemoryIdempotencyStoreClock(s *MemoryIdempotencyStore, now func() time.Time) {
+	s.now = now
+}
+
+func SetCookieCodecClock(c *CookieCodec, now func() time.Time) {
+	c.now = now
+}
+
+func SetSessionManagerClock(m *SessionManager, now func() time.Time) {
+	m.now = now
+}
+
+func HashSessionID(id string) string {
+	return hashSessionID(id)
+}
+
+func SetMemorySessionStoreClock(s *MemorySessionStore, now func() time.Time) {
+	s.now = now
+}
+
+const MaxJSONSchemaCacheEntries = maxJSONSchemaCacheEntries
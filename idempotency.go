@@ -0,0 +1,327 @@
+package httplib
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrIdempotencyKeyInFlight is the cause of 409 Conflict rendered by IdempotencyMiddleware
+	// when a request with the same Idempotency-Key is being processed.
+	ErrIdempotencyKeyInFlight = errors.New("httplib: request with the same idempotency key is in flight")
+
+	// ErrIdempotencyKeyReused is the cause of 422 Unprocessable Entity rendered by IdempotencyMiddleware
+	// when the Idempotency-Key is reused for a request with a different method, URL or body.
+	ErrIdempotencyKeyReused = errors.New("httplib: idempotency key is reused with a different request")
+
+	// ErrInvalidIdempotencyKey is the cause of 400 Bad Request rendered by IdempotencyMiddleware
+	// when the Idempotency-Key header is longer than 255 characters.
+	ErrInvalidIdempotencyKey = errors.New("httplib: invalid idempotency key")
+)
+
+// maxIdempotencyKeyLength is the maximum length of the Idempotency-Key header.
+const maxIdempotencyKeyLength = 255
+
+// defaultIdempotencyMaxResponseBytes is the default size limit of the response bodies stored by IdempotencyMiddleware.
+const defaultIdempotencyMaxResponseBytes = 1 << 20
+
+// IdempotentResponse is the response stored by IdempotencyMiddleware to be replayed.
+type IdempotentResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// IdempotencyRecord is the state of an Idempotency-Key in IdempotencyStore.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the method, the URL and the body of the request that acquired the key.
+	Fingerprint string
+
+	// Response is the stored response, or nil if the request is still in flight.
+	Response *IdempotentResponse
+}
+
+// IdempotencyStore stores the responses of IdempotencyMiddleware. It must be safe for concurrent use.
+//
+// The keys given to the store already include the principal of the request given to IdempotencyMiddleware.
+// Implementations backed by a shared storage, such as Redis or a database, allow replaying responses across servers.
+type IdempotencyStore interface {
+	// Acquire locks the key for the request of the fingerprint if the key is not stored, and returns nil.
+	// Otherwise, it returns the stored record without locking, whose Response is nil if the key is locked.
+	Acquire(ctx context.Context, key, fingerprint string) (*IdempotencyRecord, error)
+
+	// Complete stores the response of the locked key and unlocks it.
+	Complete(ctx context.Context, key string, response *IdempotentResponse) error
+
+	// Release unlocks the key without storing a response, so that the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyOption configures IdempotencyMiddleware.
+type IdempotencyOption func(*idempotencyConfig)
+
+type idempotencyConfig struct {
+	maxBodySize      int64
+	maxResponseBytes int64
+}
+
+// WithIdempotencyMaxBodySize sets the maximum size of the request body read to compute its fingerprint.
+// The default is DefaultMaxRequestBodySize.
+func WithIdempotencyMaxBodySize(size int64) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.maxBodySize = size
+	}
+}
+
+// WithIdempotencyMaxResponseBytes sets the size limit of the response bodies stored in the store.
+// Responses larger than the limit are sent to the client but not stored, and the key is released,
+// so that the retries invoke the handler again. The default is 1 MiB.
+func WithIdempotencyMaxResponseBytes(maxBytes int64) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.maxResponseBytes = maxBytes
+	}
+}
+
+// IdempotencyMiddleware returns a middleware that makes POST and PATCH requests with the Idempotency-Key header
+// idempotent, so that clients can retry them safely.
+//
+// The first response of each key is stored in the store and replayed to the retries with the same method,
+// URL and body, with the "Idempotent-Replayed: true" header, without invoking the handler.
+// Responses with status code 5xx, and responses larger than the limit set by WithIdempotencyMaxResponseBytes,
+// are not stored, so that the request can be retried. If the handler panics, the key is released.
+//
+// The principal returns the principal of the request, such as the authenticated user ID, and the responses are stored
+// by the Idempotency-Key, the principal and the fingerprint of the request, so that a client cannot replay
+// the responses of another client by its Idempotency-Key. Requests whose principal is empty, such as unauthenticated
+// ones, are passed to the handler without storing the responses, since their keys would be shared by the clients.
+// It panics if the principal is nil.
+//
+// The middleware renders:
+//   - 409 Conflict with ErrIdempotencyKeyInFlight while the first request of the key is being processed
+//   - 422 Unprocessable Entity with ErrIdempotencyKeyReused if the key is reused for a different request
+//   - 400 Bad Request with ErrInvalidIdempotencyKey if the key is longer than 255 characters
+//   - 413 Request Entity Too Large with http.MaxBytesError if the body exceeds the size set by WithIdempotencyMaxBodySize
+//
+// Requests without the header, and requests with the other methods, are passed to the handler as they are.
+// Replayed responses are recorded with ResponseLog.IdempotencyReplayed.
+func IdempotencyMiddleware(store IdempotencyStore, principal func(r *http.Request) string, opts ...IdempotencyOption) func(http.Handler) http.Handler {
+	if principal == nil {
+		panic("principal cannot be nil")
+	}
+
+	cfg := idempotencyConfig{maxBodySize: DefaultMaxRequestBodySize, maxResponseBytes: defaultIdempotencyMaxResponseBytes}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			idempotencyKey := r.Header.Get("Idempotency-Key")
+			if (r.Method != http.MethodPost && r.Method != http.MethodPatch) || idempotencyKey == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(idempotencyKey) > maxIdempotencyKeyLength {
+				RenderBadRequest(ctx, w, ErrInvalidIdempotencyKey)
+				return
+			}
+
+			user := principal(r)
+			if user == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fingerprint, err := fingerprintRequest(r, cfg.maxBodySize)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					renderStatusCode(ctx, w, http.StatusRequestEntityTooLarge, err)
+				} else {
+					RenderBadRequest(ctx, w, err)
+				}
+				return
+			}
+
+			key := user + "\x00" + idempotencyKey
+			record, err := store.Acquire(ctx, key, fingerprint)
+			switch {
+			case err != nil:
+				RenderInternalServerError(ctx, w, err)
+				return
+			case record == nil:
+				serveIdempotent(next, w, r, store, key, cfg.maxResponseBytes)
+			case record.Fingerprint != fingerprint:
+				RenderUnprocessableEntity(ctx, w, ErrIdempotencyKeyReused)
+			case record.Response == nil:
+				RenderConflict(ctx, w, ErrIdempotencyKeyInFlight)
+			default:
+				// The error can only be the failure of writing the body to the client, which cannot be reported anymore.
+				_ = renderWithBody(ctx, w, record.Response.StatusCode, &replayedResponseRenderer{response: record.Response}, nil)
+				if resPtr := GetResponseLogPtrFromContext(ctx); resPtr != nil {
+					resPtr.IdempotencyReplayed = true
+				}
+			}
+		})
+	}
+}
+
+// fingerprintRequest returns the SHA-256 hash of the method, the URL and the body of the request.
+// The body is read and replaced with a reader of the same content.
+func fingerprintRequest(r *http.Request, maxBodySize int64) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+		_ = r.Body.Close()
+		if err != nil {
+			return "", err
+		}
+
+		h.Write(body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// serveIdempotent invokes the handler with the locked key, and stores the response.
+func serveIdempotent(next http.Handler, w http.ResponseWriter, r *http.Request, store IdempotencyStore, key string, maxBytes int64) {
+	ctx := r.Context()
+
+	completed := false
+	defer func() {
+		if !completed {
+			_ = store.Release(context.WithoutCancel(ctx), key)
+		}
+	}()
+
+	cw := &responseCaptureWriter{ResponseWriter: w, maxBytes: maxBytes}
+	next.ServeHTTP(cw, r)
+
+	cw.finish()
+	if cw.statusCode >= http.StatusInternalServerError || cw.overflow {
+		return
+	}
+
+	err := store.Complete(context.WithoutCancel(ctx), key, &IdempotentResponse{StatusCode: cw.statusCode, Header: cw.header, Body: cw.body.Bytes()})
+	if err != nil {
+		if resPtr := GetResponseLogPtrFromContext(ctx); resPtr != nil {
+			resPtr.Error = errors.Join(resPtr.Error, err)
+		}
+		return
+	}
+	completed = true
+}
+
+// replayedResponseRenderer renders a stored response with the Idempotent-Replayed header.
+type replayedResponseRenderer struct {
+	response *IdempotentResponse
+}
+
+func (r *replayedResponseRenderer) RenderHeader(_ context.Context, header http.Header) error {
+	maps.Copy(header, r.response.Header.Clone())
+	header.Set("Idempotent-Replayed", "true")
+	if header.Get("Content-Length") == "" && r.response.StatusCode != http.StatusNoContent {
+		header.Set("Content-Length", strconv.Itoa(len(r.response.Body)))
+	}
+	return nil
+}
+
+func (r *replayedResponseRenderer) RenderHead(_ context.Context) error {
+	return nil
+}
+
+func (r *replayedResponseRenderer) RenderBody(_ context.Context, w io.Writer) error {
+	_, err := w.Write(r.response.Body)
+	return err
+}
+
+func (r *replayedResponseRenderer) bufferedBody() ([]byte, bool) {
+	return r.response.Body, true
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore that keeps the records in memory for a period.
+//
+// The records are not shared by servers, and are lost when the server restarts.
+type MemoryIdempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	records   map[string]*memoryIdempotencyRecord
+	lastSweep time.Time
+}
+
+type memoryIdempotencyRecord struct {
+	IdempotencyRecord
+	expiresAt time.Time
+}
+
+// NewMemoryIdempotencyStore returns a MemoryIdempotencyStore that keeps the records for ttl after they are acquired,
+// or completed. Locked keys are also released after ttl, in case the request never completes.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{ttl: ttl, now: time.Now, records: make(map[string]*memoryIdempotencyRecord)}
+}
+
+func (s *MemoryIdempotencyStore) Acquire(_ context.Context, key, fingerprint string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= s.ttl {
+		for k, record := range s.records {
+			if !now.Before(record.expiresAt) {
+				delete(s.records, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if record, ok := s.records[key]; ok && now.Before(record.expiresAt) {
+		copied := record.IdempotencyRecord
+		return &copied, nil
+	}
+
+	s.records[key] = &memoryIdempotencyRecord{IdempotencyRecord: IdempotencyRecord{Fingerprint: fingerprint}, expiresAt: now.Add(s.ttl)}
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, key string, response *IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return errors.New("httplib: idempotency key is not acquired")
+	}
+
+	record.Response = response
+	record.expiresAt = s.now().Add(s.ttl)
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[key]; ok && record.Response == nil {
+		delete(s.records, key)
+	}
+	return nil
+}
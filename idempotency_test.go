@@ -0,0 +1,387 @@
+package httplib_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderHandler creates an order numbered by the calls, and echoes the request body.
+type orderHandler struct {
+	calls      atomic.Int32
+	statusCode int
+	block      chan struct{}
+}
+
+func (h *orderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := h.calls.Add(1)
+	if h.block != nil {
+		<-h.block
+	}
+
+	if h.statusCode != 0 {
+		httplib.RenderInternalServerError(r.Context(), w, nil)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Location", "/orders/"+strconv.Itoa(int(n)))
+	_ = httplib.RenderCreatedWithBody(r.Context(), w, httplib.RawResponseWithContentType([]byte("order "+strconv.Itoa(int(n))+": "+string(body)), httplib.ContentTypeTextPlain))
+}
+
+// userPrincipal is the principal of the tests, where all requests are sent by the same user.
+func userPrincipal(*http.Request) string {
+	return "user"
+}
+
+func serveIdempotent(t *testing.T, handler http.Handler, method, target, key, body string, header http.Header) (*httptest.ResponseRecorder, *httplib.ResponseLog) {
+	t.Helper()
+
+	var res httplib.ResponseLog
+	ctx := httplib.WithResponseLogPtr(t.Context(), &res)
+	r := httptest.NewRequestWithContext(ctx, method, target, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set("Idempotency-Key", key)
+	}
+	for name, values := range header {
+		r.Header[name] = values
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w, &res
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	h := &orderHandler{}
+	handler := httplib.IdempotencyMiddleware(httplib.NewMemoryIdempotencyStore(time.Hour), userPrincipal)(h)
+
+	w, res := serveIdempotent(t, handler, http.MethodPost, "/orders", "key-1", `{"item":"apple"}`, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `order 1: {"item":"apple"}`, w.Body.String())
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	assert.False(t, res.IdempotencyReplayed)
+
+	w, res = serveIdempotent(t, handler, http.MethodPost, "/orders", "key-1", `{"item":"apple"}`, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `order 1: {"item":"apple"}`, w.Body.String())
+	assert.Equal(t, "/orders/1", w.Header().Get("Location"))
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+	assert.True(t, res.IdempotencyReplayed)
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, int64(len(`order 1: {"item":"apple"}`)), res.ResponseSize)
+
+	w, _ = serveIdempotent(t, handler, http.MethodPost, "/orders", "key-2", `{"item":"apple"}`, nil)
+	assert.Equal(t, `order 2: {"item":"apple"}`, w.Body.String())
+
+	w, _ = serveIdempotent(t, handler, http.MethodPost, "/orders", "", `{"item":"apple"}`, nil)
+	assert.Equal(t, `order 3: {"item":"apple"}`, w.Body.String())
+
+	assert.Equal(t, int32(3), h.calls.Load())
+}
+
+func TestIdempotencyMiddleware_ReplayEmptyBody(t *testing.T) {
+	tests := []struct {
+		name              string
+		statusCode        int
+		wantContentLength string
+	}{
+		{
+			name:              "ok",
+			statusCode:        http.StatusOK,
+			wantContentLength: "0",
+		},
+		{
+			name:              "no content",
+			statusCode:        http.StatusNoContent,
+			wantContentLength: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := httplib.IdempotencyMiddleware(httplib.NewMemoryIdempotencyStore(time.Hour), userPrincipal)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			}))
+
+			serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+			w, res := serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Empty(t, w.Body.String())
+			assert.Equal(t, tt.wantContentLength, w.Header().Get("Content-Length"))
+			assert.True(t, res.IdempotencyReplayed)
+		})
+	}
+}
+
+func TestIdempotencyMiddleware_Reused(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{name: "different body", method: http.MethodPost, target: "/orders", body: `{"item":"banana"}`},
+		{name: "different URL", method: http.MethodPost, target: "/orders?dry_run=true", body: `{"item":"apple"}`},
+		{name: "different method", method: http.MethodPatch, target: "/orders", body: `{"item":"apple"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &orderHandler{}
+			handler := httplib.IdempotencyMiddleware(httplib.NewMemoryIdempotencyStore(time.Hour), userPrincipal)(h)
+
+			serveIdempotent(t, handler, http.MethodPost, "/orders", "key", `{"item":"apple"}`, nil)
+
+			w, res := serveIdempotent(t, handler, tt.method, tt.target, "key", tt.body, nil)
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			assert.ErrorIs(t, res.Error, httplib.ErrIdempotencyKeyReused)
+			assert.Equal(t, int32(1), h.calls.Load())
+		})
+	}
+}
+
+func TestIdempotencyMiddleware_InFlight(t *testing.T) {
+	h := &orderHandler{block: make(chan struct{})}
+	handler := httplib.IdempotencyMiddleware(httplib.NewMemoryIdempotencyStore(time.Hour), userPrincipal)(h)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w, _ := serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+		done <- w
+	}()
+	require.Eventually(t, func() bool { return h.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	w, res := serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.ErrorIs(t, res.Error, httplib.ErrIdempotencyKeyInFlight)
+
+	w, res = serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "other body", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.ErrorIs(t, res.Error, httplib.ErrIdempotencyKeyReused)
+
+	close(h.block)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+
+	w, _ = serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotencyMiddleware_ServerErrorIsNotStored(t *testing.T) {
+	h := &orderHandler{statusCode: http.StatusInternalServerError}
+	handler := httplib.IdempotencyMiddleware(httplib.NewMemoryIdempotencyStore(time.Hour), userPrincipal)(h)
+
+	for range 2 {
+		w, _ := serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	}
+	assert.Equal(t, int32(2), h.calls.Load())
+}
+
+func TestIdempotencyMiddleware_TooLargeResponseIsNotStored(t *testing.T) {
+	h := &orderHandler{}
+	handler := httplib.IdempotencyMiddleware(httplib.NewMemoryIdempotencyStore(time.Hour), userPrincipal, httplib.WithIdempotencyMaxResponseBytes(10))(h)
+
+	for i := range 2 {
+		w, _ := serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "order "+strconv.Itoa(i+1)+": body", w.Body.String())
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	}
+	assert.Equal(t, int32(2), h.calls.Load())
+}
+
+func TestIdempotencyMiddleware_PanicReleasesKey(t *testing.T) {
+	panics := true
+	h := &orderHandler{}
+	handler := httplib.IdempotencyMiddleware(httplib.NewMemoryIdempotencyStore(time.Hour), userPrincipal)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if panics {
+			panic("boom")
+		}
+		h.ServeHTTP(w, r)
+	}))
+
+	assert.Panics(t, func() {
+		serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+	})
+
+	panics = false
+	w, _ := serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotencyMiddleware_Principal(t *testing.T) {
+	h := &orderHandler{}
+	handler := httplib.IdempotencyMiddleware(
+		httplib.NewMemoryIdempotencyStore(time.Hour),
+		func(r *http.Request) string { return r.Header.Get("X-User") },
+	)(h)
+
+	w, _ := serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", http.Header{"X-User": {"alice"}})
+	assert.Equal(t, "order 1: body", w.Body.String())
+
+	w, _ = serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", http.Header{"X-User": {"bob"}})
+	assert.Equal(t, "order 2: body", w.Body.String())
+
+	w, _ = serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", http.Header{"X-User": {"alice"}})
+	assert.Equal(t, "order 1: body", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotencyMiddleware_EmptyPrincipal(t *testing.T) {
+	h := &orderHandler{}
+	handler := httplib.IdempotencyMiddleware(httplib.NewMemoryIdempotencyStore(time.Hour), func(*http.Request) string { return "" })(h)
+
+	for i := range 2 {
+		w, _ := serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+		assert.Equal(t, "order "+strconv.Itoa(i+1)+": body", w.Body.String())
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	}
+}
+
+func TestIdempotencyMiddleware_NilPrincipal(t *testing.T) {
+	assert.Panics(t, func() {
+		httplib.IdempotencyMiddleware(httplib.NewMemoryIdempotencyStore(time.Hour), nil)
+	})
+}
+
+func TestIdempotencyMiddleware_PassThrough(t *testing.T) {
+	h := &orderHandler{}
+	handler := httplib.IdempotencyMiddleware(httplib.NewMemoryIdempotencyStore(time.Hour), userPrincipal)(h)
+
+	for range 2 {
+		serveIdempotent(t, handler, http.MethodPut, "/orders", "key", "body", nil)
+	}
+	assert.Equal(t, int32(2), h.calls.Load())
+}
+
+func TestIdempotencyMiddleware_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		body       string
+		opts       []httplib.IdempotencyOption
+		wantStatus int
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{
+			name:       "too long key",
+			key:        strings.Repeat("k", 256),
+			wantStatus: http.StatusBadRequest,
+			wantErr: func(t assert.TestingT, err error, _ ...any) bool {
+				return assert.ErrorIs(t, err, httplib.ErrInvalidIdempotencyKey)
+			},
+		},
+		{
+			name:       "too large body",
+			key:        "key",
+			body:       "0123456789",
+			opts:       []httplib.IdempotencyOption{httplib.WithIdempotencyMaxBodySize(5), nil},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantErr:    assertMaxBytesErrorFunc(5),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &orderHandler{}
+			handler := httplib.IdempotencyMiddleware(httplib.NewMemoryIdempotencyStore(time.Hour), userPrincipal, tt.opts...)(h)
+
+			w, res := serveIdempotent(t, handler, http.MethodPost, "/orders", tt.key, tt.body, nil)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			tt.wantErr(t, res.Error)
+			assert.Equal(t, int32(0), h.calls.Load())
+		})
+	}
+}
+
+type errorIdempotencyStore struct {
+	httplib.IdempotencyStore
+	acquireErr  error
+	completeErr error
+}
+
+func (s *errorIdempotencyStore) Acquire(ctx context.Context, key, fingerprint string) (*httplib.IdempotencyRecord, error) {
+	if s.acquireErr != nil {
+		return nil, s.acquireErr
+	}
+	return s.IdempotencyStore.Acquire(ctx, key, fingerprint)
+}
+
+func (s *errorIdempotencyStore) Complete(ctx context.Context, key string, response *httplib.IdempotentResponse) error {
+	if s.completeErr != nil {
+		return s.completeErr
+	}
+	return s.IdempotencyStore.Complete(ctx, key, response)
+}
+
+func TestIdempotencyMiddleware_StoreError(t *testing.T) {
+	acquireErr := errors.New("acquire error")
+	completeErr := errors.New("complete error")
+
+	t.Run("acquire", func(t *testing.T) {
+		store := &errorIdempotencyStore{IdempotencyStore: httplib.NewMemoryIdempotencyStore(time.Hour), acquireErr: acquireErr}
+		handler := httplib.IdempotencyMiddleware(store, userPrincipal)(&orderHandler{})
+
+		w, res := serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.ErrorIs(t, res.Error, acquireErr)
+	})
+
+	t.Run("complete", func(t *testing.T) {
+		store := &errorIdempotencyStore{IdempotencyStore: httplib.NewMemoryIdempotencyStore(time.Hour), completeErr: completeErr}
+		h := &orderHandler{}
+		handler := httplib.IdempotencyMiddleware(store, userPrincipal)(h)
+
+		w, res := serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.ErrorIs(t, res.Error, completeErr)
+
+		// The key is released, so that the request can be retried.
+		w, _ = serveIdempotent(t, handler, http.MethodPost, "/orders", "key", "body", nil)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, int32(2), h.calls.Load())
+	})
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := t.Context()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := httplib.NewMemoryIdempotencyStore(time.Minute)
+	httplib.SetMemoryIdempotencyStoreClock(store, func() time.Time { return now })
+
+	record, err := store.Acquire(ctx, "key", "fp")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+
+	record, err = store.Acquire(ctx, "key", "fp")
+	require.NoError(t, err)
+	assert.Equal(t, &httplib.IdempotencyRecord{Fingerprint: "fp"}, record)
+
+	response := &httplib.IdempotentResponse{StatusCode: http.StatusOK, Body: []byte("ok")}
+	require.NoError(t, store.Complete(ctx, "key", response))
+	require.NoError(t, store.Release(ctx, "key")) // completed keys are not released
+
+	record, err = store.Acquire(ctx, "key", "fp")
+	require.NoError(t, err)
+	assert.Equal(t, &httplib.IdempotencyRecord{Fingerprint: "fp", Response: response}, record)
+
+	now = now.Add(time.Minute)
+
+	record, err = store.Acquire(ctx, "key", "other")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+
+	assert.Error(t, store.Complete(ctx, "unknown", response))
+}
@@ -0,0 +1,740 @@
+package httplib
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONSchema is a compiled JSON Schema used to validate JSON documents.
+//
+// It supports the following subset of the JSON Schema draft 2020-12 vocabulary:
+//   - type, enum, const
+//   - properties, required, additionalProperties, minProperties, maxProperties
+//   - items, minItems, maxItems
+//   - minimum, maximum, exclusiveMinimum, exclusiveMaximum
+//   - minLength, maxLength, pattern
+//   - format (email, uri, uuid, date-time, date, ipv4 and ipv6; other formats are ignored)
+//   - allOf, anyOf, oneOf
+//   - $ref to a JSON Pointer in the same document (e.g. "#/$defs/address"), and $defs
+//
+// Boolean schemas (true and false) are also supported. Other keywords are ignored.
+// Patterns are evaluated with the regexp package, so they use RE2 syntax.
+//
+// A JSONSchema is safe for concurrent use by multiple goroutines.
+type JSONSchema struct {
+	root *schemaNode
+}
+
+// maxJSONSchemaCacheEntries is the maximum number of compiled schemas cached by CompileJSONSchema.
+const maxJSONSchemaCacheEntries = 128
+
+var jsonSchemaCache = &schemaCache{entries: make(map[string]*list.Element), lru: list.New()}
+
+// CompileJSONSchema compiles the given JSON Schema document.
+//
+// Compiled schemas are cached by their content, so compiling the same document again returns the same JSONSchema.
+// The cache keeps up to 128 schemas, and the least recently used one is evicted when it is full.
+// Keep the returned JSONSchema to avoid compiling it again, such as in a package-level variable.
+func CompileJSONSchema(data []byte) (*JSONSchema, error) {
+	key := string(data)
+	if cached := jsonSchemaCache.get(key); cached != nil {
+		return cached, nil
+	}
+
+	doc, err := decodeJSONValue(data)
+	if err != nil {
+		return nil, fmt.Errorf("httplib: invalid JSON Schema: %w", err)
+	}
+
+	c := &schemaCompiler{doc: doc, nodes: make(map[string]*schemaNode)}
+	root, err := c.compile(doc, "")
+	if err != nil {
+		return nil, fmt.Errorf("httplib: invalid JSON Schema: %w", err)
+	}
+
+	return jsonSchemaCache.add(key, &JSONSchema{root: root}), nil
+}
+
+// schemaCache is the cache of compiled schemas with the size limit of maxJSONSchemaCacheEntries.
+type schemaCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // of *schemaCacheEntry, the most recently used first
+}
+
+type schemaCacheEntry struct {
+	key    string
+	schema *JSONSchema
+}
+
+func (c *schemaCache) get(key string) *JSONSchema {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(element)
+	return element.Value.(*schemaCacheEntry).schema
+}
+
+// add stores the schema unless the key is already stored by another goroutine, and returns the stored one.
+func (c *schemaCache) add(key string, schema *JSONSchema) *JSONSchema {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		return element.Value.(*schemaCacheEntry).schema
+	}
+
+	c.entries[key] = c.lru.PushFront(&schemaCacheEntry{key: key, schema: schema})
+	for c.lru.Len() > maxJSONSchemaCacheEntries {
+		oldest := c.lru.Remove(c.lru.Back()).(*schemaCacheEntry)
+		delete(c.entries, oldest.key)
+	}
+	return schema
+}
+
+// MustCompileJSONSchema is like CompileJSONSchema but panics if the schema cannot be compiled.
+//
+// It simplifies safe initialization of global variables holding compiled schemas.
+func MustCompileJSONSchema(data []byte) *JSONSchema {
+	schema, err := CompileJSONSchema(data)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// ValidateJSON validates the JSON document against the schema.
+//
+// Every violation is reported together as ValidationErrors, whose Path is the JSON Pointer to the invalid value
+// and Rule is the keyword of the schema. If data is not valid JSON, the function returns the syntax error.
+func (s *JSONSchema) ValidateJSON(data []byte) error {
+	instance, err := decodeJSONValue(data)
+	if err != nil {
+		return err
+	}
+
+	var errs ValidationErrors
+	s.root.validate(instance, "", &errs)
+	if len(errs) != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// decodeJSONValue decodes a single JSON value, keeping numbers as json.Number to avoid losing precision.
+func decodeJSONValue(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, ErrTrailingData
+	}
+
+	return v, nil
+}
+
+type schemaNode struct {
+	boolean *bool
+
+	types      []string
+	enum       []any
+	constValue any
+	hasConst   bool
+
+	properties           map[string]*schemaNode
+	propertyNames        []string // keys of properties in sorted order for stable errors
+	required             []string
+	additionalProperties *schemaNode
+	minProperties        *int
+	maxProperties        *int
+
+	items    *schemaNode
+	minItems *int
+	maxItems *int
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+	format    string
+
+	allOf []*schemaNode
+	anyOf []*schemaNode
+	oneOf []*schemaNode
+	ref   *schemaNode
+}
+
+type schemaCompiler struct {
+	doc   any
+	nodes map[string]*schemaNode // JSON Pointer -> node, to share nodes referenced by $ref
+}
+
+func (c *schemaCompiler) compile(raw any, pointer string) (*schemaNode, error) {
+	if node, ok := c.nodes[pointer]; ok {
+		return node, nil
+	}
+
+	node := &schemaNode{}
+	c.nodes[pointer] = node
+
+	if b, ok := raw.(bool); ok {
+		node.boolean = &b
+		return node, nil
+	}
+
+	obj, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema at %q must be an object or a boolean", pointer)
+	}
+
+	if err := c.compileKeywords(node, obj, pointer); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func (c *schemaCompiler) compileKeywords(node *schemaNode, obj map[string]any, pointer string) error {
+	var err error
+
+	if raw, ok := obj["type"]; ok {
+		if node.types, err = schemaTypes(raw); err != nil {
+			return fmt.Errorf("%s/type: %w", pointer, err)
+		}
+	}
+
+	if raw, ok := obj["enum"]; ok {
+		if node.enum, ok = raw.([]any); !ok {
+			return fmt.Errorf("%s/enum: must be an array", pointer)
+		}
+	}
+
+	node.constValue, node.hasConst = obj["const"]
+
+	if raw, ok := obj["properties"]; ok {
+		props, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s/properties: must be an object", pointer)
+		}
+
+		node.properties = make(map[string]*schemaNode, len(props))
+		node.propertyNames = slices.Sorted(maps.Keys(props))
+		for _, name := range node.propertyNames {
+			if node.properties[name], err = c.compile(props[name], pointer+"/properties/"+escapeJSONPointer(name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if raw, ok := obj["required"]; ok {
+		if node.required, err = schemaStrings(raw); err != nil {
+			return fmt.Errorf("%s/required: %w", pointer, err)
+		}
+	}
+
+	for _, kw := range []struct {
+		keyword string
+		target  **schemaNode
+	}{
+		{"additionalProperties", &node.additionalProperties},
+		{"items", &node.items},
+	} {
+		keyword, target := kw.keyword, kw.target
+		if raw, ok := obj[keyword]; ok {
+			if *target, err = c.compile(raw, pointer+"/"+keyword); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, kw := range []struct {
+		keyword string
+		target  *[]*schemaNode
+	}{
+		{"allOf", &node.allOf},
+		{"anyOf", &node.anyOf},
+		{"oneOf", &node.oneOf},
+	} {
+		keyword, target := kw.keyword, kw.target
+		raw, ok := obj[keyword]
+		if !ok {
+			continue
+		}
+
+		list, ok := raw.([]any)
+		if !ok || len(list) == 0 {
+			return fmt.Errorf("%s/%s: must be a non-empty array", pointer, keyword)
+		}
+
+		for i, sub := range list {
+			subNode, err := c.compile(sub, pointer+"/"+keyword+"/"+strconv.Itoa(i))
+			if err != nil {
+				return err
+			}
+			*target = append(*target, subNode)
+		}
+	}
+
+	for _, kw := range []struct {
+		keyword string
+		target  **int
+	}{
+		{"minProperties", &node.minProperties},
+		{"maxProperties", &node.maxProperties},
+		{"minItems", &node.minItems},
+		{"maxItems", &node.maxItems},
+		{"minLength", &node.minLength},
+		{"maxLength", &node.maxLength},
+	} {
+		keyword, target := kw.keyword, kw.target
+		if raw, ok := obj[keyword]; ok {
+			if *target, err = schemaInt(raw); err != nil {
+				return fmt.Errorf("%s/%s: %w", pointer, keyword, err)
+			}
+		}
+	}
+
+	for _, kw := range []struct {
+		keyword string
+		target  **float64
+	}{
+		{"minimum", &node.minimum},
+		{"maximum", &node.maximum},
+		{"exclusiveMinimum", &node.exclusiveMinimum},
+		{"exclusiveMaximum", &node.exclusiveMaximum},
+	} {
+		keyword, target := kw.keyword, kw.target
+		if raw, ok := obj[keyword]; ok {
+			n, ok := raw.(json.Number)
+			if !ok {
+				return fmt.Errorf("%s/%s: must be a number", pointer, keyword)
+			}
+
+			f, err := n.Float64()
+			if err != nil {
+				return fmt.Errorf("%s/%s: %w", pointer, keyword, err)
+			}
+			*target = &f
+		}
+	}
+
+	if raw, ok := obj["pattern"]; ok {
+		pattern, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("%s/pattern: must be a string", pointer)
+		}
+
+		if node.pattern, err = regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%s/pattern: %w", pointer, err)
+		}
+	}
+
+	if raw, ok := obj["format"]; ok {
+		if node.format, ok = raw.(string); !ok {
+			return fmt.Errorf("%s/format: must be a string", pointer)
+		}
+	}
+
+	if raw, ok := obj["$ref"]; ok {
+		ref, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("%s/$ref: must be a string", pointer)
+		}
+
+		if node.ref, err = c.resolveRef(ref); err != nil {
+			return fmt.Errorf("%s/$ref: %w", pointer, err)
+		}
+	}
+
+	return nil
+}
+
+// resolveRef compiles the schema referenced by a JSON Pointer fragment such as "#/$defs/name".
+func (c *schemaCompiler) resolveRef(ref string) (*schemaNode, error) {
+	fragment, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference %q: only references in the same document are supported", ref)
+	}
+
+	pointer, err := url.PathUnescape(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference %q: %w", ref, err)
+	}
+
+	if pointer != "" && !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("unsupported reference %q: anchors are not supported", ref)
+	}
+
+	target := c.doc
+	if pointer != "" {
+		for token := range strings.SplitSeq(pointer[1:], "/") {
+			token = jsonPointerUnescaper.Replace(token)
+
+			switch v := target.(type) {
+			case map[string]any:
+				if target, ok = v[token]; !ok {
+					return nil, fmt.Errorf("reference %q not found", ref)
+				}
+			case []any:
+				i, err := strconv.Atoi(token)
+				if err != nil || i < 0 || i >= len(v) {
+					return nil, fmt.Errorf("reference %q not found", ref)
+				}
+				target = v[i]
+			default:
+				return nil, fmt.Errorf("reference %q not found", ref)
+			}
+		}
+	}
+
+	return c.compile(target, pointer)
+}
+
+var jsonPointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+var schemaTypeNames = []string{"null", "boolean", "object", "array", "number", "string", "integer"}
+
+func schemaTypes(raw any) ([]string, error) {
+	var types []string
+
+	switch v := raw.(type) {
+	case string:
+		types = []string{v}
+	case []any:
+		var err error
+		if types, err = schemaStrings(v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("must be a string or an array of strings")
+	}
+
+	for _, t := range types {
+		if !slices.Contains(schemaTypeNames, t) {
+			return nil, fmt.Errorf("unknown type %q", t)
+		}
+	}
+
+	return types, nil
+}
+
+func schemaStrings(raw any) ([]string, error) {
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, errors.New("must be an array of strings")
+	}
+
+	result := make([]string, len(list))
+	for i, item := range list {
+		if result[i], ok = item.(string); !ok {
+			return nil, errors.New("must be an array of strings")
+		}
+	}
+	return result, nil
+}
+
+func schemaInt(raw any) (*int, error) {
+	n, ok := raw.(json.Number)
+	if !ok {
+		return nil, errors.New("must be a non-negative integer")
+	}
+
+	i, err := strconv.Atoi(n.String())
+	if err != nil || i < 0 {
+		return nil, errors.New("must be a non-negative integer")
+	}
+	return &i, nil
+}
+
+func (n *schemaNode) validate(instance any, path string, errs *ValidationErrors) {
+	add := func(p string, keyword string, param string, message string) {
+		*errs = append(*errs, &ValidationError{Path: p, Rule: keyword, Param: param, Message: message})
+	}
+
+	if n.boolean != nil {
+		if !*n.boolean {
+			add(path, "false", "", "is not allowed")
+		}
+		return
+	}
+
+	if n.ref != nil {
+		n.ref.validate(instance, path, errs)
+	}
+
+	if len(n.types) != 0 && !slices.ContainsFunc(n.types, func(t string) bool { return jsonTypeMatches(instance, t) }) {
+		add(path, "type", strings.Join(n.types, " "), fmt.Sprintf("must be %s, but got %s", strings.Join(n.types, " or "), jsonTypeName(instance)))
+		return // other keywords are meaningless for a value of the wrong type
+	}
+
+	if n.enum != nil && !slices.ContainsFunc(n.enum, func(v any) bool { return jsonEqual(instance, v) }) {
+		add(path, "enum", "", "must be one of the allowed values")
+	}
+
+	if n.hasConst && !jsonEqual(instance, n.constValue) {
+		add(path, "const", "", "must be the constant value")
+	}
+
+	switch v := instance.(type) {
+	case map[string]any:
+		n.validateObject(v, path, errs, add)
+	case []any:
+		n.validateArray(v, path, errs, add)
+	case json.Number:
+		n.validateNumber(v, path, add)
+	case string:
+		n.validateString(v, path, add)
+	}
+
+	for _, sub := range n.allOf {
+		sub.validate(instance, path, errs)
+	}
+
+	if len(n.anyOf) != 0 && n.countMatches(n.anyOf, instance, path) == 0 {
+		add(path, "anyOf", "", "must match at least one of the schemas")
+	}
+
+	if len(n.oneOf) != 0 {
+		if count := n.countMatches(n.oneOf, instance, path); count != 1 {
+			add(path, "oneOf", "", fmt.Sprintf("must match exactly one of the schemas, but matched %d", count))
+		}
+	}
+}
+
+func (n *schemaNode) countMatches(schemas []*schemaNode, instance any, path string) int {
+	count := 0
+	for _, sub := range schemas {
+		var subErrs ValidationErrors
+		sub.validate(instance, path, &subErrs)
+		if len(subErrs) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func (n *schemaNode) validateObject(obj map[string]any, path string, errs *ValidationErrors, add func(string, string, string, string)) {
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			add(path+"/"+escapeJSONPointer(name), "required", "", "is required")
+		}
+	}
+
+	if n.minProperties != nil && len(obj) < *n.minProperties {
+		add(path, "minProperties", strconv.Itoa(*n.minProperties), fmt.Sprintf("must have at least %d properties", *n.minProperties))
+	}
+
+	if n.maxProperties != nil && len(obj) > *n.maxProperties {
+		add(path, "maxProperties", strconv.Itoa(*n.maxProperties), fmt.Sprintf("must have at most %d properties", *n.maxProperties))
+	}
+
+	for _, name := range n.propertyNames {
+		if value, ok := obj[name]; ok {
+			n.properties[name].validate(value, path+"/"+escapeJSONPointer(name), errs)
+		}
+	}
+
+	if n.additionalProperties == nil {
+		return
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		if _, ok := n.properties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		n.additionalProperties.validate(obj[name], path+"/"+escapeJSONPointer(name), errs)
+	}
+}
+
+func (n *schemaNode) validateArray(arr []any, path string, errs *ValidationErrors, add func(string, string, string, string)) {
+	if n.minItems != nil && len(arr) < *n.minItems {
+		add(path, "minItems", strconv.Itoa(*n.minItems), fmt.Sprintf("must have at least %d items", *n.minItems))
+	}
+
+	if n.maxItems != nil && len(arr) > *n.maxItems {
+		add(path, "maxItems", strconv.Itoa(*n.maxItems), fmt.Sprintf("must have at most %d items", *n.maxItems))
+	}
+
+	if n.items != nil {
+		for i, item := range arr {
+			n.items.validate(item, path+"/"+strconv.Itoa(i), errs)
+		}
+	}
+}
+
+func (n *schemaNode) validateNumber(num json.Number, path string, add func(string, string, string, string)) {
+	f, err := num.Float64()
+	if err != nil {
+		return
+	}
+
+	format := func(limit float64) string {
+		return strconv.FormatFloat(limit, 'g', -1, 64)
+	}
+
+	if n.minimum != nil && f < *n.minimum {
+		add(path, "minimum", format(*n.minimum), "must be greater than or equal to "+format(*n.minimum))
+	}
+
+	if n.maximum != nil && f > *n.maximum {
+		add(path, "maximum", format(*n.maximum), "must be less than or equal to "+format(*n.maximum))
+	}
+
+	if n.exclusiveMinimum != nil && f <= *n.exclusiveMinimum {
+		add(path, "exclusiveMinimum", format(*n.exclusiveMinimum), "must be greater than "+format(*n.exclusiveMinimum))
+	}
+
+	if n.exclusiveMaximum != nil && f >= *n.exclusiveMaximum {
+		add(path, "exclusiveMaximum", format(*n.exclusiveMaximum), "must be less than "+format(*n.exclusiveMaximum))
+	}
+}
+
+func (n *schemaNode) validateString(s string, path string, add func(string, string, string, string)) {
+	length := utf8.RuneCountInString(s)
+
+	if n.minLength != nil && length < *n.minLength {
+		add(path, "minLength", strconv.Itoa(*n.minLength), fmt.Sprintf("must have at least %d characters", *n.minLength))
+	}
+
+	if n.maxLength != nil && length > *n.maxLength {
+		add(path, "maxLength", strconv.Itoa(*n.maxLength), fmt.Sprintf("must have at most %d characters", *n.maxLength))
+	}
+
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		add(path, "pattern", n.pattern.String(), fmt.Sprintf("must match the pattern %q", n.pattern.String()))
+	}
+
+	if n.format != "" && !validFormat(n.format, s) {
+		add(path, "format", n.format, "must be a valid "+n.format)
+	}
+}
+
+func validFormat(format string, s string) bool {
+	switch format {
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	case "uuid":
+		return uuidPattern.MatchString(s)
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	case "ipv4":
+		addr, err := netip.ParseAddr(s)
+		return err == nil && addr.Is4()
+	case "ipv6":
+		addr, err := netip.ParseAddr(s)
+		return err == nil && addr.Is6()
+	default:
+		return true
+	}
+}
+
+func jsonTypeMatches(instance any, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := instance.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	default:
+		return jsonTypeName(instance) == t || (t == "number" && jsonTypeName(instance) == "integer")
+	}
+}
+
+func jsonTypeName(instance any) string {
+	switch v := instance.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		if jsonTypeMatches(v, "integer") {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// jsonEqual reports whether a and b are the same JSON value. Numbers are compared by their values.
+func jsonEqual(a any, b any) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aErr := av.Float64()
+		bf, bErr := bv.Float64()
+		return aErr == nil && bErr == nil && af == bf
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, value := range av {
+			other, ok := bv[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		return ok && slices.EqualFunc(av, bv, jsonEqual)
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,355 @@
+package httplib_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testUserSchema = httplib.MustCompileJSONSchema([]byte(`{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "maxLength": 5},
+		"email": {"type": "string", "format": "email"},
+		"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+		"role": {"enum": ["admin", "member"]},
+		"version": {"const": 1},
+		"code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+		"address": {"$ref": "#/$defs/address"},
+		"contact": {
+			"oneOf": [
+				{"type": "object", "properties": {"phone": {"type": "string"}}, "required": ["phone"]},
+				{"type": "object", "properties": {"mail": {"type": "string"}}, "required": ["mail"]}
+			]
+		},
+		"id": {"anyOf": [{"type": "integer"}, {"type": "string", "format": "uuid"}]},
+		"score": {"allOf": [{"type": "number"}, {"maximum": 10}]}
+	},
+	"required": ["name"],
+	"additionalProperties": false,
+	"$defs": {
+		"address": {
+			"type": "object",
+			"properties": {"city": {"type": "string"}, "tree/node": {"type": "null"}},
+			"required": ["city"]
+		}
+	}
+}`))
+
+func assertValidationRulesFunc(expected ...string) assert.ErrorAssertionFunc {
+	return func(t assert.TestingT, err error, i ...interface{}) bool {
+		var validationErrs httplib.ValidationErrors
+		if !assert.ErrorAs(t, err, &validationErrs) {
+			return false
+		}
+
+		rules := make([]string, len(validationErrs))
+		for i, validationErr := range validationErrs {
+			rules[i] = validationErr.Path + " " + validationErr.Rule
+		}
+		return assert.Equal(t, expected, rules)
+	}
+}
+
+func TestJSONSchema_ValidateJSON(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: minimal",
+			data:         `{"name":"alice"}`,
+			errAssertion: assert.NoError,
+		},
+		{
+			name: "success: all properties",
+			data: `{"name":"alice","email":"a@example.com","age":20.0,"role":"admin","version":1.0,"code":"ABC",` +
+				`"tags":["a","b"],"address":{"city":"Tokyo","tree/node":null},"contact":{"phone":"1"},` +
+				`"id":"0d6ac4b6-1fd4-4a44-9d55-2bc0e1b8e5bd","score":9.5}`,
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: not an object",
+			data:         `[]`,
+			errAssertion: assertValidationRulesFunc(" type"),
+		},
+		{
+			name:         "failure: required and additional properties",
+			data:         `{"unknown":1}`,
+			errAssertion: assertValidationRulesFunc("/name required", "/unknown false"),
+		},
+		{
+			name:         "failure: string keywords",
+			data:         `{"name":"","email":"alice","code":"abc"}`,
+			errAssertion: assertValidationRulesFunc("/code pattern", "/email format", "/name minLength"),
+		},
+		{
+			name:         "failure: number keywords",
+			data:         `{"name":"alice","age":1.5,"score":11}`,
+			errAssertion: assertValidationRulesFunc("/age type", "/score maximum"),
+		},
+		{
+			name:         "failure: range",
+			data:         `{"name":"alice","age":150}`,
+			errAssertion: assertValidationRulesFunc("/age exclusiveMaximum"),
+		},
+		{
+			name:         "failure: enum and const",
+			data:         `{"name":"alice","role":"owner","version":2}`,
+			errAssertion: assertValidationRulesFunc("/role enum", "/version const"),
+		},
+		{
+			name:         "failure: array keywords",
+			data:         `{"name":"alice","tags":["a",1,"c"]}`,
+			errAssertion: assertValidationRulesFunc("/tags maxItems", "/tags/1 type"),
+		},
+		{
+			name:         "failure: $ref",
+			data:         `{"name":"alice","address":{"tree/node":1}}`,
+			errAssertion: assertValidationRulesFunc("/address/city required", "/address/tree~1node type"),
+		},
+		{
+			name:         "failure: oneOf matches both",
+			data:         `{"name":"alice","contact":{"phone":"1","mail":"a"}}`,
+			errAssertion: assertValidationRulesFunc("/contact oneOf"),
+		},
+		{
+			name:         "failure: anyOf matches none",
+			data:         `{"name":"alice","id":"x"}`,
+			errAssertion: assertValidationRulesFunc("/id anyOf"),
+		},
+		{
+			name:         "failure: invalid JSON",
+			data:         `{"name":`,
+			errAssertion: assert.Error,
+		},
+		{
+			name: "failure: trailing data",
+			data: `{"name":"alice"} {}`,
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, httplib.ErrTrailingData, i...)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.errAssertion(t, testUserSchema.ValidateJSON([]byte(tt.data)))
+		})
+	}
+}
+
+func TestJSONSchema_RecursiveRef(t *testing.T) {
+	schema, err := httplib.CompileJSONSchema([]byte(`{
+		"type": "object",
+		"properties": {"value": {"type": "integer"}, "children": {"type": "array", "items": {"$ref": "#"}}}
+	}`))
+	require.NoError(t, err)
+
+	require.NoError(t, schema.ValidateJSON([]byte(`{"value":1,"children":[{"value":2,"children":[]}]}`)))
+	assertValidationRulesFunc("/children/0/children/0/value type")(t, schema.ValidateJSON([]byte(`{"children":[{"children":[{"value":"x"}]}]}`)))
+}
+
+func TestJSONSchema_BooleanSchema(t *testing.T) {
+	schema, err := httplib.CompileJSONSchema([]byte(`true`))
+	require.NoError(t, err)
+	assert.NoError(t, schema.ValidateJSON([]byte(`{"any":"value"}`)))
+
+	schema, err = httplib.CompileJSONSchema([]byte(`false`))
+	require.NoError(t, err)
+	assertValidationRulesFunc(" false")(t, schema.ValidateJSON([]byte(`null`)))
+}
+
+func TestCompileJSONSchema(t *testing.T) {
+	tests := []struct {
+		name         string
+		schema       string
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: empty schema",
+			schema:       `{}`,
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: invalid JSON",
+			schema:       `{`,
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: not an object",
+			schema:       `1`,
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: unknown type",
+			schema:       `{"type":"date"}`,
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: invalid pattern",
+			schema:       `{"pattern":"("}`,
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: negative minLength",
+			schema:       `{"minLength":-1}`,
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: empty oneOf",
+			schema:       `{"oneOf":[]}`,
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: missing reference",
+			schema:       `{"$ref":"#/$defs/missing"}`,
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: remote reference",
+			schema:       `{"$ref":"https://example.com/schema.json"}`,
+			errAssertion: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := httplib.CompileJSONSchema([]byte(tt.schema))
+			tt.errAssertion(t, err)
+		})
+	}
+}
+
+func TestCompileJSONSchema_ErrorOrder(t *testing.T) {
+	schema := `{"maxLength":"a","minItems":"b","maximum":"c","minimum":"d","anyOf":1,"allOf":2,"properties":{"b":{"type":1},"a":{"type":2}}}`
+
+	for range 20 {
+		_, err := httplib.CompileJSONSchema([]byte(schema))
+		assert.EqualError(t, err, "httplib: invalid JSON Schema: /properties/a/type: must be a string or an array of strings")
+	}
+
+	// Without properties, the keywords are compiled in a fixed order.
+	schema = `{"maxLength":"a","minItems":"b","maximum":"c","minimum":"d","anyOf":1,"allOf":2}`
+	for range 20 {
+		_, err := httplib.CompileJSONSchema([]byte(schema))
+		assert.EqualError(t, err, "httplib: invalid JSON Schema: /allOf: must be a non-empty array")
+	}
+}
+
+func TestCompileJSONSchema_Cache(t *testing.T) {
+	data := []byte(`{"type":"string"}`)
+
+	first, err := httplib.CompileJSONSchema(data)
+	require.NoError(t, err)
+
+	second, err := httplib.CompileJSONSchema(data)
+	require.NoError(t, err)
+
+	assert.Same(t, first, second)
+}
+
+func TestCompileJSONSchema_CacheEviction(t *testing.T) {
+	data := []byte(`{"type":"integer","minimum":-1}`)
+
+	first, err := httplib.CompileJSONSchema(data)
+	require.NoError(t, err)
+
+	for i := range httplib.MaxJSONSchemaCacheEntries {
+		_, err := httplib.CompileJSONSchema([]byte(`{"type":"integer","minimum":` + strconv.Itoa(i) + `}`))
+		require.NoError(t, err)
+	}
+
+	second, err := httplib.CompileJSONSchema(data)
+	require.NoError(t, err)
+
+	assert.NotSame(t, first, second)
+}
+
+func TestMustCompileJSONSchema_Panic(t *testing.T) {
+	assert.Panics(t, func() {
+		httplib.MustCompileJSONSchema([]byte(`{"type":1}`))
+	})
+}
+
+func TestDecodeJSONRequestBody_WithJSONSchema(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		want         user
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success",
+			body:         `{"name":"alice","age":20}`,
+			want:         user{Name: "alice", Age: 20},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: schema violation",
+			body:         `{"name":"","age":"20"}`,
+			want:         user{},
+			errAssertion: assertValidationRulesFunc("/age type", "/name minLength"),
+		},
+		{
+			name:         "failure: exceeds max body size",
+			body:         `{"name":"alice","age":20,"tags":["a","b","c","d","e","f","g","h"]}`,
+			want:         user{},
+			errAssertion: assertMaxBytesErrorFunc(32),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+
+			got, err := httplib.DecodeJSONRequestBody[user](r, httplib.WithJSONSchema(testUserSchema), httplib.WithMaxBodySize(32))
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecodeJSONArray_WithJSONSchema(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"name":"alice"},{"name":"toolong"}]`))
+
+	got, err := collectItems(httplib.DecodeJSONArray[user](r, httplib.WithJSONSchema(testUserSchema)))
+	assert.Equal(t, []user{{Name: "alice"}}, got)
+	assertItemErrorFunc(1)(t, err)
+	assertValidationRulesFunc("/name maxLength")(t, err)
+}
+
+func TestJSONSchema_RenderUnprocessableEntity(t *testing.T) {
+	err := testUserSchema.ValidateJSON([]byte(`{"name":"toolong"}`))
+	require.Error(t, err)
+
+	renderer, jsonErr := httplib.JSONResponse(err)
+	require.NoError(t, jsonErr)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, httplib.RenderUnprocessableEntityWithBody(t.Context(), w, renderer, err))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var body []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, []map[string]any{
+		{"path": "/name", "rule": "maxLength", "param": "5", "message": "must have at most 5 characters"},
+	}, body)
+}
@@ -0,0 +1,170 @@
+package httplib
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidMediaType is returned by ParseMediaType when the value is not a valid media type.
+var ErrInvalidMediaType = errors.New("httplib: invalid media type")
+
+// MediaType is a parsed media type such as "application/json; charset=utf-8", or a media range of the Accept header
+// such as "text/*;q=0.5".
+type MediaType struct {
+	// Type is the lower-cased top-level type, such as "application". It is "*" for the wildcard.
+	Type string
+
+	// Subtype is the lower-cased subtype, such as "json". It is "*" for the wildcard.
+	Subtype string
+
+	// Params are the parameters except "q". The keys are lower-cased.
+	Params map[string]string
+
+	// Quality is the value of the "q" parameter, between 0 and 1. It is 1 if the parameter is absent.
+	Quality float64
+}
+
+// ParseMediaType parses a media type or a media range.
+//
+// If s is not a valid media type, or the "q" parameter is not a valid quality value,
+// it returns an error wrapping ErrInvalidMediaType.
+func ParseMediaType(s string) (MediaType, error) {
+	mediaType, params, err := mime.ParseMediaType(s)
+	if err != nil {
+		return MediaType{}, fmt.Errorf("%w %q: %w", ErrInvalidMediaType, s, err)
+	}
+
+	typ, subtype, ok := strings.Cut(mediaType, "/")
+	if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+		return MediaType{}, fmt.Errorf("%w %q", ErrInvalidMediaType, s)
+	}
+
+	m := MediaType{Type: typ, Subtype: subtype, Quality: 1}
+
+	if q, ok := params["q"]; ok {
+		delete(params, "q")
+		if m.Quality, err = parseQuality(q); err != nil {
+			return MediaType{}, fmt.Errorf("%w %q: %w", ErrInvalidMediaType, s, err)
+		}
+	}
+
+	if len(params) != 0 {
+		m.Params = params
+	}
+
+	return m, nil
+}
+
+func parseQuality(s string) (float64, error) {
+	q, err := strconv.ParseFloat(s, 64)
+	if err != nil || q < 0 || q > 1 {
+		return 0, fmt.Errorf("invalid quality value %q", s)
+	}
+	return q, nil
+}
+
+// ParseAccept parses the values of the Accept header.
+//
+// The media ranges are sorted by Quality in descending order, and then by Specificity in descending order.
+// Ranges of the same precedence keep their order in the header. Invalid media ranges are ignored.
+func ParseAccept(values ...string) []MediaType {
+	var ranges []MediaType
+	for _, value := range values {
+		for _, element := range splitHeaderList(value) {
+			if m, err := ParseMediaType(element); err == nil {
+				ranges = append(ranges, m)
+			}
+		}
+	}
+
+	slices.SortStableFunc(ranges, func(a, b MediaType) int {
+		if a.Quality != b.Quality {
+			if a.Quality > b.Quality {
+				return -1
+			}
+			return 1
+		}
+		return b.Specificity() - a.Specificity()
+	})
+
+	return ranges
+}
+
+// splitHeaderList splits a comma-separated header value, ignoring commas in quoted strings and empty elements.
+func splitHeaderList(value string) []string {
+	var elements []string
+	start, quoted, escaped := 0, false, false
+
+	for i := 0; i <= len(value); i++ {
+		if i < len(value) {
+			switch c := value[i]; {
+			case escaped:
+				escaped = false
+				continue
+			case quoted && c == '\\':
+				escaped = true
+				continue
+			case c == '"':
+				quoted = !quoted
+				continue
+			case c != ',' || quoted:
+				continue
+			}
+		}
+
+		if element := strings.TrimSpace(value[start:i]); element != "" {
+			elements = append(elements, element)
+		}
+		start = i + 1
+	}
+
+	return elements
+}
+
+// String returns the media type in the form "type/subtype; key=value" with parameters sorted by key.
+//
+// The "q" parameter is not included.
+func (m MediaType) String() string {
+	return mime.FormatMediaType(m.Type+"/"+m.Subtype, m.Params)
+}
+
+// Specificity returns how specific the media range is:
+// 0 for "*/*", 1 for "type/*", 2 for "type/subtype" and 3 for "type/subtype" with parameters.
+func (m MediaType) Specificity() int {
+	switch {
+	case m.Type == "*":
+		return 0
+	case m.Subtype == "*":
+		return 1
+	case len(m.Params) == 0:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Matches reports whether the media range m includes the media type t.
+//
+// Wildcards in m match any type or subtype, and every parameter of m must be present in t with the same value.
+// Parameter values are compared case-insensitively. Quality values are ignored.
+func (m MediaType) Matches(t MediaType) bool {
+	if m.Type != "*" && m.Type != t.Type {
+		return false
+	}
+
+	if m.Subtype != "*" && m.Subtype != t.Subtype {
+		return false
+	}
+
+	for key, value := range m.Params {
+		if other, ok := t.Params[key]; !ok || !strings.EqualFold(value, other) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,134 @@
+package httplib_test
+
+import (
+	"testing"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMediaType(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		want         httplib.MediaType
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: type and subtype",
+			value:        "application/json",
+			want:         httplib.MediaType{Type: "application", Subtype: "json", Quality: 1},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: parameters and quality",
+			value:        "Text/HTML; Charset=UTF-8; q=0.5",
+			want:         httplib.MediaType{Type: "text", Subtype: "html", Params: map[string]string{"charset": "UTF-8"}, Quality: 0.5},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: wildcards",
+			value:        "*/*;q=0",
+			want:         httplib.MediaType{Type: "*", Subtype: "*", Quality: 0},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: no subtype",
+			value:        "text",
+			errAssertion: assertErrorIsInvalidMediaType,
+		},
+		{
+			name:         "failure: wildcard type with subtype",
+			value:        "*/json",
+			errAssertion: assertErrorIsInvalidMediaType,
+		},
+		{
+			name:         "failure: invalid quality",
+			value:        "text/html;q=2",
+			errAssertion: assertErrorIsInvalidMediaType,
+		},
+		{
+			name:         "failure: empty",
+			value:        "",
+			errAssertion: assertErrorIsInvalidMediaType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := httplib.ParseMediaType(tt.value)
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func assertErrorIsInvalidMediaType(t assert.TestingT, err error, i ...interface{}) bool {
+	return assert.ErrorIs(t, err, httplib.ErrInvalidMediaType, i...)
+}
+
+func TestParseAccept(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{
+			name:   "no values",
+			values: nil,
+			want:   []string{},
+		},
+		{
+			name:   "sorted by quality and specificity",
+			values: []string{"*/*;q=0.1, text/*, text/html;level=1, text/html, application/json;q=0.9"},
+			want:   []string{"text/html; level=1", "text/html", "text/*", "application/json", "*/*"},
+		},
+		{
+			name:   "multiple header values",
+			values: []string{"text/plain;q=0.5", "application/xml"},
+			want:   []string{"application/xml", "text/plain"},
+		},
+		{
+			name:   "invalid ranges and quoted commas",
+			values: []string{`invalid, text/plain;foo="a,b", , application/json;q=x`},
+			want:   []string{`text/plain; foo="a,b"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, mediaType := range httplib.ParseAccept(tt.values...) {
+				got = append(got, mediaType.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMediaType_Matches(t *testing.T) {
+	tests := []struct {
+		name       string
+		mediaRange string
+		mediaType  string
+		want       bool
+	}{
+		{name: "any", mediaRange: "*/*", mediaType: "application/json", want: true},
+		{name: "subtype wildcard", mediaRange: "text/*", mediaType: "text/csv", want: true},
+		{name: "subtype wildcard with other type", mediaRange: "text/*", mediaType: "application/json", want: false},
+		{name: "exact", mediaRange: "application/json", mediaType: "application/json; charset=utf-8", want: true},
+		{name: "different subtype", mediaRange: "application/xml", mediaType: "application/json", want: false},
+		{name: "same parameter", mediaRange: "text/plain; charset=UTF-8", mediaType: "text/plain; charset=utf-8", want: true},
+		{name: "different parameter", mediaRange: "text/plain; charset=ascii", mediaType: "text/plain; charset=utf-8", want: false},
+		{name: "missing parameter", mediaRange: "text/plain; charset=utf-8", mediaType: "text/plain", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediaRange, err := httplib.ParseMediaType(tt.mediaRange)
+			assert.NoError(t, err)
+
+			mediaType, err := httplib.ParseMediaType(tt.mediaType)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.want, mediaRange.Matches(mediaType))
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package httplib
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
+	"io"
 	"net/http"
 )
 
@@ -9,22 +11,87 @@ const DefaultMaxRequestBodySize = 1 << 20 // 1MB
 
 // DecodeJSONRequestBody decodes request body to T using JSON decoder.
 //
-// This function reads the request body up to DefaultMaxRequestBodySize.
+// This function reads the request body up to DefaultMaxRequestBodySize, or the size set by WithMaxBodySize.
 // If the request body exceeds this size, the function returns http.MaxBytesError.
 //
+// Unknown fields are rejected unless WithAllowUnknownFields is given.
+// If WithJSONSchema is given, the body is validated against the schema before it is decoded.
+// If WithContentDigest or WithRequiredContentDigest is given, the body is verified against the Content-Digest header.
+// If WithValidation is given, the decoded value is validated by Validate.
+//
 // The request body will be closed after decoding.
 // This function ignores any error returned by Close.
-func DecodeJSONRequestBody[T any](r *http.Request) (T, error) {
-	body := http.MaxBytesReader(nil, r.Body, DefaultMaxRequestBodySize)
-	defer body.Close()
+func DecodeJSONRequestBody[T any](r *http.Request, opts ...DecodeOption) (T, error) {
+	cfg := newDecodeConfig(DefaultMaxRequestBodySize, opts)
 
-	decoder := json.NewDecoder(body)
-	decoder.DisallowUnknownFields()
 	var t T
-	if err := decoder.Decode(&t); err != nil {
+	if err := decodeJSONRequestBody(r, &t, cfg); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	if err := cfg.validateIfEnabled(&t); err != nil {
 		var zero T
 		return zero, err
 	}
 
 	return t, nil
 }
+
+func decodeJSONRequestBody(r *http.Request, dst any, cfg decodeConfig) error {
+	var body io.ReadCloser = http.MaxBytesReader(nil, r.Body, cfg.maxBodySize)
+	defer body.Close()
+
+	if cfg.contentDigest != contentDigestDisabled {
+		verified, err := newContentDigestReader(body, r.Header, cfg.contentDigest == contentDigestRequired)
+		if err != nil {
+			return err
+		}
+		body = verified
+	}
+
+	if cfg.schema != nil || cfg.contentDigest != contentDigestDisabled {
+		// The whole body is needed to validate it against the schema, or to verify its digest, before decoding.
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+
+		if err := cfg.validateSchemaIfEnabled(data); err != nil {
+			return err
+		}
+
+		body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	reader := &readErrorReader{r: body}
+	if err := GetCodecFromContext(r.Context()).NewDecoder(reader, cfg.unmarshalOptions()).Decode(dst); err != nil {
+		return reader.cause(err)
+	}
+	return nil
+}
+
+// readErrorReader records the first error returned by the underlying reader other than io.EOF, such as http.MaxBytesError.
+//
+// Some JSON decoders, such as encoding/json built with GOEXPERIMENT=jsonv2, report a read error as a syntax error
+// that does not wrap it, which would hide http.MaxBytesError from errors.As.
+type readErrorReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErrorReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// cause returns the recorded read error if the decoding error does not wrap it, or err otherwise.
+func (r *readErrorReader) cause(err error) error {
+	if r.err != nil && !errors.Is(err, r.err) {
+		return r.err
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+package httplib
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// DecodePath binds the path values of the request to T.
+//
+// Path values are the wildcards matched by http.ServeMux patterns (e.g. "{id}" in "GET /items/{id}"),
+// and are read by http.Request.PathValue.
+//
+// T must be a struct. Its fields are bound by the "path" struct tag in the same way as DecodeQuery
+// binds the "query" struct tag. For example:
+//
+//	type ItemPath struct {
+//		ID int64 `path:"id,required"`
+//	}
+//
+// Invalid or missing values are reported together as FieldErrors.
+// If WithValidation is given, the bound value is validated by Validate. Other options are ignored.
+func DecodePath[T any](r *http.Request, opts ...DecodeOption) (T, error) {
+	return bindRequestValues[T](r, SourcePath, opts)
+}
+
+// DecodeHeader binds the headers of the request to T.
+//
+// T must be a struct. Its fields are bound by the "header" struct tag in the same way as DecodeQuery
+// binds the "query" struct tag. Header names are case-insensitive. For example:
+//
+//	type TenantHeader struct {
+//		TenantID string   `header:"X-Tenant,required"`
+//		Accept   []string `header:"Accept,comma"`
+//	}
+//
+// Invalid or missing values are reported together as FieldErrors.
+// If WithValidation is given, the bound value is validated by Validate. Other options are ignored.
+func DecodeHeader[T any](r *http.Request, opts ...DecodeOption) (T, error) {
+	return bindRequestValues[T](r, SourceHeader, opts)
+}
+
+// Bind binds the path values, query parameters, headers and JSON body of the request to T.
+//
+// T must be a struct. Fields tagged with "path", "query" and "header" are bound as DecodePath, DecodeQuery
+// and DecodeHeader do. If T has a field tagged with "body", the JSON request body is decoded into that field
+// as DecodeJSONRequestBody does, and opts are applied to it. For example:
+//
+//	type UpdateItemRequest struct {
+//		ID       int64          `path:"id,required"`
+//		DryRun   bool           `query:"dry_run"`
+//		TenantID string         `header:"X-Tenant,required"`
+//		Body     UpdateItemBody `body:""`
+//	}
+//
+// Invalid or missing values of path values, query parameters and headers are reported together as FieldErrors.
+// In that case, the request body is not read.
+//
+// If WithValidation is given, the whole value is validated by Validate after binding.
+// The field tagged with "body" is treated as the root of the JSON Pointer paths in ValidationErrors.
+func Bind[T any](r *http.Request, opts ...DecodeOption) (T, error) {
+	cfg := newDecodeConfig(DefaultMaxRequestBodySize, opts)
+	var zero T
+	var t T
+	v := reflect.ValueOf(&t).Elem()
+
+	var errs FieldErrors
+	for _, source := range []string{SourcePath, SourceQuery, SourceHeader} {
+		// The sources without fields are skipped, so that a malformed query string does not fail a request that has no query fields.
+		fieldErrs, err := bindRequestSource(v, r, source, true)
+		if err != nil {
+			return zero, err
+		}
+		errs = append(errs, fieldErrs...)
+	}
+
+	if len(errs) != 0 {
+		return zero, errs
+	}
+
+	bodyIndex, err := findBodyField(v.Type())
+	if err != nil {
+		return zero, err
+	}
+
+	if bodyIndex != nil {
+		if err := decodeJSONRequestBody(r, v.FieldByIndex(bodyIndex).Addr().Interface(), cfg); err != nil {
+			return zero, err
+		}
+	}
+
+	if err := cfg.validateIfEnabled(&t); err != nil {
+		return zero, err
+	}
+
+	return t, nil
+}
+
+// findBodyField returns the index of the field tagged with "body", or nil if there is no such field.
+func findBodyField(t reflect.Type) ([]int, error) {
+	var index []int
+
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if _, ok := sf.Tag.Lookup("body"); !ok {
+			continue
+		}
+
+		if !sf.IsExported() {
+			return nil, fmt.Errorf("httplib: body field %s must be exported", sf.Name)
+		}
+
+		if index != nil {
+			return nil, fmt.Errorf("httplib: multiple body fields in %s", t)
+		}
+
+		index = sf.Index
+	}
+
+	return index, nil
+}
+
+func bindRequestValues[T any](r *http.Request, source string, opts []DecodeOption) (T, error) {
+	cfg := newDecodeConfig(DefaultMaxRequestBodySize, opts)
+	var zero T
+	var t T
+
+	errs, err := bindRequestSource(reflect.ValueOf(&t).Elem(), r, source, false)
+	if err != nil {
+		return zero, err
+	}
+
+	if len(errs) != 0 {
+		return zero, errs
+	}
+
+	if err := cfg.validateIfEnabled(&t); err != nil {
+		return zero, err
+	}
+
+	return t, nil
+}
+
+// bindRequestSource binds the values of the request from the source to v.
+//
+// It returns an error that is not FieldErrors if the values cannot be bound regardless of their content,
+// such as a malformed query string. If skipEmpty is true and v has no fields of the source, the request is not read.
+func bindRequestSource(v reflect.Value, r *http.Request, source string, skipEmpty bool) (FieldErrors, error) {
+	fields, err := getBindFields(v.Type(), source)
+	if err != nil {
+		return nil, err
+	}
+
+	if skipEmpty && len(fields) == 0 {
+		return nil, nil
+	}
+
+	values := make(map[string][]string, len(fields))
+
+	switch source {
+	case SourceQuery:
+		if values, err = url.ParseQuery(r.URL.RawQuery); err != nil {
+			return nil, err
+		}
+	case SourcePath:
+		for _, field := range fields {
+			if value := r.PathValue(field.key); value != "" {
+				values[field.key] = []string{value}
+			}
+		}
+	case SourceHeader:
+		for _, field := range fields {
+			if headerValues := r.Header.Values(field.key); len(headerValues) != 0 {
+				values[field.key] = headerValues
+			}
+		}
+	default:
+		return nil, fmt.Errorf("httplib: unknown source %q", source)
+	}
+
+	return bindValues(v, fields, source, values, nil), nil
+}
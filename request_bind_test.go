@@ -0,0 +1,260 @@
+package httplib_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodePath(t *testing.T) {
+	type itemPath struct {
+		ID       int64   `path:"id,required"`
+		Category *string `path:"category"`
+	}
+
+	tests := []struct {
+		name         string
+		pathValues   map[string]string
+		want         itemPath
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: all values",
+			pathValues:   map[string]string{"id": "10", "category": "book"},
+			want:         itemPath{ID: 10, Category: toPtr("book")},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: optional value is missing",
+			pathValues:   map[string]string{"id": "10"},
+			want:         itemPath{ID: 10},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: required value is missing",
+			pathValues:   map[string]string{},
+			want:         itemPath{},
+			errAssertion: assertFieldErrorsFunc("id"),
+		},
+		{
+			name:         "failure: invalid value",
+			pathValues:   map[string]string{"id": "abc"},
+			want:         itemPath{},
+			errAssertion: assertFieldErrorsFunc("id"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, value := range tt.pathValues {
+				r.SetPathValue(key, value)
+			}
+
+			got, err := httplib.DecodePath[itemPath](r)
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecodePath_ServeMux(t *testing.T) {
+	type itemPath struct {
+		ID   int64  `path:"id"`
+		Rest string `path:"rest"`
+	}
+
+	var got itemPath
+	var gotErr error
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}/{rest...}", func(w http.ResponseWriter, r *http.Request) {
+		got, gotErr = httplib.DecodePath[itemPath](r)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42/a/b", nil))
+
+	require.NoError(t, gotErr)
+	assert.Equal(t, itemPath{ID: 42, Rest: "a/b"}, got)
+}
+
+func TestDecodeHeader(t *testing.T) {
+	type tenantHeader struct {
+		TenantID string   `header:"X-Tenant,required"`
+		Accept   []string `header:"accept,comma"`
+		Retry    int      `header:"X-Retry" default:"3"`
+	}
+
+	tests := []struct {
+		name         string
+		header       http.Header
+		want         tenantHeader
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name: "success: all headers",
+			header: http.Header{
+				"X-Tenant": {"t1"},
+				"Accept":   {"text/html, application/json", "*/*"},
+				"X-Retry":  {"5"},
+			},
+			want:         tenantHeader{TenantID: "t1", Accept: []string{"text/html", "application/json", "*/*"}, Retry: 5},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: default",
+			header:       http.Header{"X-Tenant": {"t1"}},
+			want:         tenantHeader{TenantID: "t1", Retry: 3},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: missing and invalid headers",
+			header:       http.Header{"X-Retry": {"x"}},
+			want:         tenantHeader{},
+			errAssertion: assertFieldErrorsFunc("X-Tenant", "X-Retry"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header = tt.header
+
+			got, err := httplib.DecodeHeader[tenantHeader](r)
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+type bindBody struct {
+	Name string `json:"name"`
+}
+
+type bindRequest struct {
+	ID       int64    `path:"id,required"`
+	DryRun   bool     `query:"dry_run"`
+	TenantID string   `header:"X-Tenant,required"`
+	Body     bindBody `body:""`
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		query        string
+		tenant       string
+		body         string
+		opts         []httplib.DecodeOption
+		want         bindRequest
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: all sources",
+			id:           "1",
+			query:        "dry_run=true",
+			tenant:       "t1",
+			body:         `{"name":"a"}`,
+			want:         bindRequest{ID: 1, DryRun: true, TenantID: "t1", Body: bindBody{Name: "a"}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: field errors from every source",
+			id:           "x",
+			query:        "dry_run=maybe",
+			tenant:       "",
+			body:         `{"name":"a"}`,
+			want:         bindRequest{},
+			errAssertion: assertFieldErrorsFunc("id", "dry_run", "X-Tenant"),
+		},
+		{
+			name:         "failure: invalid body",
+			id:           "1",
+			tenant:       "t1",
+			body:         `{"name":1}`,
+			want:         bindRequest{},
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: unknown field in body",
+			id:           "1",
+			tenant:       "t1",
+			body:         `{"name":"a","id":2}`,
+			want:         bindRequest{},
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "success: unknown field in body is allowed",
+			id:           "1",
+			tenant:       "t1",
+			body:         `{"name":"a","id":2}`,
+			opts:         []httplib.DecodeOption{httplib.WithAllowUnknownFields()},
+			want:         bindRequest{ID: 1, TenantID: "t1", Body: bindBody{Name: "a"}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: body exceeds max body size",
+			id:           "1",
+			tenant:       "t1",
+			body:         `{"name":"abcdef"}`,
+			opts:         []httplib.DecodeOption{httplib.WithMaxBodySize(10)},
+			want:         bindRequest{},
+			errAssertion: assertMaxBytesErrorFunc(10),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/?"+tt.query, strings.NewReader(tt.body))
+			r.SetPathValue("id", tt.id)
+			r.Header.Set("X-Tenant", tt.tenant)
+
+			got, err := httplib.Bind[bindRequest](r, tt.opts...)
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBind_FieldErrorsJSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/?dry_run=maybe", strings.NewReader(`{}`))
+	r.SetPathValue("id", "1")
+
+	_, err := httplib.Bind[bindRequest](r)
+	require.Error(t, err)
+
+	data, err := json.Marshal(err)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"source":"query","field":"dry_run","value":"maybe","message":"invalid syntax"},
+		{"source":"header","field":"X-Tenant","message":"required"}
+	]`, string(data))
+}
+
+func TestBind_WithoutBody(t *testing.T) {
+	type listRequest struct {
+		Page int `query:"page" default:"1"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	got, err := httplib.Bind[listRequest](r)
+	require.NoError(t, err)
+	assert.Equal(t, listRequest{Page: 1}, got)
+}
+
+func TestBind_InvalidBodyField(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+
+	_, err := httplib.Bind[struct {
+		A bindBody `body:""`
+		B bindBody `body:""`
+	}](r)
+	assert.ErrorContains(t, err, "httplib: multiple body fields")
+
+	_, err = httplib.Bind[struct {
+		body bindBody `body:""`
+	}](r)
+	assert.EqualError(t, err, "httplib: body field body must be exported")
+}
@@ -0,0 +1,186 @@
+package httplib
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrUnsupportedMediaType is returned when the Content-Type of the request is not supported by the decoder.
+//
+// The decoders return UnsupportedMediaTypeError, which matches ErrUnsupportedMediaType by errors.Is.
+var ErrUnsupportedMediaType = errors.New("httplib: unsupported media type")
+
+// UnsupportedMediaTypeError is returned when the Content-Type of the request is not supported by the decoder.
+type UnsupportedMediaTypeError struct {
+	// ContentType is the Content-Type header of the request. It is empty if the header is absent.
+	ContentType string
+
+	// Accepted is the list of the media types the decoder accepts.
+	Accepted []ContentType
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("%s %q: accepted media types are %s", ErrUnsupportedMediaType, e.ContentType, strings.Join(e.Accepted, ", "))
+}
+
+// Is reports whether target is ErrUnsupportedMediaType.
+func (e *UnsupportedMediaTypeError) Is(target error) bool {
+	return target == ErrUnsupportedMediaType
+}
+
+// BodyDecoder decodes a request body into dst, which is a pointer to the destination value.
+//
+// The body is limited by the size limit of the decoder. opts tells how to handle unknown fields.
+// The request is given to read its headers and context; its body must not be read directly.
+type BodyDecoder func(r *http.Request, body io.Reader, dst any, opts UnmarshalOptions) error
+
+type bodyDecoderEntry struct {
+	mediaType string
+	decode    func(r *http.Request, dst any, cfg decodeConfig) error
+}
+
+var defaultBodyDecoders = []bodyDecoderEntry{
+	{mediaType: ContentTypeJSON, decode: decodeJSONRequestBody},
+	{mediaType: ContentTypeXML, decode: decodeXMLRequestBody},
+	{mediaType: ContentTypeTextXML, decode: decodeXMLRequestBody},
+	{mediaType: ContentTypeFormURLEncoded, decode: decodeFormRequestBody},
+}
+
+// DecodeRequestBody decodes the request body to T by the decoder chosen by the Content-Type of the request.
+//
+// The following media types are supported by default:
+//   - application/json, and media types with the "+json" suffix such as application/merge-patch+json,
+//     decoded as DecodeJSONRequestBody does
+//   - application/xml and text/xml, and media types with the "+xml" suffix, decoded as DecodeXMLRequestBody does
+//   - application/x-www-form-urlencoded, decoded as DecodeFormRequestBody does
+//
+// Other media types can be added, and the default decoders can be replaced, by WithBodyDecoder.
+// Media types are compared without parameters, so "application/json; charset=utf-8" is decoded as application/json.
+//
+// This function reads the request body up to DefaultMaxRequestBodySize, or the size set by WithMaxBodySize.
+// If the request body exceeds this size, the function returns http.MaxBytesError.
+// If the Content-Type is absent or not supported, the function returns UnsupportedMediaTypeError
+// listing the accepted media types, which can be rendered by RenderUnsupportedMediaType.
+//
+// Unknown fields are rejected unless WithAllowUnknownFields is given.
+// If WithValidation is given, the decoded value is validated by Validate.
+//
+// The request body will be closed after decoding.
+// This function ignores any error returned by Close.
+func DecodeRequestBody[T any](r *http.Request, opts ...DecodeOption) (T, error) {
+	cfg := newDecodeConfig(DefaultMaxRequestBodySize, opts)
+	var zero T
+
+	decode, err := cfg.bodyDecoder(r.Header.Get("Content-Type"))
+	if err != nil {
+		return zero, err
+	}
+
+	var t T
+	if err := decode(r, &t, cfg); err != nil {
+		return zero, err
+	}
+
+	if err := cfg.validateIfEnabled(&t); err != nil {
+		return zero, err
+	}
+
+	return t, nil
+}
+
+// bodyDecoder returns the decoder of the content type.
+// Decoders given by WithBodyDecoder take precedence over the default decoders.
+func (cfg decodeConfig) bodyDecoder(contentType string) (func(r *http.Request, dst any, cfg decodeConfig) error, error) {
+	entries := slices.Clone(cfg.bodyDecoders)
+	slices.Reverse(entries) // the last registered decoder wins
+	entries = append(entries, defaultBodyDecoders...)
+
+	if mediaType, err := ParseMediaType(contentType); err == nil {
+		candidates := []string{mediaType.Type + "/" + mediaType.Subtype}
+		if _, suffix, ok := strings.Cut(mediaType.Subtype, "+"); ok {
+			candidates = append(candidates, "application/"+suffix)
+		}
+
+		for _, candidate := range candidates {
+			for _, entry := range entries {
+				if entry.mediaType == candidate {
+					return entry.decode, nil
+				}
+			}
+		}
+	}
+
+	accepted := make([]ContentType, 0, len(entries))
+	for _, entry := range entries {
+		if !slices.Contains(accepted, entry.mediaType) {
+			accepted = append(accepted, entry.mediaType)
+		}
+	}
+
+	return nil, &UnsupportedMediaTypeError{ContentType: contentType, Accepted: accepted}
+}
+
+// WithBodyDecoder registers the decoder of the media type for DecodeRequestBody.
+//
+// mediaType is compared without parameters, such as "application/cbor".
+// If a decoder of the same media type is already registered, including the default decoders, it is replaced.
+// The body given to the decoder is limited by the size limit of DecodeRequestBody.
+// If mediaType is not a valid media type or decoder is nil, the option is ignored.
+func WithBodyDecoder(mediaType ContentType, decoder BodyDecoder) DecodeOption {
+	return func(cfg *decodeConfig) {
+		parsed, err := ParseMediaType(mediaType)
+		if err != nil || decoder == nil {
+			return
+		}
+
+		cfg.bodyDecoders = append(cfg.bodyDecoders, bodyDecoderEntry{
+			mediaType: parsed.Type + "/" + parsed.Subtype,
+			decode: func(r *http.Request, dst any, cfg decodeConfig) error {
+				body := http.MaxBytesReader(nil, r.Body, cfg.maxBodySize)
+				defer body.Close()
+
+				return decoder(r, body, dst, cfg.unmarshalOptions())
+			},
+		})
+	}
+}
+
+// DecodeXMLRequestBody decodes request body to T using XML decoder.
+//
+// This function reads the request body up to DefaultMaxRequestBodySize, or the size set by WithMaxBodySize.
+// If the request body exceeds this size, the function returns http.MaxBytesError.
+// Only UTF-8 documents are supported; other encodings declared in the XML declaration are reported as an error.
+//
+// Unknown elements and attributes are always ignored, because encoding/xml cannot reject them.
+// If WithValidation is given, the decoded value is validated by Validate.
+//
+// The request body will be closed after decoding.
+// This function ignores any error returned by Close.
+func DecodeXMLRequestBody[T any](r *http.Request, opts ...DecodeOption) (T, error) {
+	cfg := newDecodeConfig(DefaultMaxRequestBodySize, opts)
+
+	var t T
+	if err := decodeXMLRequestBody(r, &t, cfg); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	if err := cfg.validateIfEnabled(&t); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return t, nil
+}
+
+func decodeXMLRequestBody(r *http.Request, dst any, cfg decodeConfig) error {
+	body := http.MaxBytesReader(nil, r.Body, cfg.maxBodySize)
+	defer body.Close()
+
+	return xml.NewDecoder(body).Decode(dst)
+}
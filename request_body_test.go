@@ -0,0 +1,296 @@
+package httplib_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bodyCommand struct {
+	XMLName xml.Name `json:"-" xml:"command"`
+	Name    string   `json:"name" xml:"name" form:"name" validate:"required"`
+	Count   int      `json:"count" xml:"count" form:"count"`
+}
+
+func assertUnsupportedMediaTypeFunc(expectedContentType string, expectedAccepted ...httplib.ContentType) assert.ErrorAssertionFunc {
+	return func(t assert.TestingT, err error, i ...interface{}) bool {
+		var unsupportedErr *httplib.UnsupportedMediaTypeError
+		if !assert.ErrorAs(t, err, &unsupportedErr) {
+			return false
+		}
+		return assert.ErrorIs(t, err, httplib.ErrUnsupportedMediaType) &&
+			assert.Equal(t, expectedContentType, unsupportedErr.ContentType) &&
+			assert.Equal(t, expectedAccepted, unsupportedErr.Accepted)
+	}
+}
+
+func TestDecodeRequestBody(t *testing.T) {
+	defaultAccepted := []httplib.ContentType{
+		httplib.ContentTypeJSON,
+		httplib.ContentTypeXML,
+		httplib.ContentTypeTextXML,
+		httplib.ContentTypeFormURLEncoded,
+	}
+
+	tests := []struct {
+		name         string
+		contentType  string
+		body         string
+		opts         []httplib.DecodeOption
+		want         bodyCommand
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: JSON",
+			contentType:  httplib.ContentTypeJSONUTF8,
+			body:         `{"name":"a","count":1}`,
+			want:         bodyCommand{Name: "a", Count: 1},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: JSON suffix",
+			contentType:  "application/merge-patch+json",
+			body:         `{"name":"a"}`,
+			want:         bodyCommand{Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: XML",
+			contentType:  httplib.ContentTypeXML,
+			body:         `<command><name>a</name><count>1</count></command>`,
+			want:         bodyCommand{XMLName: xml.Name{Local: "command"}, Name: "a", Count: 1},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: text/xml with charset",
+			contentType:  "text/xml; charset=utf-8",
+			body:         `<?xml version="1.0"?><command><name>a</name></command>`,
+			want:         bodyCommand{XMLName: xml.Name{Local: "command"}, Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: XML suffix",
+			contentType:  "application/atom+xml",
+			body:         `<command><name>a</name></command>`,
+			want:         bodyCommand{XMLName: xml.Name{Local: "command"}, Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: form",
+			contentType:  httplib.ContentTypeFormURLEncoded,
+			body:         "name=a&count=1",
+			want:         bodyCommand{Name: "a", Count: 1},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: unknown field in JSON",
+			contentType:  httplib.ContentTypeJSON,
+			body:         `{"name":"a","unknown":1}`,
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: unknown field in form",
+			contentType:  httplib.ContentTypeFormURLEncoded,
+			body:         "name=a&unknown=1",
+			errAssertion: assertFieldErrorsFunc("unknown"),
+		},
+		{
+			name:         "failure: validation",
+			contentType:  httplib.ContentTypeXML,
+			body:         `<command><count>1</count></command>`,
+			opts:         []httplib.DecodeOption{httplib.WithValidation()},
+			errAssertion: assertValidationErrorsFunc("/name"),
+		},
+		{
+			name:         "failure: exceeds max body size",
+			contentType:  httplib.ContentTypeXML,
+			body:         `<command><name>abcdefghij</name></command>`,
+			opts:         []httplib.DecodeOption{httplib.WithMaxBodySize(10)},
+			errAssertion: assertMaxBytesErrorFunc(10),
+		},
+		{
+			name:         "failure: missing Content-Type",
+			contentType:  "",
+			body:         `{"name":"a"}`,
+			errAssertion: assertUnsupportedMediaTypeFunc("", defaultAccepted...),
+		},
+		{
+			name:         "failure: unsupported Content-Type",
+			contentType:  "text/plain",
+			body:         "a",
+			errAssertion: assertUnsupportedMediaTypeFunc("text/plain", defaultAccepted...),
+		},
+		{
+			name:         "failure: invalid Content-Type",
+			contentType:  "json",
+			body:         `{"name":"a"}`,
+			errAssertion: assertUnsupportedMediaTypeFunc("json", defaultAccepted...),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			got, err := httplib.DecodeRequestBody[bodyCommand](r, tt.opts...)
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecodeRequestBody_WithBodyDecoder(t *testing.T) {
+	lineDecoder := func(name string) httplib.BodyDecoder {
+		return func(r *http.Request, body io.Reader, dst any, opts httplib.UnmarshalOptions) error {
+			data, err := io.ReadAll(body)
+			if err != nil {
+				return err
+			}
+			*dst.(*bodyCommand) = bodyCommand{Name: name + ":" + string(data)}
+			return nil
+		}
+	}
+
+	opts := []httplib.DecodeOption{
+		httplib.WithBodyDecoder("text/plain", lineDecoder("first")),
+		httplib.WithBodyDecoder("Text/Plain; charset=utf-8", lineDecoder("plain")),
+		httplib.WithBodyDecoder(httplib.ContentTypeJSON, lineDecoder("json")),
+		httplib.WithBodyDecoder("invalid", lineDecoder("invalid")),
+		httplib.WithBodyDecoder("application/cbor", nil),
+		httplib.WithMaxBodySize(5),
+	}
+
+	t.Run("custom media type", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("a"))
+		r.Header.Set("Content-Type", "text/plain")
+
+		got, err := httplib.DecodeRequestBody[bodyCommand](r, opts...)
+		require.NoError(t, err)
+		assert.Equal(t, bodyCommand{Name: "plain:a"}, got)
+	})
+
+	t.Run("replaced default decoder", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+		r.Header.Set("Content-Type", httplib.ContentTypeJSON)
+
+		got, err := httplib.DecodeRequestBody[bodyCommand](r, opts...)
+		require.NoError(t, err)
+		assert.Equal(t, bodyCommand{Name: "json:{}"}, got)
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("abcdef"))
+		r.Header.Set("Content-Type", "text/plain")
+
+		_, err := httplib.DecodeRequestBody[bodyCommand](r, opts...)
+		assertMaxBytesErrorFunc(5)(t, err)
+	})
+
+	t.Run("accepted media types", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+		r.Header.Set("Content-Type", "application/cbor")
+
+		_, err := httplib.DecodeRequestBody[bodyCommand](r, opts...)
+		assertUnsupportedMediaTypeFunc("application/cbor",
+			httplib.ContentTypeJSON,
+			httplib.ContentTypeTextPlain,
+			httplib.ContentTypeXML,
+			httplib.ContentTypeTextXML,
+			httplib.ContentTypeFormURLEncoded,
+		)(t, err)
+	})
+}
+
+func TestDecodeXMLRequestBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		opts         []httplib.DecodeOption
+		want         bodyCommand
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success",
+			body:         `<command><name>a</name><unknown>b</unknown></command>`,
+			want:         bodyCommand{XMLName: xml.Name{Local: "command"}, Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: invalid XML",
+			body:         `<command><name>a</command>`,
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: unsupported encoding",
+			body:         `<?xml version="1.0" encoding="Shift_JIS"?><command></command>`,
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: exceeds max body size",
+			body:         `<command><name>abcdefghij</name></command>`,
+			opts:         []httplib.DecodeOption{httplib.WithMaxBodySize(10)},
+			errAssertion: assertMaxBytesErrorFunc(10),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+
+			got, err := httplib.DecodeXMLRequestBody[bodyCommand](r, tt.opts...)
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRenderUnsupportedMediaType(t *testing.T) {
+	tests := []struct {
+		name       string
+		cause      error
+		wantBody   string
+		wantAccept string
+	}{
+		{
+			name:       "UnsupportedMediaTypeError",
+			cause:      &httplib.UnsupportedMediaTypeError{ContentType: "text/plain", Accepted: []httplib.ContentType{httplib.ContentTypeJSON, httplib.ContentTypeXML}},
+			wantBody:   "application/json\napplication/xml\n",
+			wantAccept: "application/json, application/xml",
+		},
+		{
+			name:  "other error",
+			cause: errors.New("unsupported media type"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res httplib.ResponseLog
+			ctx := httplib.WithResponseLogPtr(t.Context(), &res)
+
+			w := httptest.NewRecorder()
+			require.NoError(t, httplib.RenderUnsupportedMediaType(ctx, w, tt.cause))
+
+			assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.wantAccept, w.Header().Get("Accept"))
+
+			assert.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+			assert.Equal(t, tt.cause, res.Error)
+			assert.Equal(t, "github.com/Siroshun09/go-httplib_test.TestRenderUnsupportedMediaType.func1", res.HandlerInfo.FuncName)
+		})
+	}
+}
+
+func TestUnsupportedMediaTypeError_Error(t *testing.T) {
+	err := &httplib.UnsupportedMediaTypeError{ContentType: "text/plain", Accepted: []httplib.ContentType{httplib.ContentTypeJSON}}
+	assert.EqualError(t, err, `httplib: unsupported media type "text/plain": accepted media types are application/json`)
+}
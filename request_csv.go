@@ -0,0 +1,225 @@
+package httplib
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// CSVError is an error of a row of a CSV request body.
+type CSVError struct {
+	// Row is the 1-based number of the record in the body. The header row is row 1.
+	Row int
+
+	// Column is the 1-based number of the column. It is 0 if the error is not related to a specific column.
+	Column int
+
+	// Err is the cause of the error. It is a *FieldError if a cell cannot be bound to the struct field.
+	Err error
+}
+
+func (e *CSVError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+	}
+	return fmt.Sprintf("row %d, column %d: %v", e.Row, e.Column, e.Err)
+}
+
+func (e *CSVError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the CSVError as a JSON object with "row", "column", "field", "value" and "message",
+// so that CSVErrors can be rendered by JSONResponse. Zero "column" and empty "field" and "value" are omitted.
+func (e *CSVError) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Row     int    `json:"row"`
+		Column  int    `json:"column,omitempty"`
+		Field   string `json:"field,omitempty"`
+		Value   string `json:"value,omitempty"`
+		Message string `json:"message"`
+	}{
+		Row:     e.Row,
+		Column:  e.Column,
+		Message: e.Err.Error(),
+	}
+
+	var fieldErr *FieldError
+	if errors.As(e.Err, &fieldErr) {
+		v.Field, v.Value, v.Message = fieldErr.Field, fieldErr.Value, fieldErr.Err.Error()
+	}
+
+	return json.Marshal(v)
+}
+
+// CSVErrors is a list of CSVError reported together for a row.
+type CSVErrors []*CSVError
+
+func (e CSVErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e CSVErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// DecodeCSVRequestBody returns an iterator that decodes each row of a text/csv request body to T.
+//
+// The first row must be the header, whose cells are the column names. T must be a struct, and the columns are
+// bound to its fields by the "csv" struct tag in the same way as DecodeQuery binds the "query" struct tag.
+// A UTF-8 byte order mark at the beginning of the body is skipped. Every row must have as many cells as the header.
+// The delimiter is a comma unless WithDelimiter is given.
+//
+// This function reads the request body up to DefaultMaxStreamRequestBodySize, or the size set by WithMaxBodySize,
+// and each row up to DefaultMaxRequestItemSize, or the size set by WithMaxItemSize.
+// If either limit is exceeded, the iterator yields http.MaxBytesError wrapped by CSVError.
+// If the Content-Type is not text/csv, the iterator yields UnsupportedMediaTypeError.
+//
+// Columns of the header that do not match any field are rejected unless WithAllowUnknownFields is given.
+// Invalid cells of a row are reported together as CSVErrors, and the other errors are reported as CSVError.
+// If WithValidation is given, each decoded row is validated by Validate, and its errors are wrapped by CSVError.
+//
+// The iteration stops after the first error.
+// The request body will be closed when the iteration ends, so the returned iterator can be used only once.
+func DecodeCSVRequestBody[T any](r *http.Request, opts ...DecodeOption) iter.Seq2[T, error] {
+	cfg := newDecodeConfig(DefaultMaxStreamRequestBodySize, opts)
+
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		body := http.MaxBytesReader(nil, r.Body, cfg.maxBodySize)
+		defer body.Close()
+
+		if _, err := parseRequestMediaType(r, ContentTypeCSV); err != nil {
+			yield(zero, err)
+			return
+		}
+
+		fields, err := getBindFields(reflect.TypeFor[T](), SourceCSV)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		limited := &offsetLimitReader{r: body, itemLimit: cfg.maxItemSize}
+		buffered := bufio.NewReader(limited)
+
+		limited.limitFrom(0)
+		bomSize := 0
+		if bom, err := buffered.Peek(len(utf8BOM)); err == nil && string(bom) == utf8BOM {
+			bomSize, _ = buffered.Discard(len(utf8BOM))
+		}
+
+		reader := csv.NewReader(buffered)
+		reader.ReuseRecord = true
+		if cfg.delimiter != 0 {
+			reader.Comma = cfg.delimiter
+		}
+
+		header, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("missing header row")
+			}
+			yield(zero, &CSVError{Row: 1, Err: err})
+			return
+		}
+		header = append([]string(nil), header...) // the record is reused by the reader
+
+		if err := checkCSVHeader(header, fields, cfg.allowUnknownFields); err != nil {
+			yield(zero, err)
+			return
+		}
+
+		values := make(map[string][]string, len(header))
+		for row := 2; ; row++ {
+			limited.limitFrom(int64(bomSize) + reader.InputOffset())
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(zero, &CSVError{Row: row, Err: err})
+				return
+			}
+
+			clear(values)
+			for i, name := range header {
+				values[name] = append(values[name], record[i])
+			}
+
+			var t T
+			if fieldErrs := bindValues(reflect.ValueOf(&t).Elem(), fields, SourceCSV, values, nil); len(fieldErrs) != 0 {
+				yield(zero, toCSVErrors(row, header, fieldErrs))
+				return
+			}
+
+			if err := cfg.validateIfEnabled(&t); err != nil {
+				yield(zero, &CSVError{Row: row, Err: err})
+				return
+			}
+
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
+
+const utf8BOM = "\ufeff"
+
+func checkCSVHeader(header []string, fields []bindField, allowUnknownFields bool) error {
+	if allowUnknownFields {
+		return nil
+	}
+
+	var errs CSVErrors
+	for i, name := range header {
+		known := false
+		for _, field := range fields {
+			if field.key == name {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			errs = append(errs, &CSVError{Row: 1, Column: i + 1, Err: &FieldError{Source: SourceCSV, Field: name, Err: ErrUnknownField}})
+		}
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+func toCSVErrors(row int, header []string, fieldErrs FieldErrors) CSVErrors {
+	errs := make(CSVErrors, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		column := 0
+		for j, name := range header {
+			if name == fieldErr.Field {
+				column = j + 1
+				break
+			}
+		}
+		errs[i] = &CSVError{Row: row, Column: column, Err: fieldErr}
+	}
+	return errs
+}
@@ -0,0 +1,176 @@
+package httplib_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type csvRow struct {
+	ID     int       `csv:"id" validate:"min=1"`
+	Name   string    `csv:"name" validate:"required"`
+	Amount *float64  `csv:"amount"`
+	Date   time.Time `csv:"date" layout:"2006-01-02"`
+}
+
+func newCSVRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", httplib.ContentTypeCSVUTF8)
+	return r
+}
+
+func assertCSVErrorFunc(expectedRow, expectedColumn int) assert.ErrorAssertionFunc {
+	return func(t assert.TestingT, err error, i ...interface{}) bool {
+		var csvErr *httplib.CSVError
+		if !assert.ErrorAs(t, err, &csvErr) {
+			return false
+		}
+		return assert.Equal(t, expectedRow, csvErr.Row) && assert.Equal(t, expectedColumn, csvErr.Column)
+	}
+}
+
+func TestDecodeCSVRequestBody(t *testing.T) {
+	date := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		data         string
+		opts         []httplib.DecodeOption
+		want         []csvRow
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name: "success: multiple rows",
+			data: "id,name,amount,date\n1,a,1.5,2026-01-02\n2,b,,2026-01-02\n",
+			want: []csvRow{
+				{ID: 1, Name: "a", Amount: toPtr(1.5), Date: date},
+				{ID: 2, Name: "b", Date: date},
+			},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: BOM, CRLF and reordered columns",
+			data:         "\ufeffname,id\r\na,1\r\n",
+			want:         []csvRow{{ID: 1, Name: "a"}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: delimiter",
+			data:         "id;name\n1;a\n",
+			opts:         []httplib.DecodeOption{httplib.WithDelimiter(';')},
+			want:         []csvRow{{ID: 1, Name: "a"}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: header only",
+			data:         "id,name\n",
+			want:         nil,
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: unknown column allowed",
+			data:         "id,name,memo\n1,a,x\n",
+			opts:         []httplib.DecodeOption{httplib.WithAllowUnknownFields()},
+			want:         []csvRow{{ID: 1, Name: "a"}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: empty body",
+			data:         "",
+			errAssertion: assertCSVErrorFunc(1, 0),
+		},
+		{
+			name:         "failure: unknown column",
+			data:         "id,name,memo\n1,a,x\n",
+			errAssertion: assertCSVErrorFunc(1, 3),
+		},
+		{
+			name:         "failure: invalid cell in third row",
+			data:         "id,name,amount\n1,a,1\n2,b,abc\n",
+			want:         []csvRow{{ID: 1, Name: "a", Amount: toPtr(1.0)}},
+			errAssertion: assertCSVErrorFunc(3, 3),
+		},
+		{
+			name:         "failure: wrong number of fields",
+			data:         "id,name\n1,a,x\n",
+			errAssertion: assertCSVErrorFunc(2, 0),
+		},
+		{
+			name:         "failure: validation",
+			data:         "id,name\n0,a\n",
+			opts:         []httplib.DecodeOption{httplib.WithValidation()},
+			errAssertion: assertCSVErrorFunc(2, 0),
+		},
+		{
+			name:         "failure: body too large",
+			data:         "id,name\n1,a\n2,b\n",
+			opts:         []httplib.DecodeOption{httplib.WithMaxBodySize(12)},
+			want:         []csvRow{{ID: 1, Name: "a"}},
+			errAssertion: assertMaxBytesErrorFunc(12),
+		},
+		{
+			name:         "failure: row too large",
+			data:         "id,name\n1,a\n2," + strings.Repeat("b", 20) + "\n",
+			opts:         []httplib.DecodeOption{httplib.WithMaxItemSize(10)},
+			want:         []csvRow{{ID: 1, Name: "a"}},
+			errAssertion: assertMaxBytesErrorFunc(10),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := collectItems(httplib.DecodeCSVRequestBody[csvRow](newCSVRequest(tt.data), tt.opts...))
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, items)
+		})
+	}
+}
+
+func TestDecodeCSVRequestBody_UnsupportedMediaType(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("id\n1\n"))
+	r.Header.Set("Content-Type", httplib.ContentTypeJSON)
+
+	_, err := collectItems(httplib.DecodeCSVRequestBody[csvRow](r))
+	assertUnsupportedMediaTypeFunc(httplib.ContentTypeJSON, httplib.ContentTypeCSV)(t, err)
+}
+
+func TestDecodeCSVRequestBody_Break(t *testing.T) {
+	count := 0
+	for _, err := range httplib.DecodeCSVRequestBody[csvRow](newCSVRequest("id,name\n1,a\n2,b\n")) {
+		require.NoError(t, err)
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestCSVErrors_MarshalJSON(t *testing.T) {
+	_, err := collectItems(httplib.DecodeCSVRequestBody[csvRow](newCSVRequest("id,name\nx,a\n")))
+
+	var csvErrs httplib.CSVErrors
+	require.ErrorAs(t, err, &csvErrs)
+	assert.EqualError(t, csvErrs, csvErrs[0].Error())
+
+	data, err := json.Marshal(csvErrs)
+	require.NoError(t, err)
+
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Len(t, got, 1)
+	assert.EqualValues(t, 2, got[0]["row"])
+	assert.EqualValues(t, 1, got[0]["column"])
+	assert.Equal(t, "id", got[0]["field"])
+	assert.Equal(t, "x", got[0]["value"])
+	assert.NotEmpty(t, got[0]["message"])
+
+	data, err = json.Marshal(&httplib.CSVError{Row: 4, Err: errors.New("bad")})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"row":4,"message":"bad"}`, string(data))
+}
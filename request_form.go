@@ -0,0 +1,389 @@
+package httplib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+)
+
+const (
+	// DefaultMaxMultipartRequestBodySize is the default limit of the whole request body for DecodeMultipartRequestBody.
+	DefaultMaxMultipartRequestBodySize = 64 << 20 // 64MB
+
+	// DefaultMaxFileSize is the default limit of a single uploaded file for DecodeMultipartRequestBody.
+	DefaultMaxFileSize = 32 << 20 // 32MB
+
+	// DefaultMaxMemory is the default number of bytes of uploaded files kept in memory by DecodeMultipartRequestBody.
+	DefaultMaxMemory = 10 << 20 // 10MB
+)
+
+// DecodeFormRequestBody decodes an application/x-www-form-urlencoded request body to T.
+//
+// T must be a struct. Its fields are bound by the "form" struct tag in the same way as DecodeQuery
+// binds the "query" struct tag.
+//
+// This function reads the request body up to DefaultMaxRequestBodySize, or the size set by WithMaxBodySize.
+// If the request body exceeds this size, the function returns http.MaxBytesError.
+// If the Content-Type is not application/x-www-form-urlencoded, the function returns ErrUnsupportedMediaType.
+//
+// Unknown fields are rejected unless WithAllowUnknownFields is given.
+// Invalid or unknown fields are reported together as FieldErrors.
+// If WithValidation is given, the decoded value is validated by Validate.
+//
+// The request body will be closed after decoding.
+// This function ignores any error returned by Close.
+func DecodeFormRequestBody[T any](r *http.Request, opts ...DecodeOption) (T, error) {
+	cfg := newDecodeConfig(DefaultMaxRequestBodySize, opts)
+	var zero T
+
+	if _, err := parseRequestMediaType(r, ContentTypeFormURLEncoded); err != nil {
+		return zero, err
+	}
+
+	var t T
+	if err := decodeFormRequestBody(r, &t, cfg); err != nil {
+		return zero, err
+	}
+
+	if err := cfg.validateIfEnabled(&t); err != nil {
+		return zero, err
+	}
+
+	return t, nil
+}
+
+func decodeFormRequestBody(r *http.Request, dst any, cfg decodeConfig) error {
+	body := http.MaxBytesReader(nil, r.Body, cfg.maxBodySize)
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	return bindForm(dst, values, nil, cfg.allowUnknownFields)
+}
+
+// DecodeMultipartRequestBody decodes a multipart/form-data request body to T.
+//
+// Form values are bound in the same way as DecodeFormRequestBody.
+// Uploaded files are bound to fields of type *FormFile or []*FormFile by the "form" struct tag.
+//
+// This function reads the request body up to DefaultMaxMultipartRequestBodySize, or the size set by WithMaxBodySize,
+// each uploaded file up to DefaultMaxFileSize, or the size set by WithMaxFileSize,
+// and each form value up to DefaultMaxRequestItemSize, or the size set by WithMaxItemSize.
+// If any of these limits is exceeded, the function returns http.MaxBytesError.
+// If the Content-Type is not multipart/form-data, the function returns ErrUnsupportedMediaType.
+//
+// Uploaded files are kept in memory up to DefaultMaxMemory in total, or the size set by WithMaxMemory,
+// and the rest are stored in temporary files. The temporary files are removed when the request context is done,
+// or when FormFile.Remove is called.
+//
+// Unknown fields are rejected unless WithAllowUnknownFields is given.
+// Invalid or unknown fields are reported together as FieldErrors.
+// If WithValidation is given, the decoded value is validated by Validate.
+//
+// The request body will be closed after decoding.
+// This function ignores any error returned by Close.
+func DecodeMultipartRequestBody[T any](r *http.Request, opts ...DecodeOption) (T, error) {
+	cfg := newDecodeConfig(DefaultMaxMultipartRequestBodySize, opts)
+	var zero T
+
+	params, err := parseRequestMediaType(r, ContentTypeMultipartFormData)
+	if err != nil {
+		return zero, err
+	}
+
+	boundary := params["boundary"]
+	if boundary == "" {
+		return zero, http.ErrMissingBoundary
+	}
+
+	body := http.MaxBytesReader(nil, r.Body, cfg.maxBodySize)
+	defer body.Close()
+
+	values, files, err := readMultipartForm(multipart.NewReader(body, boundary), cfg)
+	if err != nil {
+		removeFormFiles(files)
+		return zero, err
+	}
+
+	var t T
+	if err := bindForm(&t, values, files, cfg.allowUnknownFields); err != nil {
+		removeFormFiles(files)
+		return zero, err
+	}
+
+	if err := cfg.validateIfEnabled(&t); err != nil {
+		removeFormFiles(files)
+		return zero, err
+	}
+
+	if len(files) != 0 {
+		context.AfterFunc(r.Context(), func() {
+			removeFormFiles(files)
+		})
+	}
+
+	return t, nil
+}
+
+// parseRequestMediaType checks that the media type of the request is expected and returns its parameters.
+func parseRequestMediaType(r *http.Request, expected ContentType) (map[string]string, error) {
+	contentType := r.Header.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != expected {
+		return nil, &UnsupportedMediaTypeError{ContentType: contentType, Accepted: []ContentType{expected}}
+	}
+	return params, nil
+}
+
+func bindForm(dst any, values url.Values, files map[string][]*FormFile, allowUnknownFields bool) error {
+	v := reflect.ValueOf(dst).Elem()
+
+	fields, err := getBindFields(v.Type(), SourceForm)
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]struct{}, len(values)+len(files))
+	errs := bindValues(v, fields, SourceForm, values, used)
+
+	for _, field := range fields {
+		if !field.file {
+			continue
+		}
+
+		fieldFiles, ok := files[field.key]
+		if !ok {
+			if field.required {
+				errs = append(errs, &FieldError{Source: SourceForm, Field: field.key, Err: ErrRequired})
+			}
+			continue
+		}
+
+		used[field.key] = struct{}{}
+
+		fv := fieldByIndex(v, field.index)
+		if fv.Kind() == reflect.Slice {
+			fv.Set(reflect.ValueOf(fieldFiles))
+		} else {
+			fv.Set(reflect.ValueOf(fieldFiles[0]))
+		}
+	}
+
+	if !allowUnknownFields {
+		errs = append(errs, unknownFieldErrors(used, values, files)...)
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func unknownFieldErrors(used map[string]struct{}, values url.Values, files map[string][]*FormFile) FieldErrors {
+	var keys []string
+
+	for key := range values {
+		if _, ok := used[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	for key := range files {
+		if _, ok := used[key]; !ok && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys) // for stable error messages
+
+	errs := make(FieldErrors, len(keys))
+	for i, key := range keys {
+		errs[i] = &FieldError{Source: SourceForm, Field: key, Err: ErrUnknownField}
+	}
+	return errs
+}
+
+func readMultipartForm(reader *multipart.Reader, cfg decodeConfig) (url.Values, map[string][]*FormFile, error) {
+	values := make(url.Values)
+	files := make(map[string][]*FormFile)
+	memoryRemaining := cfg.maxMemory
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return values, files, nil
+		}
+		if err != nil {
+			return values, files, err
+		}
+
+		name := part.FormName()
+		if name == "" {
+			_ = part.Close()
+			continue
+		}
+
+		if part.FileName() == "" {
+			value, err := readLimited(part, cfg.maxItemSize)
+			_ = part.Close()
+			if err != nil {
+				return values, files, err
+			}
+			values.Add(name, string(value))
+			continue
+		}
+
+		file, err := readFormFile(part, cfg.maxFileSize, &memoryRemaining)
+		_ = part.Close()
+		if err != nil {
+			return values, files, err
+		}
+		files[name] = append(files[name], file)
+	}
+}
+
+// readLimited reads all data from r, and returns http.MaxBytesError if the data exceeds limit.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limit {
+		return nil, &http.MaxBytesError{Limit: limit}
+	}
+
+	return data, nil
+}
+
+// readFormFile reads an uploaded file in memory if it fits in memoryRemaining, otherwise stores it in a temporary file.
+func readFormFile(part *multipart.Part, maxFileSize int64, memoryRemaining *int64) (*FormFile, error) {
+	file := &FormFile{
+		Filename: part.FileName(),
+		Header:   part.Header,
+	}
+
+	memoryLimit := min(*memoryRemaining, maxFileSize)
+
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, part, memoryLimit+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if n <= memoryLimit {
+		*memoryRemaining -= n
+		file.content = buf.Bytes()
+		file.Size = n
+		return file, nil
+	}
+
+	if n > maxFileSize {
+		return nil, &http.MaxBytesError{Limit: maxFileSize}
+	}
+
+	tmp, err := os.CreateTemp("", "httplib-multipart-")
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := io.Copy(tmp, io.MultiReader(&buf, io.LimitReader(part, maxFileSize-n+1)))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil && size > maxFileSize {
+		err = &http.MaxBytesError{Limit: maxFileSize}
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	file.tmpFile = tmp.Name()
+	file.Size = size
+	return file, nil
+}
+
+func removeFormFiles(files map[string][]*FormFile) {
+	for _, fieldFiles := range files {
+		for _, file := range fieldFiles {
+			_ = file.Remove()
+		}
+	}
+}
+
+// FormFile is a file uploaded in a multipart/form-data request.
+//
+// The content is kept in memory or in a temporary file, depending on its size.
+type FormFile struct {
+	// Filename is the name of the file sent by the client, without the directory.
+	Filename string
+
+	// Header is the MIME header of the part.
+	Header textproto.MIMEHeader
+
+	// Size is the size of the file in bytes.
+	Size int64
+
+	content []byte
+	tmpFile string
+}
+
+// ContentType returns the Content-Type of the file sent by the client.
+func (f *FormFile) ContentType() string {
+	return f.Header.Get("Content-Type")
+}
+
+// Open returns a reader of the file content.
+//
+// The returned multipart.File must be closed by the caller.
+func (f *FormFile) Open() (multipart.File, error) {
+	if f.tmpFile != "" {
+		return os.Open(f.tmpFile)
+	}
+
+	return sectionReadCloser{io.NewSectionReader(bytes.NewReader(f.content), 0, int64(len(f.content)))}, nil
+}
+
+// Remove removes the temporary file that holds the content, if any.
+//
+// After calling this method, Open can no longer be used for a file stored in a temporary file.
+func (f *FormFile) Remove() error {
+	if f.tmpFile == "" {
+		return nil
+	}
+
+	err := os.Remove(f.tmpFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+type sectionReadCloser struct {
+	*io.SectionReader
+}
+
+func (sectionReadCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,381 @@
+package httplib_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type formObject struct {
+	Name     string    `form:"name"`
+	Age      int       `form:"age"`
+	Score    float64   `form:"score"`
+	Agree    bool      `form:"agree"`
+	Tags     []string  `form:"tag"`
+	IDs      []uint    `form:"id"`
+	Nickname *string   `form:"nickname"`
+	Born     time.Time `form:"born" layout:"2006-01-02"`
+	Updated  time.Time `form:"updated"`
+	Ignored  string    `form:"-"`
+	Untagged string
+}
+
+func newFormRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", httplib.ContentTypeFormURLEncoded)
+	return r
+}
+
+func assertFieldErrorsFunc(expectedFields ...string) assert.ErrorAssertionFunc {
+	return func(t assert.TestingT, err error, i ...interface{}) bool {
+		var fieldErrs httplib.FieldErrors
+		if !assert.ErrorAs(t, err, &fieldErrs) {
+			return false
+		}
+
+		fields := make([]string, len(fieldErrs))
+		for i, fieldErr := range fieldErrs {
+			fields[i] = fieldErr.Field
+		}
+		return assert.Equal(t, expectedFields, fields)
+	}
+}
+
+func TestDecodeFormRequestBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		opts         []httplib.DecodeOption
+		want         formObject
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name: "success: all fields",
+			data: "name=alice&age=20&score=1.5&agree=on&tag=a&tag=b&id=1&id=2&nickname=al&born=2000-01-02&updated=2024-12-31T23:59:59Z",
+			want: formObject{
+				Name:     "alice",
+				Age:      20,
+				Score:    1.5,
+				Agree:    true,
+				Tags:     []string{"a", "b"},
+				IDs:      []uint{1, 2},
+				Nickname: toPtr("al"),
+				Born:     time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+				Updated:  time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC),
+			},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: empty body",
+			data:         "",
+			want:         formObject{},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: empty values are treated as absent",
+			data:         "name=&age=&nickname=&tag=",
+			want:         formObject{Tags: []string{}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: only first value is used for non-slice",
+			data:         "name=a&name=b",
+			want:         formObject{Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: invalid values are reported together",
+			data:         "age=abc&agree=maybe&id=-1&born=2000/01/02",
+			want:         formObject{},
+			errAssertion: assertFieldErrorsFunc("age", "agree", "id", "born"),
+		},
+		{
+			name:         "failure: out of range",
+			data:         "age=99999999999999999999",
+			want:         formObject{},
+			errAssertion: assertFieldErrorsFunc("age"),
+		},
+		{
+			name:         "failure: unknown fields",
+			data:         "name=a&unknown=b&Untagged=c&Ignored=d",
+			want:         formObject{},
+			errAssertion: assertFieldErrorsFunc("Ignored", "Untagged", "unknown"),
+		},
+		{
+			name:         "success: unknown fields are allowed",
+			data:         "name=a&unknown=b",
+			opts:         []httplib.DecodeOption{httplib.WithAllowUnknownFields()},
+			want:         formObject{Name: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: invalid query",
+			data:         "name=%zz",
+			want:         formObject{},
+			errAssertion: assert.Error,
+		},
+		{
+			name:         "failure: exceeds max body size",
+			data:         "name=abcdef",
+			opts:         []httplib.DecodeOption{httplib.WithMaxBodySize(10)},
+			want:         formObject{},
+			errAssertion: assertMaxBytesErrorFunc(10),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := httplib.DecodeFormRequestBody[formObject](newFormRequest(tt.data), tt.opts...)
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecodeFormRequestBody_FieldErrorMessage(t *testing.T) {
+	_, err := httplib.DecodeFormRequestBody[formObject](newFormRequest("age=abc&unknown=a"))
+	assert.EqualError(t, err, `form "age": invalid value "abc": invalid syntax; form "unknown": unknown field`)
+	assert.ErrorIs(t, err, httplib.ErrUnknownField)
+}
+
+func TestDecodeFormRequestBody_ContentType(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentType  string
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: with charset",
+			contentType:  "application/x-www-form-urlencoded; charset=utf-8",
+			errAssertion: assert.NoError,
+		},
+		{
+			name:        "failure: json",
+			contentType: httplib.ContentTypeJSON,
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, httplib.ErrUnsupportedMediaType)
+			},
+		},
+		{
+			name:        "failure: missing",
+			contentType: "",
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, httplib.ErrUnsupportedMediaType)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=a"))
+			r.Header.Set("Content-Type", tt.contentType)
+			_, err := httplib.DecodeFormRequestBody[formObject](r)
+			tt.errAssertion(t, err)
+		})
+	}
+}
+
+func TestDecodeFormRequestBody_UnsupportedType(t *testing.T) {
+	type unsupported struct {
+		Map map[string]string `form:"map"`
+	}
+
+	_, err := httplib.DecodeFormRequestBody[unsupported](newFormRequest("map=a"))
+	assert.EqualError(t, err, "httplib: unsupported type map[string]string of field Map")
+
+	_, err = httplib.DecodeFormRequestBody[string](newFormRequest("a=b"))
+	assert.EqualError(t, err, "httplib: cannot bind to non-struct type string")
+}
+
+func TestDecodeFormRequestBody_ReadError(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", errorReader{err: errors.New("read error")})
+	r.Header.Set("Content-Type", httplib.ContentTypeFormURLEncoded)
+
+	_, err := httplib.DecodeFormRequestBody[formObject](r)
+	assert.EqualError(t, err, "read error")
+}
+
+type multipartObject struct {
+	Title       string              `form:"title"`
+	Avatar      *httplib.FormFile   `form:"avatar"`
+	Attachments []*httplib.FormFile `form:"attachment"`
+}
+
+type multipartPart struct {
+	name     string
+	filename string
+	content  string
+}
+
+func newMultipartRequest(t *testing.T, parts ...multipartPart) *http.Request {
+	t.Helper()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, part := range parts {
+		var w io.Writer
+		var err error
+		if part.filename == "" {
+			w, err = mw.CreateFormField(part.name)
+		} else {
+			w, err = mw.CreateFormFile(part.name, part.filename)
+		}
+		require.NoError(t, err)
+		_, err = io.WriteString(w, part.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func readFormFile(t *testing.T, file *httplib.FormFile) string {
+	t.Helper()
+
+	f, err := file.Open()
+	require.NoError(t, err)
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestDecodeMultipartRequestBody(t *testing.T) {
+	r := newMultipartRequest(t,
+		multipartPart{name: "title", content: "hello"},
+		multipartPart{name: "avatar", filename: "avatar.png", content: "png"},
+		multipartPart{name: "attachment", filename: "a.txt", content: "aaa"},
+		multipartPart{name: "attachment", filename: "dir/b.txt", content: "bbbb"},
+	)
+
+	got, err := httplib.DecodeMultipartRequestBody[multipartObject](r)
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello", got.Title)
+
+	require.NotNil(t, got.Avatar)
+	assert.Equal(t, "avatar.png", got.Avatar.Filename)
+	assert.Equal(t, httplib.ContentTypeOctetStream, got.Avatar.ContentType())
+	assert.EqualValues(t, 3, got.Avatar.Size)
+	assert.Equal(t, "png", readFormFile(t, got.Avatar))
+
+	require.Len(t, got.Attachments, 2)
+	assert.Equal(t, "a.txt", got.Attachments[0].Filename)
+	assert.Equal(t, "aaa", readFormFile(t, got.Attachments[0]))
+	assert.Equal(t, "b.txt", got.Attachments[1].Filename)
+	assert.Equal(t, "bbbb", readFormFile(t, got.Attachments[1]))
+}
+
+func TestDecodeMultipartRequestBody_TemporaryFile(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	r := newMultipartRequest(t,
+		multipartPart{name: "avatar", filename: "small.txt", content: "12345"},
+		multipartPart{name: "attachment", filename: "large.txt", content: "1234567890"},
+	).WithContext(ctx)
+
+	got, err := httplib.DecodeMultipartRequestBody[multipartObject](r, httplib.WithMaxMemory(8))
+	require.NoError(t, err)
+
+	// the first file fits in memory, and the second one is stored in a temporary file
+	assert.Equal(t, "12345", readFormFile(t, got.Avatar))
+	require.Len(t, got.Attachments, 1)
+	assert.Equal(t, "1234567890", readFormFile(t, got.Attachments[0]))
+	assert.EqualValues(t, 10, got.Attachments[0].Size)
+
+	f, err := got.Attachments[0].Open()
+	require.NoError(t, err)
+	tmpFile, ok := f.(*os.File)
+	require.True(t, ok)
+	require.NoError(t, f.Close())
+
+	// temporary files are removed after the request context is done
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(tmpFile.Name())
+		return errors.Is(err, os.ErrNotExist)
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, got.Attachments[0].Remove())
+	assert.NoError(t, got.Avatar.Remove())
+}
+
+func TestDecodeMultipartRequestBody_Error(t *testing.T) {
+	tests := []struct {
+		name         string
+		parts        []multipartPart
+		opts         []httplib.DecodeOption
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "failure: file exceeds max file size in memory",
+			parts:        []multipartPart{{name: "avatar", filename: "a.txt", content: "123456"}},
+			opts:         []httplib.DecodeOption{httplib.WithMaxFileSize(5)},
+			errAssertion: assertMaxBytesErrorFunc(5),
+		},
+		{
+			name:         "failure: file exceeds max file size in temporary file",
+			parts:        []multipartPart{{name: "avatar", filename: "a.txt", content: "123456"}},
+			opts:         []httplib.DecodeOption{httplib.WithMaxFileSize(5), httplib.WithMaxMemory(0)},
+			errAssertion: assertMaxBytesErrorFunc(5),
+		},
+		{
+			name:         "failure: value exceeds max item size",
+			parts:        []multipartPart{{name: "title", content: "123456"}},
+			opts:         []httplib.DecodeOption{httplib.WithMaxItemSize(5)},
+			errAssertion: assertMaxBytesErrorFunc(5),
+		},
+		{
+			name:         "failure: body exceeds max body size",
+			parts:        []multipartPart{{name: "title", content: strings.Repeat("a", 100)}},
+			opts:         []httplib.DecodeOption{httplib.WithMaxBodySize(50)},
+			errAssertion: assertMaxBytesErrorFunc(50),
+		},
+		{
+			name:         "failure: unknown fields",
+			parts:        []multipartPart{{name: "unknown", content: "a"}, {name: "unknown_file", filename: "a.txt", content: "a"}},
+			errAssertion: assertFieldErrorsFunc("unknown", "unknown_file"),
+		},
+		{
+			name:         "success: unknown fields are allowed",
+			parts:        []multipartPart{{name: "unknown", content: "a"}, {name: "unknown_file", filename: "a.txt", content: "a"}},
+			opts:         []httplib.DecodeOption{httplib.WithAllowUnknownFields()},
+			errAssertion: assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newMultipartRequest(t, tt.parts...)
+			_, err := httplib.DecodeMultipartRequestBody[multipartObject](r, tt.opts...)
+			tt.errAssertion(t, err)
+		})
+	}
+}
+
+func TestDecodeMultipartRequestBody_ContentType(t *testing.T) {
+	t.Run("failure: not multipart", func(t *testing.T) {
+		_, err := httplib.DecodeMultipartRequestBody[multipartObject](newFormRequest("title=a"))
+		assert.ErrorIs(t, err, httplib.ErrUnsupportedMediaType)
+	})
+
+	t.Run("failure: missing boundary", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+		r.Header.Set("Content-Type", httplib.ContentTypeMultipartFormData)
+		_, err := httplib.DecodeMultipartRequestBody[multipartObject](r)
+		assert.ErrorIs(t, err, http.ErrMissingBoundary)
+	})
+}
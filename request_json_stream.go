@@ -0,0 +1,265 @@
+package httplib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+)
+
+const (
+	// DefaultMaxStreamRequestBodySize is the default limit of the whole request body for streaming decoders.
+	DefaultMaxStreamRequestBodySize = 64 << 20 // 64MB
+
+	// DefaultMaxRequestItemSize is the default limit of a single item for streaming decoders.
+	DefaultMaxRequestItemSize = DefaultMaxRequestBodySize
+)
+
+var (
+	// ErrNotJSONArray is returned by DecodeJSONArray when the request body is not a JSON array.
+	ErrNotJSONArray = errors.New("httplib: request body is not a JSON array")
+
+	// ErrTrailingData is returned when the request body has data after the decoded JSON value.
+	ErrTrailingData = errors.New("httplib: unexpected data after JSON value")
+)
+
+// ItemError is an error that occurred while decoding an item of a streamed request body.
+type ItemError struct {
+	// Index is the zero-based index of the item.
+	Index int
+
+	// Err is the cause of the error.
+	Err error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeJSONLines returns an iterator that decodes each line of a newline-delimited JSON (NDJSON) request body to T.
+//
+// This function reads the request body up to DefaultMaxStreamRequestBodySize, or the size set by WithMaxBodySize,
+// and each line up to DefaultMaxRequestItemSize, or the size set by WithMaxItemSize.
+// If either limit is exceeded, the iterator yields http.MaxBytesError.
+// Blank lines are skipped and do not count as items.
+//
+// Unknown fields are rejected unless WithAllowUnknownFields is given.
+// If WithJSONSchema is given, each item is validated against the schema before it is decoded.
+// If WithValidation is given, each decoded item is validated by Validate.
+//
+// Errors for an item are wrapped by ItemError. The iteration stops after the first error.
+// The request body will be closed when the iteration ends, so the returned iterator can be used only once.
+func DecodeJSONLines[T any](r *http.Request, opts ...DecodeOption) iter.Seq2[T, error] {
+	cfg := newDecodeConfig(DefaultMaxStreamRequestBodySize, opts)
+
+	return func(yield func(T, error) bool) {
+		body := http.MaxBytesReader(nil, r.Body, cfg.maxBodySize)
+		defer body.Close()
+
+		reader := bufio.NewReader(body)
+		codec := GetCodecFromContext(r.Context())
+		var zero T
+
+		for index := 0; ; {
+			line, err := readLine(reader, cfg.maxItemSize)
+			if err != nil && !errors.Is(err, io.EOF) {
+				yield(zero, &ItemError{Index: index, Err: err})
+				return
+			}
+
+			if trimmed := bytes.TrimSpace(line); len(trimmed) != 0 {
+				t, decodeErr := decodeJSONItem[T](trimmed, codec, cfg)
+				if decodeErr != nil {
+					yield(zero, &ItemError{Index: index, Err: decodeErr})
+					return
+				}
+
+				if !yield(t, nil) {
+					return
+				}
+
+				index++
+			}
+
+			if err != nil { // io.EOF
+				return
+			}
+		}
+	}
+}
+
+// readLine reads a line without the trailing newline.
+//
+// If the line exceeds limit, it returns http.MaxBytesError.
+func readLine(reader *bufio.Reader, limit int64) ([]byte, error) {
+	var line []byte
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if int64(len(line)+len(chunk)) > limit+1 { // +1 for the newline
+			return nil, &http.MaxBytesError{Limit: limit}
+		}
+
+		line = append(line, chunk...)
+
+		switch {
+		case err == nil:
+			return line[:len(line)-1], nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		default:
+			if int64(len(line)) > limit {
+				return nil, &http.MaxBytesError{Limit: limit}
+			}
+			return line, err
+		}
+	}
+}
+
+func decodeJSONItem[T any](data []byte, codec Codec, cfg decodeConfig) (T, error) {
+	if err := cfg.validateSchemaIfEnabled(data); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	decoder := codec.NewDecoder(bytes.NewReader(data), cfg.unmarshalOptions())
+
+	var t T
+	if err := decoder.Decode(&t); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	if len(bytes.TrimSpace(data[decoder.InputOffset():])) != 0 {
+		var zero T
+		return zero, ErrTrailingData
+	}
+
+	if err := cfg.validateIfEnabled(&t); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return t, nil
+}
+
+// DecodeJSONArray returns an iterator that decodes each element of a top-level JSON array in the request body to T.
+//
+// Elements are decoded one by one, so the whole array is never held in memory.
+//
+// This function reads the request body up to DefaultMaxStreamRequestBodySize, or the size set by WithMaxBodySize,
+// and each element up to DefaultMaxRequestItemSize, or the size set by WithMaxItemSize.
+// If either limit is exceeded, the iterator yields http.MaxBytesError.
+// If the request body is not a JSON array, the iterator yields ErrNotJSONArray.
+//
+// Unknown fields are rejected unless WithAllowUnknownFields is given.
+// If WithJSONSchema is given, each item is validated against the schema before it is decoded.
+// If WithValidation is given, each decoded item is validated by Validate.
+//
+// Errors for an element are wrapped by ItemError. The iteration stops after the first error.
+// The request body will be closed when the iteration ends, so the returned iterator can be used only once.
+func DecodeJSONArray[T any](r *http.Request, opts ...DecodeOption) iter.Seq2[T, error] {
+	cfg := newDecodeConfig(DefaultMaxStreamRequestBodySize, opts)
+
+	return func(yield func(T, error) bool) {
+		body := http.MaxBytesReader(nil, r.Body, cfg.maxBodySize)
+		defer body.Close()
+
+		limited := &offsetLimitReader{r: body, itemLimit: cfg.maxItemSize}
+		reader := &readErrorReader{r: limited}
+		decoder := json.NewDecoder(reader) // only splits the array into elements, which are decoded by the Codec
+		codec := GetCodecFromContext(r.Context())
+
+		var zero T
+
+		limited.limitFrom(0)
+		token, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = ErrNotJSONArray
+			}
+			yield(zero, reader.cause(err))
+			return
+		}
+
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			yield(zero, ErrNotJSONArray)
+			return
+		}
+
+		for index := 0; ; index++ {
+			limited.limitFrom(decoder.InputOffset())
+			if !decoder.More() {
+				break
+			}
+
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				yield(zero, &ItemError{Index: index, Err: reader.cause(err)})
+				return
+			}
+
+			t, err := decodeJSONItem[T](raw, codec, cfg)
+			if err != nil {
+				yield(zero, &ItemError{Index: index, Err: err})
+				return
+			}
+
+			if !yield(t, nil) {
+				return
+			}
+		}
+
+		if _, err := decoder.Token(); err != nil { // closing ']'
+			yield(zero, reader.cause(err))
+			return
+		}
+
+		limited.limitFrom(decoder.InputOffset())
+		if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+			err = reader.cause(err)
+			var maxBytesErr *http.MaxBytesError
+			if !errors.As(err, &maxBytesErr) {
+				err = ErrTrailingData
+			}
+			yield(zero, err)
+		}
+	}
+}
+
+// offsetLimitReader limits the number of bytes that can be read past the given offset.
+//
+// Since json.Decoder buffers the input, the limit is applied to the absolute offset in the stream
+// rather than to each call of Read.
+type offsetLimitReader struct {
+	r         io.Reader
+	itemLimit int64
+	read      int64
+	max       int64
+}
+
+func (l *offsetLimitReader) limitFrom(offset int64) {
+	l.max = offset + l.itemLimit
+}
+
+func (l *offsetLimitReader) Read(p []byte) (int, error) {
+	if l.read >= l.max {
+		return 0, &http.MaxBytesError{Limit: l.itemLimit}
+	}
+
+	if remaining := l.max - l.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	return n, err
+}
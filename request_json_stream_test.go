@@ -0,0 +1,308 @@
+package httplib_test
+
+import (
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func collectItems[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var items []T
+	for item, err := range seq {
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func assertItemErrorFunc(expectedIndex int) assert.ErrorAssertionFunc {
+	return func(t assert.TestingT, err error, i ...interface{}) bool {
+		var itemErr *httplib.ItemError
+		if !assert.ErrorAs(t, err, &itemErr) {
+			return false
+		}
+		return assert.Equal(t, expectedIndex, itemErr.Index)
+	}
+}
+
+func TestDecodeJSONLines(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		opts         []httplib.DecodeOption
+		want         []streamItem
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: multiple lines",
+			data:         "{\"id\":1,\"name\":\"a\"}\n{\"id\":2,\"name\":\"b\"}\n",
+			want:         []streamItem{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: no trailing newline",
+			data:         "{\"id\":1,\"name\":\"a\"}\n{\"id\":2,\"name\":\"b\"}",
+			want:         []streamItem{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: CRLF and blank lines",
+			data:         "{\"id\":1,\"name\":\"a\"}\r\n\r\n\n{\"id\":2,\"name\":\"b\"}\r\n",
+			want:         []streamItem{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: empty body",
+			data:         "",
+			want:         nil,
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: invalid json in second line",
+			data:         "{\"id\":1,\"name\":\"a\"}\n{\"id\":\n",
+			want:         []streamItem{{ID: 1, Name: "a"}},
+			errAssertion: assertItemErrorFunc(1),
+		},
+		{
+			name:         "failure: unknown field",
+			data:         "{\"id\":1,\"unknown\":true}\n",
+			want:         nil,
+			errAssertion: assertItemErrorFunc(0),
+		},
+		{
+			name:         "success: unknown field is allowed",
+			data:         "{\"id\":1,\"unknown\":true}\n",
+			opts:         []httplib.DecodeOption{httplib.WithAllowUnknownFields()},
+			want:         []streamItem{{ID: 1}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name: "failure: multiple values in a line",
+			data: "{\"id\":1} {\"id\":2}\n",
+			want: nil,
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assertItemErrorFunc(0)(t, err) && assert.ErrorIs(t, err, httplib.ErrTrailingData)
+			},
+		},
+		{
+			name:         "success: line equal to max item size",
+			data:         "{\"id\":1}\n{\"id\":22}\n",
+			opts:         []httplib.DecodeOption{httplib.WithMaxItemSize(9)},
+			want:         []streamItem{{ID: 1}, {ID: 22}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name: "failure: line exceeds max item size",
+			data: "{\"id\":1}\n{\"id\":333}\n",
+			opts: []httplib.DecodeOption{httplib.WithMaxItemSize(9)},
+			want: []streamItem{{ID: 1}},
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assertItemErrorFunc(1)(t, err) && assertMaxBytesErrorFunc(9)(t, err)
+			},
+		},
+		{
+			name: "failure: last line without newline exceeds max item size",
+			data: "{\"id\":333}",
+			opts: []httplib.DecodeOption{httplib.WithMaxItemSize(9)},
+			want: nil,
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assertItemErrorFunc(0)(t, err) && assertMaxBytesErrorFunc(9)(t, err)
+			},
+		},
+		{
+			name: "failure: body exceeds max body size",
+			data: "{\"id\":1}\n{\"id\":2}\n",
+			opts: []httplib.DecodeOption{httplib.WithMaxBodySize(12)},
+			want: []streamItem{{ID: 1}},
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assertItemErrorFunc(1)(t, err) && assertMaxBytesErrorFunc(12)(t, err)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.data))
+			got, err := collectItems(httplib.DecodeJSONLines[streamItem](r, tt.opts...))
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecodeJSONLines_LongLine(t *testing.T) {
+	name := strings.Repeat("a", 10000) // longer than the buffer of bufio.Reader
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"`+name+`"}`+"\n"))
+
+	got, err := collectItems(httplib.DecodeJSONLines[streamItem](r))
+	require.NoError(t, err)
+	assert.Equal(t, []streamItem{{Name: name}}, got)
+}
+
+func TestDecodeJSONLines_Break(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("1\n2\n3\n"))
+
+	var got []int
+	for item, err := range httplib.DecodeJSONLines[int](r) {
+		require.NoError(t, err)
+		got = append(got, item)
+		if len(got) == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, []int{1, 2}, got)
+}
+
+func TestDecodeJSONLines_ReadError(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", errorReader{err: errors.New("read error")})
+
+	got, err := collectItems(httplib.DecodeJSONLines[int](r))
+	assert.Empty(t, got)
+	assert.EqualError(t, err, "item 0: read error")
+}
+
+func TestDecodeJSONArray(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		opts         []httplib.DecodeOption
+		want         []streamItem
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: multiple elements",
+			data:         `[{"id":1,"name":"a"}, {"id":2,"name":"b"}]`,
+			want:         []streamItem{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: whitespaces around array",
+			data:         " \n[ {\"id\":1} ]\n ",
+			want:         []streamItem{{ID: 1}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: empty array",
+			data:         `[]`,
+			want:         nil,
+			errAssertion: assert.NoError,
+		},
+		{
+			name: "failure: empty body",
+			data: ``,
+			want: nil,
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, httplib.ErrNotJSONArray)
+			},
+		},
+		{
+			name: "failure: object",
+			data: `{"id":1}`,
+			want: nil,
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, httplib.ErrNotJSONArray)
+			},
+		},
+		{
+			name:         "failure: invalid element",
+			data:         `[{"id":1},{"id":"a"}]`,
+			want:         []streamItem{{ID: 1}},
+			errAssertion: assertItemErrorFunc(1),
+		},
+		{
+			name:         "failure: unknown field",
+			data:         `[{"id":1,"unknown":true}]`,
+			want:         nil,
+			errAssertion: assertItemErrorFunc(0),
+		},
+		{
+			name:         "success: unknown field is allowed",
+			data:         `[{"id":1,"unknown":true}]`,
+			opts:         []httplib.DecodeOption{httplib.WithAllowUnknownFields()},
+			want:         []streamItem{{ID: 1}},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: not closed",
+			data:         `[{"id":1}`,
+			want:         []streamItem{{ID: 1}},
+			errAssertion: assert.Error,
+		},
+		{
+			name: "failure: trailing value",
+			data: `[{"id":1}] {"id":2}`,
+			want: []streamItem{{ID: 1}},
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, httplib.ErrTrailingData)
+			},
+		},
+		{
+			name: "failure: element exceeds max item size",
+			data: `[{"id":1},{"id":1,"name":"` + strings.Repeat("a", 100) + `"}]`,
+			opts: []httplib.DecodeOption{httplib.WithMaxItemSize(50)},
+			want: []streamItem{{ID: 1}},
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assertItemErrorFunc(1)(t, err) && assertMaxBytesErrorFunc(50)(t, err)
+			},
+		},
+		{
+			name: "success: many elements smaller than max item size",
+			data: `[` + strings.Repeat(`{"id":1},`, 20) + `{"id":1}]`,
+			opts: []httplib.DecodeOption{httplib.WithMaxItemSize(10)},
+			want: func() []streamItem {
+				items := make([]streamItem, 21)
+				for i := range items {
+					items[i] = streamItem{ID: 1}
+				}
+				return items
+			}(),
+			errAssertion: assert.NoError,
+		},
+		{
+			name: "failure: body exceeds max body size",
+			data: `[{"id":1},{"id":2},{"id":3}]`,
+			opts: []httplib.DecodeOption{httplib.WithMaxBodySize(15)},
+			want: []streamItem{{ID: 1}},
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assertItemErrorFunc(1)(t, err) && assertMaxBytesErrorFunc(15)(t, err)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.data))
+			got, err := collectItems(httplib.DecodeJSONArray[streamItem](r, tt.opts...))
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecodeJSONArray_Break(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[1,2,3]`))
+
+	var got []int
+	for item, err := range httplib.DecodeJSONArray[int](r) {
+		require.NoError(t, err)
+		got = append(got, item)
+		if len(got) == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, []int{1, 2}, got)
+}
@@ -53,6 +53,12 @@ type RequestLog struct {
 	//
 	// It is taken from the "Referer" header and may be empty.
 	Referer string
+
+	// SessionIDHash is the hash of the ID of the session loaded by SessionManager.Middleware,
+	// which is set only in the context of the handler. See ResponseLog.SessionIDHash for the saved session.
+	//
+	// The raw session ID is never logged, since it authenticates the client. It is empty if the request has no session.
+	SessionIDHash string
 }
 
 // NewRequestLog creates a RequestLog from an http.Request and timestamp.
@@ -97,6 +103,7 @@ func NewRequestLog(r *http.Request, timestamp time.Time) RequestLog {
 //   - remote_addr: client address (IP:port)
 //   - user_agent: client user agent string
 //   - referer: referring URL
+//   - session_id_hash: hash of the session ID (included only if SessionIDHash is not empty)
 //
 // Returns an empty slog.Attr if the RequestLog is nil.
 func (l *RequestLog) ToAttr() slog.Attr {
@@ -104,7 +111,10 @@ func (l *RequestLog) ToAttr() slog.Attr {
 		return slog.Attr{}
 	}
 
-	return slog.GroupAttrs("http_request",
+	attrs := make([]slog.Attr, 0, 11)
+
+	attrs = append(
+		attrs,
 		slog.String("timestamp", l.Timestamp.Format(time.RFC3339)),
 		slog.String("method", l.Method),
 		slog.String("url", l.URL),
@@ -116,6 +126,12 @@ func (l *RequestLog) ToAttr() slog.Attr {
 		slog.String("user_agent", l.UserAgent),
 		slog.String("referer", l.Referer),
 	)
+
+	if l.SessionIDHash != "" {
+		attrs = append(attrs, slog.String("session_id_hash", l.SessionIDHash))
+	}
+
+	return slog.GroupAttrs("http_request", attrs...)
 }
 
 // GetIP extracts and parses the IP address from RemoteAddr.
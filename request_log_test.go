@@ -125,6 +125,26 @@ func TestRequestLog_ToAttr(t *testing.T) {
 				slog.String("referer", "https://ref.example.com/"),
 			),
 		},
+		{
+			name: "with session",
+			log: &httplib.RequestLog{
+				Method:        http.MethodGet,
+				SessionIDHash: "0123456789abcdef",
+			},
+			want: slog.GroupAttrs("http_request",
+				slog.String("timestamp", time.Time{}.Format(time.RFC3339)),
+				slog.String("method", http.MethodGet),
+				slog.String("url", ""),
+				slog.String("host", ""),
+				slog.String("request_uri", ""),
+				slog.Int64("content_length", 0),
+				slog.String("proto", ""),
+				slog.String("remote_addr", ""),
+				slog.String("user_agent", ""),
+				slog.String("referer", ""),
+				slog.String("session_id_hash", "0123456789abcdef"),
+			),
+		},
 		{
 			name: "nil",
 			log:  nil,
@@ -0,0 +1,171 @@
+package httplib
+
+// DecodeOption configures how a request body is decoded.
+//
+// Options that do not apply to a decoder are ignored by it.
+type DecodeOption func(*decodeConfig)
+
+type decodeConfig struct {
+	maxBodySize        int64
+	maxItemSize        int64
+	maxFileSize        int64
+	maxMemory          int64
+	allowUnknownFields bool
+	validate           bool
+	schema             *JSONSchema
+	bodyDecoders       []bodyDecoderEntry
+	delimiter          rune
+	contentDigest      contentDigestVerification
+}
+
+type contentDigestVerification uint8
+
+const (
+	contentDigestDisabled contentDigestVerification = iota
+	contentDigestOptional
+	contentDigestRequired
+)
+
+func newDecodeConfig(defaultMaxBodySize int64, opts []DecodeOption) decodeConfig {
+	cfg := decodeConfig{
+		maxBodySize: defaultMaxBodySize,
+		maxItemSize: DefaultMaxRequestItemSize,
+		maxFileSize: DefaultMaxFileSize,
+		maxMemory:   DefaultMaxMemory,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	return cfg
+}
+
+// WithMaxBodySize sets the maximum number of bytes read from the request body.
+//
+// If size <= 0, the option is ignored and the decoder's default limit is used.
+func WithMaxBodySize(size int64) DecodeOption {
+	return func(cfg *decodeConfig) {
+		if size > 0 {
+			cfg.maxBodySize = size
+		}
+	}
+}
+
+// WithMaxItemSize sets the maximum number of bytes of a single item in streaming decoders
+// such as DecodeJSONLines, DecodeJSONArray and DecodeCSVRequestBody,
+// and of a single form value in DecodeMultipartRequestBody.
+//
+// If size <= 0, the option is ignored and DefaultMaxRequestItemSize is used.
+func WithMaxItemSize(size int64) DecodeOption {
+	return func(cfg *decodeConfig) {
+		if size > 0 {
+			cfg.maxItemSize = size
+		}
+	}
+}
+
+// WithMaxFileSize sets the maximum number of bytes of a single uploaded file for DecodeMultipartRequestBody.
+//
+// If size <= 0, the option is ignored and DefaultMaxFileSize is used.
+func WithMaxFileSize(size int64) DecodeOption {
+	return func(cfg *decodeConfig) {
+		if size > 0 {
+			cfg.maxFileSize = size
+		}
+	}
+}
+
+// WithMaxMemory sets the maximum number of bytes of uploaded files kept in memory by DecodeMultipartRequestBody.
+//
+// Files that do not fit in memory are stored in temporary files.
+// If size < 0, the option is ignored and DefaultMaxMemory is used.
+// If size is 0, every file is stored in a temporary file.
+func WithMaxMemory(size int64) DecodeOption {
+	return func(cfg *decodeConfig) {
+		if size >= 0 {
+			cfg.maxMemory = size
+		}
+	}
+}
+
+// WithDelimiter sets the field delimiter of DecodeCSVRequestBody, such as '\t' or ';'.
+//
+// If delimiter is 0, the option is ignored and a comma is used.
+func WithDelimiter(delimiter rune) DecodeOption {
+	return func(cfg *decodeConfig) {
+		if delimiter != 0 {
+			cfg.delimiter = delimiter
+		}
+	}
+}
+
+// WithAllowUnknownFields allows fields in the request body that do not match any field of the destination type.
+//
+// By default, decoders reject unknown fields.
+func WithAllowUnknownFields() DecodeOption {
+	return func(cfg *decodeConfig) {
+		cfg.allowUnknownFields = true
+	}
+}
+
+// WithValidation validates the decoded value by Validate.
+//
+// If the value is invalid, the decoder returns ValidationErrors.
+func WithValidation() DecodeOption {
+	return func(cfg *decodeConfig) {
+		cfg.validate = true
+	}
+}
+
+// validateIfEnabled validates v by Validate if WithValidation is given.
+func (cfg decodeConfig) validateIfEnabled(v any) error {
+	if !cfg.validate {
+		return nil
+	}
+	return Validate(v)
+}
+
+// WithJSONSchema validates the JSON request body against the schema before it is decoded.
+//
+// If the body does not conform to the schema, the decoder returns ValidationErrors.
+// The schema should be compiled once, e.g. by MustCompileJSONSchema in a global variable, and reused.
+// If schema is nil, the option is ignored.
+func WithJSONSchema(schema *JSONSchema) DecodeOption {
+	return func(cfg *decodeConfig) {
+		cfg.schema = schema
+	}
+}
+
+// validateSchemaIfEnabled validates the JSON document against the schema if WithJSONSchema is given.
+func (cfg decodeConfig) validateSchemaIfEnabled(data []byte) error {
+	if cfg.schema == nil {
+		return nil
+	}
+	return cfg.schema.ValidateJSON(data)
+}
+
+func (cfg decodeConfig) unmarshalOptions() UnmarshalOptions {
+	return UnmarshalOptions{DisallowUnknownFields: !cfg.allowUnknownFields}
+}
+
+// WithContentDigest verifies the request body against the Content-Digest header (RFC 9530) if present,
+// by the strongest of the supported algorithms, "sha-512" and "sha-256". Other algorithms are ignored.
+//
+// The whole body is read before it is decoded. If the digest does not match, or the header is malformed,
+// the decoder returns ContentDigestError. It applies to DecodeJSONRequestBody and DecodeRequestBody for JSON.
+func WithContentDigest() DecodeOption {
+	return func(cfg *decodeConfig) {
+		cfg.contentDigest = contentDigestOptional
+	}
+}
+
+// WithRequiredContentDigest is like WithContentDigest, but also returns ContentDigestError
+// if the request has no Content-Digest header of a supported algorithm.
+func WithRequiredContentDigest() DecodeOption {
+	return func(cfg *decodeConfig) {
+		cfg.contentDigest = contentDigestRequired
+	}
+}
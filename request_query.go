@@ -0,0 +1,40 @@
+package httplib
+
+import (
+	"net/http"
+)
+
+// DecodeQuery binds the query parameters of the request to T.
+//
+// T must be a struct. Its fields are bound by the "query" struct tag, and untagged fields are left unchanged.
+// The tag value is the parameter name, optionally followed by comma-separated options:
+//   - required: the request must have a non-empty value for the parameter
+//   - comma: each value is split by commas, so "ids=1,2&ids=3" is bound to a slice of three elements
+//
+// For example:
+//
+//	type ListParams struct {
+//		Page   int           `query:"page" default:"1"`
+//		Sort   SortOrder     `query:"sort,required"`
+//		IDs    []int64       `query:"id,comma"`
+//		Since  time.Time     `query:"since" layout:"2006-01-02"`
+//		Window time.Duration `query:"window" default:"1h"`
+//	}
+//
+// Supported field types are string, bool, integers, floats, time.Time, time.Duration,
+// types implementing encoding.TextUnmarshaler (e.g. netip.Addr or an enum type), and pointers and slices of them.
+// The layout of time.Time fields can be set by the "layout" struct tag, which defaults to time.RFC3339.
+// The "default" struct tag specifies the value used when the parameter is absent.
+// Repeated parameters are bound to slices, and only the first value is used for other types.
+// An empty value is treated as absent for any type other than string.
+// Fields of untagged embedded structs are bound as if they were fields of T.
+//
+// Unlike the request body decoders, unknown parameters are ignored.
+//
+// Invalid or missing parameters are reported together as FieldErrors,
+// which can be rendered with JSONResponse and RenderBadRequestWithBody.
+// If WithValidation is given, the bound value is validated by Validate. Other options are ignored.
+// If T has a field of unsupported type, or the query string is malformed, the function returns an error that is not FieldErrors.
+func DecodeQuery[T any](r *http.Request, opts ...DecodeOption) (T, error) {
+	return bindRequestValues[T](r, SourceQuery, opts)
+}
@@ -0,0 +1,222 @@
+package httplib_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sortOrder string
+
+func (s *sortOrder) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "asc", "desc":
+		*s = sortOrder(text)
+		return nil
+	default:
+		return errors.New("must be asc or desc")
+	}
+}
+
+type pagination struct {
+	Page    int `query:"page" default:"1"`
+	PerPage int `query:"per_page" default:"20"`
+}
+
+type Filter struct {
+	Status []string `query:"status,comma"`
+}
+
+type queryObject struct {
+	pagination
+	*Filter
+
+	Sort   sortOrder     `query:"sort,required"`
+	IDs    []int64       `query:"id,comma"`
+	Names  []string      `query:"name"`
+	Since  time.Time     `query:"since" layout:"2006-01-02"`
+	Window time.Duration `query:"window" default:"1h"`
+	Addr   netip.Addr    `query:"addr"`
+	Limit  *uint8        `query:"limit"`
+	Debug  bool          `query:"debug"`
+}
+
+func TestDecodeQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		want         queryObject
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:  "success: all parameters",
+			query: "page=3&per_page=50&status=open,closed&status=draft&sort=desc&id=1,2&id=3&name=a&name=b&since=2024-01-02&window=30m&addr=192.0.2.1&limit=10&debug=true",
+			want: queryObject{
+				pagination: pagination{Page: 3, PerPage: 50},
+				Filter:     &Filter{Status: []string{"open", "closed", "draft"}},
+				Sort:       "desc",
+				IDs:        []int64{1, 2, 3},
+				Names:      []string{"a", "b"},
+				Since:      time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				Window:     30 * time.Minute,
+				Addr:       netip.MustParseAddr("192.0.2.1"),
+				Limit:      toPtr(uint8(10)),
+				Debug:      true,
+			},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:  "success: defaults",
+			query: "sort=asc",
+			want: queryObject{
+				pagination: pagination{Page: 1, PerPage: 20},
+				Sort:       "asc",
+				Window:     time.Hour,
+			},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:  "success: empty value uses default",
+			query: "sort=asc&page=",
+			want: queryObject{
+				pagination: pagination{Page: 1, PerPage: 20},
+				Sort:       "asc",
+				Window:     time.Hour,
+			},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:  "success: unknown parameters are ignored",
+			query: "sort=asc&utm_source=mail",
+			want: queryObject{
+				pagination: pagination{Page: 1, PerPage: 20},
+				Sort:       "asc",
+				Window:     time.Hour,
+			},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: required parameter is missing",
+			query:        "",
+			want:         queryObject{},
+			errAssertion: assertFieldErrorsFunc("sort"),
+		},
+		{
+			name:         "failure: required parameter is empty",
+			query:        "sort=",
+			want:         queryObject{},
+			errAssertion: assertFieldErrorsFunc("sort"),
+		},
+		{
+			name:         "failure: invalid values are reported together",
+			query:        "page=a&status=open&sort=random&id=1,x&window=1y&addr=localhost&limit=256&since=2024",
+			want:         queryObject{},
+			errAssertion: assertFieldErrorsFunc("page", "sort", "id", "since", "window", "addr", "limit"),
+		},
+		{
+			name:         "failure: malformed query",
+			query:        "sort=%zz",
+			want:         queryObject{},
+			errAssertion: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			got, err := httplib.DecodeQuery[queryObject](r)
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecodeQuery_FieldErrors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?page=a&sort=random", nil)
+	_, err := httplib.DecodeQuery[queryObject](r)
+	require.Error(t, err)
+
+	assert.EqualError(t, err, `query "page": invalid value "a": invalid syntax; query "sort": invalid value "random": must be asc or desc`)
+
+	data, err := json.Marshal(err)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"source":"query","field":"page","value":"a","message":"invalid syntax"},
+		{"source":"query","field":"sort","value":"random","message":"must be asc or desc"}
+	]`, string(data))
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err = httplib.DecodeQuery[queryObject](r)
+	assert.ErrorIs(t, err, httplib.ErrRequired)
+
+	data, err = json.Marshal(err)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"source":"query","field":"sort","message":"required"}]`, string(data))
+}
+
+func TestDecodeQuery_MalformedQueryWithoutFields(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?sort=%zz", nil)
+
+	_, err := httplib.DecodeQuery[struct {
+		Name string `json:"name"`
+	}](r)
+	assert.Error(t, err)
+
+	var fieldErrs httplib.FieldErrors
+	assert.False(t, errors.As(err, &fieldErrs), fmt.Sprintf("unexpected FieldErrors: %v", err))
+}
+
+func TestDecodeQuery_InvalidType(t *testing.T) {
+	tests := []struct {
+		name    string
+		decode  func(r *http.Request) error
+		wantErr string
+	}{
+		{
+			name: "unsupported field type",
+			decode: func(r *http.Request) error {
+				_, err := httplib.DecodeQuery[struct {
+					Map map[string]string `query:"map"`
+				}](r)
+				return err
+			},
+			wantErr: "httplib: unsupported type map[string]string of field Map",
+		},
+		{
+			name: "unknown tag option",
+			decode: func(r *http.Request) error {
+				_, err := httplib.DecodeQuery[struct {
+					Value string `query:"value,unknown"`
+				}](r)
+				return err
+			},
+			wantErr: `httplib: unknown option "unknown" of field Value`,
+		},
+		{
+			name: "non-struct type",
+			decode: func(r *http.Request) error {
+				_, err := httplib.DecodeQuery[int](r)
+				return err
+			},
+			wantErr: "httplib: cannot bind to non-struct type int",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?map=a&value=b", nil)
+			err := tt.decode(r)
+			assert.EqualError(t, err, tt.wantErr)
+
+			var fieldErrs httplib.FieldErrors
+			assert.False(t, errors.As(err, &fieldErrs), fmt.Sprintf("unexpected FieldErrors: %v", err))
+		})
+	}
+}
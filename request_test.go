@@ -571,3 +571,54 @@ func TestDecodeJSONRequestBody_LargeRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeJSONRequestBody_Options(t *testing.T) {
+	type testObject struct {
+		A string `json:"a"`
+	}
+
+	tests := []struct {
+		name         string
+		data         string
+		opts         []httplib.DecodeOption
+		want         testObject
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: allow unknown fields",
+			data:         `{"a":"a","unknown":"unknown"}`,
+			opts:         []httplib.DecodeOption{httplib.WithAllowUnknownFields()},
+			want:         testObject{A: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "success: equal to max body size",
+			data:         `{"a":"a"}`,
+			opts:         []httplib.DecodeOption{httplib.WithMaxBodySize(9)},
+			want:         testObject{A: "a"},
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "failure: exceeds max body size",
+			data:         `{"a":"aa"}`,
+			opts:         []httplib.DecodeOption{httplib.WithMaxBodySize(9)},
+			want:         testObject{},
+			errAssertion: assertMaxBytesErrorFunc(9),
+		},
+		{
+			name:         "success: non-positive max body size is ignored",
+			data:         `{"a":"a"}`,
+			opts:         []httplib.DecodeOption{httplib.WithMaxBodySize(0), nil},
+			want:         testObject{A: "a"},
+			errAssertion: assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.data))
+			got, err := httplib.DecodeJSONRequestBody[testObject](r, tt.opts...)
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
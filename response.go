@@ -3,7 +3,9 @@ package httplib
 import (
 	"context"
 	"errors"
+	"maps"
 	"net/http"
+	"strings"
 )
 
 // RenderOK renders a response with status code http.StatusOK without body.
@@ -14,6 +16,9 @@ func RenderOK(ctx context.Context, w http.ResponseWriter) {
 // RenderOKWithBody renders a response with status code http.StatusOK and body.
 //
 // Both RenderHeader and RenderBody will always be called, even if RenderHeader returns an error.
+// For HEAD requests, RenderBody is not called for HeadResponseBodyRenderer (see its documentation).
+// HEAD requests are identified by the method stored in the context by WithRequestMethod or WithRequestLog;
+// without either, the body is rendered as for GET requests and discarded by net/http.
 // The errors will be joined by errors.Join.
 //
 // When the bodyRenderer returns errors, this function will:
@@ -32,6 +37,9 @@ func RenderCreated(ctx context.Context, w http.ResponseWriter) {
 // RenderCreatedWithBody renders a response with status code http.StatusCreated and body.
 //
 // Both RenderHeader and RenderBody will always be called, even if RenderHeader returns an error.
+// For HEAD requests, RenderBody is not called for HeadResponseBodyRenderer (see its documentation).
+// HEAD requests are identified by the method stored in the context by WithRequestMethod or WithRequestLog;
+// without either, the body is rendered as for GET requests and discarded by net/http.
 // The errors will be joined by errors.Join.
 //
 // When the bodyRenderer returns errors, this function will:
@@ -56,6 +64,13 @@ func RenderNoContentForUnauthorized(ctx context.Context, w http.ResponseWriter,
 	renderStatusCode(ctx, w, http.StatusNoContent, cause)
 }
 
+// RenderNotModified renders a response with status code http.StatusNotModified without body.
+//
+// The headers required for the response, such as ETag and Cache-Control, should be set before calling this function.
+func RenderNotModified(ctx context.Context, w http.ResponseWriter) {
+	renderStatusCode(ctx, w, http.StatusNotModified, nil)
+}
+
 // RenderRedirect renders a response with status code http.StatusTemporaryRedirect and a redirect url.
 func RenderRedirect(ctx context.Context, w http.ResponseWriter, r *http.Request, url string) {
 	resPtr := GetResponseLogPtrFromContext(ctx)
@@ -79,6 +94,9 @@ func RenderBadRequest(ctx context.Context, w http.ResponseWriter, cause error) {
 // RenderBadRequestWithBody renders a response with status code http.StatusBadRequest and body.
 //
 // Both RenderHeader and RenderBody will always be called, even if RenderHeader returns an error.
+// For HEAD requests, RenderBody is not called for HeadResponseBodyRenderer (see its documentation).
+// HEAD requests are identified by the method stored in the context by WithRequestMethod or WithRequestLog;
+// without either, the body is rendered as for GET requests and discarded by net/http.
 // The errors will be joined by errors.Join.
 //
 // When the bodyRenderer returns errors, this function will:
@@ -110,6 +128,15 @@ func RenderNotFound(ctx context.Context, w http.ResponseWriter, cause error) {
 	renderStatusCode(ctx, w, http.StatusNotFound, cause)
 }
 
+// RenderMethodNotAllowed renders a response with status code http.StatusMethodNotAllowed without body.
+//
+// The Allow header is set to the allowed methods, as required by RFC 9110.
+// The cause error will be used for ResponseLog.Error.
+func RenderMethodNotAllowed(ctx context.Context, w http.ResponseWriter, cause error, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	renderStatusCode(ctx, w, http.StatusMethodNotAllowed, cause)
+}
+
 // RenderConflict renders a response with status code http.StatusConflict without body.
 //
 // The cause error will be used for ResponseLog.Error.
@@ -117,6 +144,53 @@ func RenderConflict(ctx context.Context, w http.ResponseWriter, cause error) {
 	renderStatusCode(ctx, w, http.StatusConflict, cause)
 }
 
+// RenderUnsupportedMediaType renders a response with status code http.StatusUnsupportedMediaType.
+//
+// If cause is UnsupportedMediaTypeError, the response has a text/plain body listing the accepted media types,
+// one per line, and the Accept header listing them as well. Otherwise, the response has no body.
+//
+// The cause error will be used for ResponseLog.Error. The returned error is the error of writing the body.
+func RenderUnsupportedMediaType(ctx context.Context, w http.ResponseWriter, cause error) error {
+	var unsupportedErr *UnsupportedMediaTypeError
+	if !errors.As(cause, &unsupportedErr) {
+		renderStatusCode(ctx, w, http.StatusUnsupportedMediaType, cause)
+		return nil
+	}
+
+	w.Header().Set("Accept", strings.Join(unsupportedErr.Accepted, ", "))
+	body := RawResponseWithContentType([]byte(strings.Join(unsupportedErr.Accepted, "\n")+"\n"), ContentTypeTextPlainUTF8)
+	return renderWithBody(ctx, w, http.StatusUnsupportedMediaType, body, cause)
+}
+
+// RenderUnprocessableEntity renders a response with status code http.StatusUnprocessableEntity without body.
+//
+// The cause error will be used for ResponseLog.Error.
+func RenderUnprocessableEntity(ctx context.Context, w http.ResponseWriter, cause error) {
+	renderStatusCode(ctx, w, http.StatusUnprocessableEntity, cause)
+}
+
+// RenderUnprocessableEntityWithBody renders a response with status code http.StatusUnprocessableEntity and body.
+//
+// This function can be used to render ValidationErrors, for example:
+//
+//	renderer, err := httplib.JSONResponse(validationErrs)
+//	// handle err
+//	httplib.RenderUnprocessableEntityWithBody(ctx, w, renderer, validationErrs)
+//
+// Both RenderHeader and RenderBody will always be called, even if RenderHeader returns an error.
+// For HEAD requests, RenderBody is not called for HeadResponseBodyRenderer (see its documentation).
+// HEAD requests are identified by the method stored in the context by WithRequestMethod or WithRequestLog;
+// without either, the body is rendered as for GET requests and discarded by net/http.
+// The errors will be joined by errors.Join.
+//
+// When the bodyRenderer returns errors, this function will:
+//   - Keep the status code as http.StatusUnprocessableEntity
+//   - Set ResponseLog in the context (the renderer error is not stored in ResponseLog.Error)
+//   - Return the renderer error
+func RenderUnprocessableEntityWithBody(ctx context.Context, w http.ResponseWriter, bodyRenderer ResponseBodyRenderer, cause error) error {
+	return renderWithBody(ctx, w, http.StatusUnprocessableEntity, bodyRenderer, cause)
+}
+
 // RenderInternalServerError renders a response with status code http.StatusInternalServerError without body.
 //
 // The cause error will be used for ResponseLog.Error.
@@ -124,6 +198,33 @@ func RenderInternalServerError(ctx context.Context, w http.ResponseWriter, cause
 	renderStatusCode(ctx, w, http.StatusInternalServerError, cause)
 }
 
+// renderFallback is the renderer used by transactional rendering when RenderHeader fails.
+type renderFallback struct {
+	renderer ResponseBodyRenderer
+}
+
+// WithTransactionalRendering returns a new context that enables transactional rendering for the Render functions.
+//
+// By default, the status code is written even if RenderHeader of the ResponseBodyRenderer returns an error,
+// so the client may receive a success status with broken headers. In transactional rendering:
+//   - RenderHeader writes to a copy of the response headers, which is applied only if it succeeds
+//   - If RenderHeader fails, the intended status code is aborted, and http.StatusInternalServerError is rendered
+//     with the fallback renderer instead. If fallback is nil, the response has no body
+//   - RenderHead, instead of RenderBody, is called for the failed renderer to release its resources
+//     (see HeadResponseBodyRenderer). The body of a renderer not implementing it is never rendered
+//   - The header error is stored in ResponseLog.Error, joined with the cause error if any,
+//     and is still returned to the caller
+//
+// Errors of RenderBody cannot be handled in this way, since the status code has already been written.
+// Use BufferedResponse to render the body in RenderHeader, so that its errors can fall back as well.
+//
+// The fallback renderer is shared by requests, so it must be reusable and safe for concurrent use,
+// like the renderers returned by RawResponseWithContentType. To enable it for every request of a server,
+// set it to the base context of the server as WithCodec does.
+func WithTransactionalRendering(ctx context.Context, fallback ResponseBodyRenderer) context.Context {
+	return context.WithValue(ctx, contextKeyRenderFallback, &renderFallback{renderer: fallback})
+}
+
 func renderStatusCode(ctx context.Context, w http.ResponseWriter, statusCode int, cause error) {
 	_ = renderResponse(ctx, w, statusCode, nil, cause) // no error will be occurred
 }
@@ -135,16 +236,45 @@ func renderWithBody(ctx context.Context, w http.ResponseWriter, statusCode int,
 func renderResponse(ctx context.Context, w http.ResponseWriter, statusCode int, bodyRenderer ResponseBodyRenderer, cause error) error {
 	var err error
 
-	if bodyRenderer != nil {
+	if fallback, ok := ctx.Value(contextKeyRenderFallback).(*renderFallback); ok && bodyRenderer != nil {
+		if headerErr := renderHeaderTransactionally(ctx, w.Header(), bodyRenderer); headerErr != nil {
+			err = errors.Join(headerErr, releaseRenderer(ctx, bodyRenderer))
+			statusCode, bodyRenderer = http.StatusInternalServerError, fallback.renderer
+			if cause == nil {
+				cause = headerErr
+			} else {
+				cause = errors.Join(cause, headerErr)
+			}
+
+			if bodyRenderer != nil {
+				if fallbackErr := bodyRenderer.RenderHeader(ctx, w.Header()); fallbackErr != nil {
+					err = errors.Join(err, fallbackErr)
+				}
+			}
+		}
+	} else if bodyRenderer != nil {
 		if headerErr := bodyRenderer.RenderHeader(ctx, w.Header()); headerErr != nil {
 			err = headerErr
 		}
 	}
 
+	head := isHeadRequest(ctx)
+
+	var trailerNames []string
+	if bodyRenderer != nil && !head {
+		trailerNames = announceTrailers(w.Header(), bodyRenderer)
+	}
+
 	w.WriteHeader(statusCode)
 	size := int64(0)
+	bodyOmitted := false
 
-	if bodyRenderer != nil {
+	if bodyRenderer != nil && head {
+		if headErr := renderHead(ctx, bodyRenderer); headErr != nil {
+			err = errors.Join(err, headErr)
+		}
+		bodyOmitted = true
+	} else if bodyRenderer != nil {
 		wrapped := responseBodyWriter{w: w}
 		bodyErr := bodyRenderer.RenderBody(ctx, &wrapped)
 		if bodyErr != nil {
@@ -153,11 +283,23 @@ func renderResponse(ctx context.Context, w http.ResponseWriter, statusCode int,
 		size = wrapped.responseSize
 	}
 
+	var trailers http.Header
+	if len(trailerNames) != 0 {
+		var trailerErr error
+		trailers, trailerErr = renderTrailers(ctx, w.Header(), bodyRenderer, trailerNames)
+		if trailerErr != nil {
+			err = errors.Join(err, trailerErr)
+		}
+	}
+
 	resPtr := GetResponseLogPtrFromContext(ctx)
 	if resPtr != nil {
 		*resPtr = ResponseLog{
 			StatusCode:   statusCode,
 			ResponseSize: size,
+			BodyOmitted:  bodyOmitted,
+			Trailers:     trailers,
+			CacheControl: w.Header().Get("Cache-Control"),
 			Error:        cause,
 			// skip=3: renderResponse(0) -> renderStatusCode/renderWithBody(1) -> RenderXX(2) -> caller(3)
 			HandlerInfo: NewHandlerInfo(3),
@@ -166,3 +308,16 @@ func renderResponse(ctx context.Context, w http.ResponseWriter, statusCode int,
 
 	return err
 }
+
+// renderHeaderTransactionally calls RenderHeader of the renderer with a copy of the header,
+// and applies the changes to the header only if it succeeds.
+func renderHeaderTransactionally(ctx context.Context, header http.Header, renderer ResponseBodyRenderer) error {
+	staged := header.Clone()
+	if err := renderer.RenderHeader(ctx, staged); err != nil {
+		return err
+	}
+
+	clear(header)
+	maps.Copy(header, staged)
+	return nil
+}
@@ -1,11 +1,13 @@
 package httplib
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"strconv"
+	"sync"
 )
 
 type ResponseBodyRenderer interface {
@@ -13,16 +15,281 @@ type ResponseBodyRenderer interface {
 	RenderBody(ctx context.Context, w io.Writer) error
 }
 
+// HeadResponseBodyRenderer is a ResponseBodyRenderer that can skip rendering the body for HEAD requests.
+//
+// For HEAD requests, the Render functions call RenderHead instead of RenderBody after RenderHeader,
+// so that RenderHeader still sets headers such as Content-Type and Content-Length, but the body is never generated.
+// RenderHead should release the resources held for RenderBody, such as open files.
+//
+// The body of a ResponseBodyRenderer not implementing this interface is rendered and discarded.
+// All renderers of this package implement this interface.
+//
+// A request is detected as a HEAD request by the method stored in the context by WithRequestMethod,
+// or RequestLog.Method stored by WithRequestLog if there is none. Of this package, only SessionManager.Middleware,
+// ResponseCache.Middleware, CoalesceMiddleware and the handler returned by NewStaticHandler store the method,
+// so other handlers should store it by WithRequestMethod, such as in a middleware.
+// Otherwise, the body is rendered for HEAD requests, and is discarded by net/http.
+type HeadResponseBodyRenderer interface {
+	ResponseBodyRenderer
+	RenderHead(ctx context.Context) error
+}
+
+// renderHead calls RenderHead of the renderer if it implements HeadResponseBodyRenderer,
+// or renders the body to io.Discard otherwise.
+func renderHead(ctx context.Context, renderer ResponseBodyRenderer) error {
+	if headRenderer, ok := renderer.(HeadResponseBodyRenderer); ok {
+		return headRenderer.RenderHead(ctx)
+	}
+	return renderer.RenderBody(ctx, io.Discard)
+}
+
+// releaseRenderer calls RenderHead of the renderer if it implements HeadResponseBodyRenderer, to release its resources.
+// Unlike renderHead, the body of the other renderers is not rendered, since it is not sent anyway.
+func releaseRenderer(ctx context.Context, renderer ResponseBodyRenderer) error {
+	if headRenderer, ok := renderer.(HeadResponseBodyRenderer); ok {
+		return headRenderer.RenderHead(ctx)
+	}
+	return nil
+}
+
+// isHeadRequest reports whether the request is a HEAD request, by the method stored by WithRequestMethod,
+// or RequestLog.Method stored by WithRequestLog if there is none.
+func isHeadRequest(ctx context.Context) bool {
+	if method, ok := ctx.Value(contextKeyRequestMethod).(string); ok {
+		return method == http.MethodHead
+	}
+	return GetRequestLogFromContext(ctx).Method == http.MethodHead
+}
+
+// JSONResponse returns a ResponseBodyRenderer that renders v as JSON.
+//
+// The value is marshaled by JSONCodec immediately, so that marshaling errors are returned by this function.
+// If the context given to RenderHeader carries another Codec set by WithCodec, the value is marshaled again
+// by that Codec in RenderHeader. Use JSONResponseWithCodec to marshal the value only once by a specific Codec.
+//
+// JSONCodec encodes the value into a pooled buffer, which is released after the first RenderBody or RenderHead.
+// The renderer can still be rendered again, and is safe for concurrent use,
+// but the later renders marshal the value again and keep it in a new slice.
 func JSONResponse(v any) (ResponseBodyRenderer, error) {
-	data, err := json.Marshal(v) // TODO: use json.MarshalWrite after encoding/json/v2 is stabilized.
+	return newJSONResponseBodyRenderer(JSONCodec{}, v, true)
+}
+
+// JSONResponseWithCodec returns a ResponseBodyRenderer that renders v as JSON marshaled by the codec.
+//
+// The value is marshaled immediately, so that marshaling errors are returned by this function.
+// The Codec stored in the context is ignored. As JSONResponse, the returned renderer is reusable.
+func JSONResponseWithCodec(codec Codec, v any) (ResponseBodyRenderer, error) {
+	return newJSONResponseBodyRenderer(codec, v, false)
+}
+
+// maxPooledJSONBufferSize is the maximum capacity of buffers returned to jsonBufferPool,
+// so that a few large responses do not keep a large amount of memory in the pool.
+const maxPooledJSONBufferSize = 64 << 10
+
+var jsonBufferPool = sync.Pool{
+	New: func() any {
+		buf := new(jsonBuffer)
+		buf.encoder = json.NewEncoder(&buf.Buffer)
+		return buf
+	},
+}
+
+// jsonBuffer is a buffer pooled by jsonBufferPool with the json.Encoder writing into it.
+type jsonBuffer struct {
+	bytes.Buffer
+	encoder *json.Encoder
+}
+
+// encodeJSON encodes v by the codec into a pooled buffer.
+// The output is the same as JSONCodec.Marshal, since json.Marshal escapes HTML as json.Encoder does by default.
+func encodeJSON(codec JSONCodec, v any) (*jsonBuffer, error) {
+	buf := jsonBufferPool.Get().(*jsonBuffer)
+	buf.Reset()
+	buf.encoder.SetEscapeHTML(!codec.DisableHTMLEscape)
+
+	if err := buf.encoder.Encode(v); err != nil {
+		buf.release()
+		return nil, err
+	}
+	buf.Truncate(buf.Len() - 1) // remove the newline written by json.Encoder, as json.Marshal does not write it
+	return buf, nil
+}
+
+// release returns the buffer to jsonBufferPool. The buffer must not be used after this call.
+func (buf *jsonBuffer) release() {
+	if buf.Cap() <= maxPooledJSONBufferSize {
+		jsonBufferPool.Put(buf)
+	}
+}
+
+type jsonResponseBodyRenderer struct {
+	v             any
+	followContext bool // whether the value is marshaled by the Codec stored in the context
+
+	mu    sync.Mutex
+	buf   *jsonBuffer // the pooled buffer holding the value encoded by JSONCodec, or nil if it is released
+	b     []byte      // the value marshaled by the other Codecs, or by any Codec after buf is released
+	codec Codec       // the Codec that encoded buf or b
+}
+
+func newJSONResponseBodyRenderer(codec Codec, v any, followContext bool) (*jsonResponseBodyRenderer, error) {
+	renderer := &jsonResponseBodyRenderer{v: v, followContext: followContext, codec: codec}
+
+	if jsonCodec, ok := codec.(JSONCodec); ok {
+		buf, err := encodeJSON(jsonCodec, v)
+		if err != nil {
+			return nil, err
+		}
+		renderer.buf = buf
+		return renderer, nil
+	}
+
+	b, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	renderer.b = b
+	return renderer, nil
+}
+
+// bodyLocked returns the encoded value, marshaling it again if the Codec stored in the context is another one,
+// or if the pooled buffer has been released. It must be called with r.mu held.
+func (r *jsonResponseBodyRenderer) bodyLocked(ctx context.Context) ([]byte, error) {
+	codec := r.codec
+	if r.followContext {
+		codec = GetCodecFromContext(ctx)
+	}
+
+	if sameCodec(codec, r.codec) {
+		if r.buf != nil {
+			return r.buf.Bytes(), nil
+		}
+		if r.b != nil {
+			return r.b, nil
+		}
+	}
+
+	b, err := codec.Marshal(r.v)
 	if err != nil {
 		return nil, err
 	}
 
-	return &rawResponseBodyRenderer{
-		b:           data,
-		contentType: ContentTypeJSONUTF8,
-	}, nil
+	r.releaseLocked()
+	r.b, r.codec = b, codec
+	return b, nil
+}
+
+// releaseLocked releases the pooled buffer. It must be called with r.mu held.
+func (r *jsonResponseBodyRenderer) releaseLocked() {
+	if r.buf != nil {
+		r.buf.release()
+		r.buf = nil
+	}
+}
+
+func (r *jsonResponseBodyRenderer) RenderHeader(ctx context.Context, header http.Header) error {
+	r.mu.Lock()
+	b, err := r.bodyLocked(ctx)
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	header.Set("Content-Type", ContentTypeJSONUTF8)
+	header.Set("Content-Length", strconv.Itoa(len(b)))
+	return nil
+}
+
+// bufferedBody copies the pooled buffer out before returning the body,
+// since the buffer may be released by RenderBody of another render while the caller reads it.
+func (r *jsonResponseBodyRenderer) bufferedBody() ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.buf != nil {
+		r.b = bytes.Clone(r.buf.Bytes())
+		r.releaseLocked()
+	}
+	return r.b, r.b != nil
+}
+
+func (r *jsonResponseBodyRenderer) RenderHead(_ context.Context) error {
+	r.mu.Lock()
+	r.releaseLocked()
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *jsonResponseBodyRenderer) RenderBody(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	b, err := r.bodyLocked(ctx)
+	if err != nil {
+		r.mu.Unlock()
+		return err
+	}
+	buf := r.buf // the pooled buffer holding b, which is taken by this render and released after writing it
+	r.buf = nil
+	r.mu.Unlock()
+
+	if buf != nil {
+		defer buf.release()
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// BufferedResponse returns a ResponseBodyRenderer that renders the body of the renderer into a buffer in RenderHeader.
+//
+// Errors of RenderBody, such as ones of streaming renderers like XMLStreamResponse, are returned by RenderHeader
+// before the status code is written, so that they can be rendered as http.StatusInternalServerError
+// by WithTransactionalRendering. The Content-Length header is set to the size of the buffered body.
+// The whole body is held in memory, so it should not be used for large responses.
+func BufferedResponse(renderer ResponseBodyRenderer) ResponseBodyRenderer {
+	return &bufferedResponseBodyRenderer{renderer: renderer}
+}
+
+type bufferedResponseBodyRenderer struct {
+	renderer ResponseBodyRenderer
+	buf      bytes.Buffer
+	rendered bool // whether RenderBody or RenderHead of the renderer has been called
+}
+
+func (r *bufferedResponseBodyRenderer) RenderHeader(ctx context.Context, header http.Header) error {
+	if err := r.renderer.RenderHeader(ctx, header); err != nil {
+		return err
+	}
+
+	r.rendered = true
+	if err := r.renderer.RenderBody(ctx, &r.buf); err != nil {
+		r.buf.Reset()
+		return err
+	}
+
+	header.Set("Content-Length", strconv.Itoa(r.buf.Len()))
+	return nil
+}
+
+func (r *bufferedResponseBodyRenderer) bufferedBody() ([]byte, bool) {
+	return r.buf.Bytes(), r.rendered
+}
+
+func (r *bufferedResponseBodyRenderer) RenderHead(ctx context.Context) error {
+	if r.rendered {
+		return nil
+	}
+
+	r.rendered = true
+	return releaseRenderer(ctx, r.renderer)
+}
+
+func (r *bufferedResponseBodyRenderer) RenderBody(ctx context.Context, w io.Writer) error {
+	if !r.rendered { // RenderHeader of the renderer has failed
+		return r.RenderHead(ctx)
+	}
+
+	_, err := w.Write(r.buf.Bytes())
+	return err
 }
 
 type rawResponseBodyRenderer struct {
@@ -44,6 +311,14 @@ func (r *rawResponseBodyRenderer) RenderHeader(_ context.Context, header http.He
 	return nil
 }
 
+func (r *rawResponseBodyRenderer) bufferedBody() ([]byte, bool) {
+	return r.b, true
+}
+
+func (r *rawResponseBodyRenderer) RenderHead(_ context.Context) error {
+	return nil
+}
+
 func (r *rawResponseBodyRenderer) RenderBody(_ context.Context, w io.Writer) error {
 	_, err := w.Write(r.b)
 	return err
@@ -0,0 +1,265 @@
+package httplib
+
+import (
+	"context"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvFlushRows is the number of rows written between flushes of the response.
+const csvFlushRows = 100
+
+// CSVOption configures CSVResponse and CSVStructResponse.
+type CSVOption func(*csvConfig)
+
+type csvConfig struct {
+	header    []string
+	delimiter rune
+	bom       bool
+	filename  string
+}
+
+func newCSVConfig(opts []CSVOption) csvConfig {
+	cfg := csvConfig{delimiter: ','}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	return cfg
+}
+
+// WithCSVHeader writes the header row before the rows of CSVResponse.
+//
+// It is ignored by CSVStructResponse, whose header row is built from the struct tags.
+func WithCSVHeader(header ...string) CSVOption {
+	return func(cfg *csvConfig) {
+		cfg.header = header
+	}
+}
+
+// WithCSVDelimiter sets the field delimiter, such as '\t' or ';'. The default is a comma.
+//
+// If delimiter is 0, the option is ignored.
+func WithCSVDelimiter(delimiter rune) CSVOption {
+	return func(cfg *csvConfig) {
+		if delimiter != 0 {
+			cfg.delimiter = delimiter
+		}
+	}
+}
+
+// WithCSVBOM writes a UTF-8 byte order mark at the beginning of the body,
+// so that spreadsheet applications such as Microsoft Excel detect the encoding.
+func WithCSVBOM() CSVOption {
+	return func(cfg *csvConfig) {
+		cfg.bom = true
+	}
+}
+
+// WithCSVFilename sets the Content-Disposition header to "attachment" with the filename,
+// so that browsers download the response as a file.
+func WithCSVFilename(filename string) CSVOption {
+	return func(cfg *csvConfig) {
+		cfg.filename = filename
+	}
+}
+
+// CSVResponse returns a ResponseBodyRenderer that renders rows as text/csv.
+//
+// The rows are written as they are produced by the iterator, and the response is flushed periodically,
+// so the whole document is never held in memory. The Content-Length header is not set.
+// Since the status code has already been written when the rows are produced,
+// errors are only returned from RenderBody, and the response may be truncated.
+func CSVResponse(rows iter.Seq[[]string], opts ...CSVOption) ResponseBodyRenderer {
+	cfg := newCSVConfig(opts)
+	return &csvResponseBodyRenderer{cfg: cfg, header: cfg.header, rows: rows}
+}
+
+// CSVStructResponse returns a ResponseBodyRenderer that renders rows of the struct type T as text/csv.
+//
+// The header row is the keys of the "csv" struct tags, and the fields are written in the same order.
+// The fields can have the same types as DecodeCSVRequestBody supports: time.Time is written in the layout
+// of the "layout" struct tag (time.RFC3339 by default), encoding.TextMarshaler by MarshalText,
+// nil pointers as empty cells, and slices as their elements joined by commas.
+//
+// If T is not a struct, or has a field of an unsupported type, it returns an error.
+// The rows are written in the same way as CSVResponse.
+func CSVStructResponse[T any](rows iter.Seq[T], opts ...CSVOption) (ResponseBodyRenderer, error) {
+	fields, err := getBindFields(reflect.TypeFor[T](), SourceCSV)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]string, len(fields))
+	for i, field := range fields {
+		header[i] = field.key
+	}
+
+	records := func(yield func([]string) bool) {
+		record := make([]string, len(fields))
+		for row := range rows {
+			v := reflect.ValueOf(&row).Elem()
+			for i, field := range fields {
+				record[i] = formatFieldValue(fieldValueByIndex(v, field.index), field.layout)
+			}
+
+			if !yield(record) {
+				return
+			}
+		}
+	}
+
+	return &csvResponseBodyRenderer{cfg: newCSVConfig(opts), header: header, rows: records}, nil
+}
+
+type csvResponseBodyRenderer struct {
+	cfg    csvConfig
+	header []string
+	rows   iter.Seq[[]string]
+}
+
+func (r *csvResponseBodyRenderer) RenderHeader(_ context.Context, header http.Header) error {
+	header.Set("Content-Type", ContentTypeCSVUTF8)
+	if r.cfg.filename != "" {
+		header.Set("Content-Disposition", formatContentDisposition("attachment", r.cfg.filename))
+	}
+	return nil
+}
+
+func (r *csvResponseBodyRenderer) RenderHead(_ context.Context) error {
+	return nil
+}
+
+func (r *csvResponseBodyRenderer) RenderBody(ctx context.Context, w io.Writer) error {
+	if r.cfg.bom {
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return err
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = r.cfg.delimiter
+
+	if r.header != nil {
+		if err := writer.Write(r.header); err != nil {
+			return err
+		}
+	}
+
+	count := 0
+	for row := range r.rows {
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+
+		if count++; count%csvFlushRows == 0 {
+			if err := flushCSV(ctx, writer, w); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flushCSV(ctx, writer, w)
+}
+
+// flushCSV flushes the csv.Writer and the response, and reports the error of the writer or the context.
+func flushCSV(ctx context.Context, writer *csv.Writer, w io.Writer) error {
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return ctx.Err()
+}
+
+// marshalFieldText returns the text of the marshaler, or an empty string if MarshalText fails.
+func marshalFieldText(marshaler encoding.TextMarshaler) string {
+	text, err := marshaler.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// fieldValueByIndex returns the nested field of v. It returns an invalid Value if a nil embedded pointer is on the way.
+func fieldValueByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// formatFieldValue formats a field value of a type supported by the binders.
+func formatFieldValue(v reflect.Value, layout string) string {
+	if !v.IsValid() {
+		return ""
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Slice {
+		values := make([]string, v.Len())
+		for i := range v.Len() {
+			values[i] = formatFieldValue(v.Index(i), layout)
+		}
+		return strings.Join(values, ",")
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return value.Format(layout)
+	case time.Duration:
+		return value.String()
+	case encoding.TextMarshaler:
+		return marshalFieldText(value)
+	}
+
+	// MarshalText with a pointer receiver is available if the field is addressable, such as a field of a row.
+	if v.CanAddr() {
+		if marshaler, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			return marshalFieldText(marshaler)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,170 @@
+package httplib_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVResponse(t *testing.T) {
+	tests := []struct {
+		name                   string
+		rows                   [][]string
+		opts                   []httplib.CSVOption
+		wantData               string
+		wantContentDisposition string
+	}{
+		{
+			name:     "default",
+			rows:     [][]string{{"1", "a,b"}, {"2", `"c"`}},
+			wantData: "1,\"a,b\"\n2,\"\"\"c\"\"\"\n",
+		},
+		{
+			name:     "header, delimiter and BOM",
+			rows:     [][]string{{"1", "a"}},
+			opts:     []httplib.CSVOption{httplib.WithCSVHeader("id", "name"), httplib.WithCSVDelimiter('\t'), httplib.WithCSVDelimiter(0), httplib.WithCSVBOM(), nil},
+			wantData: "\ufeffid\tname\n1\ta\n",
+		},
+		{
+			name:                   "ascii filename",
+			rows:                   nil,
+			opts:                   []httplib.CSVOption{httplib.WithCSVFilename(`report "2026".csv`)},
+			wantData:               "",
+			wantContentDisposition: `attachment; filename="report \"2026\".csv"`,
+		},
+		{
+			name:                   "non-ascii filename",
+			rows:                   nil,
+			opts:                   []httplib.CSVOption{httplib.WithCSVFilename("売上 2026.csv")},
+			wantData:               "",
+			wantContentDisposition: `attachment; filename="__ 2026.csv"; filename*=UTF-8''%E5%A3%B2%E4%B8%8A%202026.csv`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			renderer := httplib.CSVResponse(slices.Values(tt.rows), tt.opts...)
+
+			w := httptest.NewRecorder()
+
+			assert.NoError(t, renderer.RenderHeader(ctx, w.Header()))
+			assert.NoError(t, renderer.RenderBody(ctx, w))
+
+			assert.Equal(t, httplib.ContentTypeCSVUTF8, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantContentDisposition, w.Header().Get("Content-Disposition"))
+			assert.Empty(t, w.Header().Get("Content-Length"))
+			assert.Equal(t, tt.wantData, w.Body.String())
+		})
+	}
+}
+
+func TestCSVResponse_Flush(t *testing.T) {
+	rows := func(yield func([]string) bool) {
+		for range 250 {
+			if !yield([]string{"a"}) {
+				return
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	require.NoError(t, httplib.CSVResponse(rows).RenderBody(t.Context(), w))
+	assert.True(t, w.Flushed)
+	assert.Equal(t, strings.Repeat("a\n", 250), w.Body.String())
+}
+
+func TestCSVResponse_Error(t *testing.T) {
+	t.Run("response writer error", func(t *testing.T) {
+		err := errors.New("response writer error")
+		w := &errorResponseWriter{ResponseWriter: httptest.NewRecorder(), err: err}
+
+		renderer := httplib.CSVResponse(slices.Values([][]string{{"a"}}))
+		assert.ErrorIs(t, renderer.RenderBody(t.Context(), w), err)
+	})
+
+	t.Run("invalid delimiter", func(t *testing.T) {
+		renderer := httplib.CSVResponse(slices.Values([][]string{{"a"}}), httplib.WithCSVDelimiter('"'))
+		assert.Error(t, renderer.RenderBody(t.Context(), httptest.NewRecorder()))
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		renderer := httplib.CSVResponse(slices.Values([][]string{{"a"}}))
+		assert.ErrorIs(t, renderer.RenderBody(ctx, httptest.NewRecorder()), context.Canceled)
+	})
+}
+
+type csvExportRow struct {
+	ID      int           `csv:"id"`
+	Name    string        `csv:"name"`
+	Amount  *float64      `csv:"amount"`
+	Date    time.Time     `csv:"date" layout:"2006-01-02"`
+	Tags    []string      `csv:"tags"`
+	Elapsed time.Duration `csv:"elapsed"`
+	Ignored string        `csv:"-"`
+}
+
+func TestCSVStructResponse(t *testing.T) {
+	ctx := t.Context()
+	rows := []csvExportRow{
+		{ID: 1, Name: "a", Amount: toPtr(1.5), Date: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), Tags: []string{"x", "y"}, Elapsed: time.Second, Ignored: "z"},
+		{ID: 2, Name: "b"},
+	}
+
+	renderer, err := httplib.CSVStructResponse(slices.Values(rows), httplib.WithCSVHeader("ignored"))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	assert.NoError(t, renderer.RenderHeader(ctx, w.Header()))
+	assert.NoError(t, renderer.RenderBody(ctx, w))
+
+	assert.Equal(t, httplib.ContentTypeCSVUTF8, w.Header().Get("Content-Type"))
+	assert.Equal(t, "id,name,amount,date,tags,elapsed\n1,a,1.5,2026-01-02,\"x,y\",1s\n2,b,,0001-01-01,,0s\n", w.Body.String())
+}
+
+// csvPoint implements encoding.TextMarshaler with a pointer receiver.
+type csvPoint struct {
+	X, Y int
+}
+
+func (p *csvPoint) MarshalText() ([]byte, error) {
+	return fmt.Appendf(nil, "%d:%d", p.X, p.Y), nil
+}
+
+func (p *csvPoint) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d:%d", &p.X, &p.Y)
+	return err
+}
+
+func TestCSVStructResponse_PointerReceiverTextMarshaler(t *testing.T) {
+	type row struct {
+		Point  csvPoint   `csv:"point"`
+		Points []csvPoint `csv:"points"`
+	}
+
+	rows := []row{{Point: csvPoint{X: 1, Y: 2}, Points: []csvPoint{{X: 3, Y: 4}, {X: 5, Y: 6}}}}
+	renderer, err := httplib.CSVStructResponse(slices.Values(rows))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	assert.NoError(t, renderer.RenderBody(t.Context(), w))
+	assert.Equal(t, "point,points\n1:2,\"3:4,5:6\"\n", w.Body.String())
+}
+
+func TestCSVStructResponse_UnsupportedType(t *testing.T) {
+	renderer, err := httplib.CSVStructResponse(slices.Values([]int{1}))
+	assert.Error(t, err)
+	assert.Nil(t, renderer)
+}
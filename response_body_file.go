@@ -0,0 +1,242 @@
+package httplib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// ErrIsDirectory is returned by FileResponse when the file is a directory.
+var ErrIsDirectory = errors.New("httplib: file is a directory")
+
+// sniffLen is the number of bytes that http.DetectContentType considers.
+const sniffLen = 512
+
+// FileOption configures ReaderResponse, ReadSeekerResponse and FileResponse.
+type FileOption func(*fileConfig)
+
+type fileConfig struct {
+	contentType  ContentType
+	disposition  string
+	filename     string
+	lastModified time.Time
+}
+
+func newFileConfig(opts []FileOption) fileConfig {
+	var cfg fileConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	return cfg
+}
+
+// WithFileContentType sets the Content-Type of the response.
+//
+// Without this option, the Content-Type is determined by the extension of the filename,
+// or detected by http.DetectContentType from the first 512 bytes of the content.
+// If contentType is empty, the option is ignored.
+func WithFileContentType(contentType ContentType) FileOption {
+	return func(cfg *fileConfig) {
+		if contentType != "" {
+			cfg.contentType = contentType
+		}
+	}
+}
+
+// WithAttachment sets the Content-Disposition header to "attachment", so that browsers download the content.
+//
+// The filename is encoded as specified in RFC 6266. If filename is empty, FileResponse uses the base name of the file,
+// and the other renderers omit the filename parameter.
+func WithAttachment(filename string) FileOption {
+	return func(cfg *fileConfig) {
+		cfg.disposition, cfg.filename = "attachment", filename
+	}
+}
+
+// WithInline sets the Content-Disposition header to "inline", so that browsers display the content.
+//
+// The filename is handled in the same way as WithAttachment.
+func WithInline(filename string) FileOption {
+	return func(cfg *fileConfig) {
+		cfg.disposition, cfg.filename = "inline", filename
+	}
+}
+
+// WithLastModified sets the Last-Modified header. FileResponse uses the modification time of the file by default.
+func WithLastModified(modTime time.Time) FileOption {
+	return func(cfg *fileConfig) {
+		cfg.lastModified = modTime
+	}
+}
+
+// ReaderResponse returns a ResponseBodyRenderer that renders the content read from r.
+//
+// The length of the content is unknown, so the Content-Length header is not set and the response is chunked.
+// If r implements io.Closer, it is closed after the body is written, even if writing fails.
+func ReaderResponse(r io.Reader, opts ...FileOption) ResponseBodyRenderer {
+	return &readerResponseBodyRenderer{r: r, size: -1, cfg: newFileConfig(opts)}
+}
+
+// ReadSeekerResponse returns a ResponseBodyRenderer that renders the content from the current offset of r to the end.
+//
+// The size of the content is determined by seeking, so that the Content-Length header is set.
+// If seeking fails, it returns an error without closing r.
+// Otherwise, r is closed after the body is written if it implements io.Closer, as ReaderResponse does.
+func ReadSeekerResponse(r io.ReadSeeker, opts ...FileOption) (ResponseBodyRenderer, error) {
+	size, err := remainingSize(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &readerResponseBodyRenderer{r: r, size: size, cfg: newFileConfig(opts)}, nil
+}
+
+// FileResponse returns a ResponseBodyRenderer that renders the file of the name in fsys.
+//
+// The file is opened immediately, so that errors such as fs.ErrNotExist are returned by this function.
+// If the file is a directory, it returns ErrIsDirectory. The Content-Length header is set to the size of the file,
+// and the Last-Modified header to its modification time unless it is zero.
+// The file is closed after the body is written.
+func FileResponse(fsys fs.FS, name string, opts ...FileOption) (ResponseBodyRenderer, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	if info.IsDir() {
+		_ = f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrIsDirectory}
+	}
+
+	cfg := fileConfig{lastModified: info.ModTime()}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	if cfg.disposition != "" && cfg.filename == "" {
+		cfg.filename = path.Base(name)
+	}
+
+	return &readerResponseBodyRenderer{r: f, size: info.Size(), cfg: cfg, name: name}, nil
+}
+
+func remainingSize(r io.Seeker) (int64, error) {
+	offset, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return max(end-offset, 0), nil
+}
+
+type readerResponseBodyRenderer struct {
+	r    io.Reader
+	size int64 // -1 if unknown
+	cfg  fileConfig
+	name string // the name used to determine the Content-Type by extension
+
+	sniffed []byte // the bytes read for sniffing, which are written before the rest of r
+}
+
+func (r *readerResponseBodyRenderer) RenderHeader(_ context.Context, header http.Header) error {
+	contentType, err := r.contentType()
+	if err != nil {
+		return err
+	}
+
+	header.Set("Content-Type", contentType)
+	if r.size >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(r.size, 10))
+	}
+	if r.cfg.disposition != "" {
+		header.Set("Content-Disposition", formatContentDisposition(r.cfg.disposition, r.cfg.filename))
+	}
+	if !r.cfg.lastModified.IsZero() {
+		header.Set("Last-Modified", r.cfg.lastModified.UTC().Format(http.TimeFormat))
+	}
+	return nil
+}
+
+func (r *readerResponseBodyRenderer) contentType() (ContentType, error) {
+	if r.cfg.contentType != "" {
+		return r.cfg.contentType, nil
+	}
+
+	for _, name := range []string{r.name, r.cfg.filename} {
+		if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+			return contentType, nil
+		}
+	}
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(r.r, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+
+	r.sniffed = buf[:n]
+	return http.DetectContentType(r.sniffed), nil
+}
+
+func (r *readerResponseBodyRenderer) RenderHead(_ context.Context) error {
+	if closer, ok := r.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (r *readerResponseBodyRenderer) RenderBody(_ context.Context, w io.Writer) (err error) {
+	if closer, ok := r.r.(io.Closer); ok {
+		defer func() {
+			err = errors.Join(err, closer.Close())
+		}()
+	}
+
+	sniffed := r.sniffed
+	if r.size >= 0 && int64(len(sniffed)) > r.size {
+		sniffed = sniffed[:r.size] // the reader has grown after its size was determined
+	}
+
+	if len(sniffed) != 0 {
+		if _, err := w.Write(sniffed); err != nil {
+			return err
+		}
+	}
+
+	if r.size < 0 {
+		_, err = io.Copy(w, r.r)
+		return err
+	}
+
+	// Content-Length has been announced, so that the bytes appended to the reader after that are not written.
+	if _, err = io.CopyN(w, r.r, r.size-int64(len(sniffed))); errors.Is(err, io.EOF) {
+		return fmt.Errorf("httplib: body is shorter than Content-Length %d: %w", r.size, io.ErrUnexpectedEOF)
+	}
+	return err
+}
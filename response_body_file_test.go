@@ -0,0 +1,346 @@
+package httplib_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+	err    error
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return r.err
+}
+
+func TestReaderResponse(t *testing.T) {
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("JST", 9*60*60))
+
+	tests := []struct {
+		name                   string
+		data                   string
+		opts                   []httplib.FileOption
+		wantContentType        string
+		wantContentDisposition string
+		wantLastModified       string
+	}{
+		{
+			name:            "sniff html",
+			data:            "<!DOCTYPE html><p>hi</p>",
+			wantContentType: httplib.ContentTypeHTMLUTF8,
+		},
+		{
+			name:            "sniff large binary",
+			data:            "\x00" + strings.Repeat("a", 1024),
+			wantContentType: httplib.ContentTypeOctetStream,
+		},
+		{
+			name:            "empty",
+			data:            "",
+			wantContentType: httplib.ContentTypeTextPlainUTF8,
+		},
+		{
+			name:                   "extension of attachment filename",
+			data:                   "a,b\n",
+			opts:                   []httplib.FileOption{httplib.WithAttachment("report.csv")},
+			wantContentType:        httplib.ContentTypeCSVUTF8,
+			wantContentDisposition: `attachment; filename="report.csv"`,
+		},
+		{
+			name:                   "explicit content type and inline without filename",
+			data:                   "{}",
+			opts:                   []httplib.FileOption{httplib.WithFileContentType(httplib.ContentTypeJSON), httplib.WithFileContentType(""), httplib.WithInline(""), nil},
+			wantContentType:        httplib.ContentTypeJSON,
+			wantContentDisposition: "inline",
+		},
+		{
+			name:             "last modified",
+			data:             "text",
+			opts:             []httplib.FileOption{httplib.WithLastModified(modTime)},
+			wantContentType:  httplib.ContentTypeTextPlainUTF8,
+			wantLastModified: "Thu, 01 Jan 2026 18:04:05 GMT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := httplib.WithResponseLogPtr(t.Context(), &httplib.ResponseLog{})
+			source := &closeRecorder{Reader: strings.NewReader(tt.data)}
+
+			w := httptest.NewRecorder()
+			require.NoError(t, httplib.RenderOKWithBody(ctx, w, httplib.ReaderResponse(source, tt.opts...)))
+
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantContentDisposition, w.Header().Get("Content-Disposition"))
+			assert.Equal(t, tt.wantLastModified, w.Header().Get("Last-Modified"))
+			assert.Empty(t, w.Header().Get("Content-Length"))
+			assert.Equal(t, tt.data, w.Body.String())
+			assert.True(t, source.closed)
+			assertResponseLog(ctx, t, http.StatusOK, int64(len(tt.data)), nil)
+		})
+	}
+}
+
+func TestReaderResponse_Error(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("sniff error", func(t *testing.T) {
+		err := errors.New("read error")
+		renderer := httplib.ReaderResponse(errorReader{err: err})
+		assert.ErrorIs(t, renderer.RenderHeader(ctx, http.Header{}), err)
+	})
+
+	t.Run("close error", func(t *testing.T) {
+		err := errors.New("close error")
+		source := &closeRecorder{Reader: strings.NewReader("a"), err: err}
+		renderer := httplib.ReaderResponse(source, httplib.WithFileContentType(httplib.ContentTypeTextPlain))
+
+		w := httptest.NewRecorder()
+		assert.ErrorIs(t, renderer.RenderBody(ctx, w), err)
+		assert.Equal(t, "a", w.Body.String())
+	})
+
+	t.Run("response writer error", func(t *testing.T) {
+		err := errors.New("response writer error")
+		source := &closeRecorder{Reader: strings.NewReader("a")}
+		renderer := httplib.ReaderResponse(source)
+		w := &errorResponseWriter{ResponseWriter: httptest.NewRecorder(), err: err}
+
+		require.NoError(t, renderer.RenderHeader(ctx, w.Header()))
+		assert.ErrorIs(t, renderer.RenderBody(ctx, w), err)
+		assert.True(t, source.closed)
+	})
+}
+
+type errorSeeker struct {
+	io.Reader
+	err error
+}
+
+func (s errorSeeker) Seek(_ int64, _ int) (int64, error) {
+	return 0, s.err
+}
+
+func TestReadSeekerResponse(t *testing.T) {
+	ctx := httplib.WithResponseLogPtr(t.Context(), &httplib.ResponseLog{})
+
+	source := bytes.NewReader([]byte("skip:<html>content"))
+	_, err := source.Seek(5, io.SeekStart)
+	require.NoError(t, err)
+
+	renderer, err := httplib.ReadSeekerResponse(source)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+
+	assert.Equal(t, httplib.ContentTypeHTMLUTF8, w.Header().Get("Content-Type"))
+	assert.Equal(t, "13", w.Header().Get("Content-Length"))
+	assert.Equal(t, "<html>content", w.Body.String())
+	assertResponseLog(ctx, t, http.StatusOK, 13, nil)
+}
+
+// resizableReadSeeker is an io.ReadSeeker whose data can be changed after its size is determined, as a file being written.
+type resizableReadSeeker struct {
+	data   []byte
+	offset int64
+}
+
+func (r *resizableReadSeeker) Read(p []byte) (int, error) {
+	if r.offset >= int64(len(r.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[r.offset:])
+	r.offset += int64(n)
+	return n, nil
+}
+
+func (r *resizableReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += int64(len(r.data))
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func TestReadSeekerResponse_Resized(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		resized      string
+		wantBody     string
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "grown",
+			data:         "content",
+			resized:      "content appended",
+			wantBody:     "content",
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "grown by a large amount",
+			data:         "a",
+			resized:      strings.Repeat("a", 1024),
+			wantBody:     "a",
+			errAssertion: assert.NoError,
+		},
+		{
+			name:     "truncated",
+			data:     "content",
+			resized:  "con",
+			wantBody: "con",
+			errAssertion: func(t assert.TestingT, err error, _ ...any) bool {
+				return assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &resizableReadSeeker{data: []byte(tt.data)}
+			renderer, err := httplib.ReadSeekerResponse(source, httplib.WithFileContentType(httplib.ContentTypeTextPlain))
+			require.NoError(t, err)
+			source.data = []byte(tt.resized)
+
+			w := httptest.NewRecorder()
+			require.NoError(t, renderer.RenderHeader(t.Context(), w.Header()))
+			tt.errAssertion(t, renderer.RenderBody(t.Context(), w))
+
+			assert.Equal(t, strconv.Itoa(len(tt.data)), w.Header().Get("Content-Length"))
+			assert.Equal(t, tt.wantBody, w.Body.String())
+		})
+	}
+
+	t.Run("grown after sniffing", func(t *testing.T) {
+		source := &resizableReadSeeker{data: []byte("<html>")}
+		renderer, err := httplib.ReadSeekerResponse(source)
+		require.NoError(t, err)
+		source.data = []byte("<html>appended")
+
+		w := httptest.NewRecorder()
+		require.NoError(t, renderer.RenderHeader(t.Context(), w.Header()))
+		require.NoError(t, renderer.RenderBody(t.Context(), w))
+		assert.Equal(t, httplib.ContentTypeHTMLUTF8, w.Header().Get("Content-Type"))
+		assert.Equal(t, "<html>", w.Body.String())
+	})
+}
+
+func TestReadSeekerResponse_Error(t *testing.T) {
+	err := errors.New("seek error")
+	renderer, seekErr := httplib.ReadSeekerResponse(errorSeeker{Reader: strings.NewReader("a"), err: err})
+	assert.ErrorIs(t, seekErr, err)
+	assert.Nil(t, renderer)
+}
+
+func TestFileResponse(t *testing.T) {
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"assets/app.js":    {Data: []byte("console.log(1)"), ModTime: modTime},
+		"assets/noext":     {Data: []byte("%PDF-1.7")},
+		"docs/売上 2026.csv": {Data: []byte("a,b\n"), ModTime: modTime},
+	}
+
+	tests := []struct {
+		name                   string
+		file                   string
+		opts                   []httplib.FileOption
+		wantContentType        string
+		wantContentDisposition string
+		wantLastModified       string
+	}{
+		{
+			name:             "extension",
+			file:             "assets/app.js",
+			wantContentType:  "text/javascript; charset=utf-8",
+			wantLastModified: "Fri, 02 Jan 2026 03:04:05 GMT",
+		},
+		{
+			name:            "sniff",
+			file:            "assets/noext",
+			wantContentType: "application/pdf",
+		},
+		{
+			name:                   "attachment with base name",
+			file:                   "docs/売上 2026.csv",
+			opts:                   []httplib.FileOption{httplib.WithAttachment(""), httplib.WithLastModified(time.Time{})},
+			wantContentType:        httplib.ContentTypeCSVUTF8,
+			wantContentDisposition: `attachment; filename="__ 2026.csv"; filename*=UTF-8''%E5%A3%B2%E4%B8%8A%202026.csv`,
+		},
+		{
+			name:                   "inline with filename",
+			file:                   "assets/noext",
+			opts:                   []httplib.FileOption{httplib.WithInline("doc.pdf")},
+			wantContentType:        "application/pdf",
+			wantContentDisposition: `inline; filename="doc.pdf"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := httplib.WithResponseLogPtr(t.Context(), &httplib.ResponseLog{})
+
+			renderer, err := httplib.FileResponse(fsys, tt.file, tt.opts...)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+
+			data := fsys[tt.file].Data
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantContentDisposition, w.Header().Get("Content-Disposition"))
+			assert.Equal(t, tt.wantLastModified, w.Header().Get("Last-Modified"))
+			assert.Equal(t, strconv.Itoa(len(data)), w.Header().Get("Content-Length"))
+			assert.Equal(t, data, w.Body.Bytes())
+			assertResponseLog(ctx, t, http.StatusOK, int64(len(data)), nil)
+		})
+	}
+}
+
+func TestFileResponse_Error(t *testing.T) {
+	fsys := fstest.MapFS{"dir/a.txt": {}}
+
+	tests := []struct {
+		name         string
+		file         string
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name: "not found",
+			file: "missing.txt",
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, fs.ErrNotExist, i...)
+			},
+		},
+		{
+			name: "directory",
+			file: "dir",
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, httplib.ErrIsDirectory, i...)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renderer, err := httplib.FileResponse(fsys, tt.file)
+			tt.errAssertion(t, err)
+			assert.Nil(t, renderer)
+		})
+	}
+}
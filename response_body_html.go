@@ -0,0 +1,231 @@
+package httplib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"sync"
+)
+
+// ErrTemplateNotFound is returned by HTMLResponse when the page template does not exist.
+var ErrTemplateNotFound = errors.New("httplib: template not found")
+
+// TemplateOption configures NewTemplates.
+type TemplateOption func(*templateConfig)
+
+type templateConfig struct {
+	shared []string
+	layout string
+	funcs  template.FuncMap
+	reload bool
+}
+
+// WithTemplateShared parses the files matching the patterns into every page, such as layouts and partials.
+//
+// The patterns are the same as fs.Glob, and each of them must match at least one file.
+// Files matching both the shared patterns and the page pattern are not pages.
+func WithTemplateShared(patterns ...string) TemplateOption {
+	return func(cfg *templateConfig) {
+		cfg.shared = append(cfg.shared, patterns...)
+	}
+}
+
+// WithTemplateLayout executes the template of the name, instead of the page itself, when rendering a page.
+//
+// The layout is usually defined in a shared file and includes the blocks defined by each page, for example:
+//
+//	{{define "layout"}}<html><body>{{block "content" .}}{{end}}</body></html>{{end}}
+func WithTemplateLayout(name string) TemplateOption {
+	return func(cfg *templateConfig) {
+		cfg.layout = name
+	}
+}
+
+// WithTemplateFuncs adds the functions to the templates. It must be given before the templates use them.
+func WithTemplateFuncs(funcs template.FuncMap) TemplateOption {
+	return func(cfg *templateConfig) {
+		if cfg.funcs == nil {
+			cfg.funcs = make(template.FuncMap, len(funcs))
+		}
+		for name, fn := range funcs {
+			cfg.funcs[name] = fn
+		}
+	}
+}
+
+// WithTemplateReload parses the templates again every time a page is rendered,
+// so that changes of the files are reflected without restarting the server.
+//
+// This is intended for development with a disk-backed fs.FS such as os.DirFS, and should not be used in production.
+func WithTemplateReload() TemplateOption {
+	return func(cfg *templateConfig) {
+		cfg.reload = true
+	}
+}
+
+// Templates is a set of html/template pages loaded from an fs.FS.
+//
+// Each page is parsed together with the shared templates given by WithTemplateShared, in a separate template set,
+// so that pages can define blocks of the same name for a layout. Templates is safe for concurrent use.
+type Templates struct {
+	fsys    fs.FS
+	pattern string
+	cfg     templateConfig
+
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+// NewTemplates parses the pages matching the pattern in fsys, such as an embed.FS or os.DirFS.
+//
+// The pattern is the same as fs.Glob, and each page is named by its path in fsys, such as "pages/index.html".
+// If the templates cannot be parsed, or no page matches the pattern, it returns an error.
+func NewTemplates(fsys fs.FS, pattern string, opts ...TemplateOption) (*Templates, error) {
+	t := &Templates{fsys: fsys, pattern: pattern}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&t.cfg)
+		}
+	}
+
+	pages, err := t.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	t.pages = pages
+	return t, nil
+}
+
+// MustNewTemplates is like NewTemplates but panics if the templates cannot be parsed.
+//
+// It simplifies safe initialization of global variables holding templates.
+func MustNewTemplates(fsys fs.FS, pattern string, opts ...TemplateOption) *Templates {
+	t, err := NewTemplates(fsys, pattern, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (t *Templates) parse() (map[string]*template.Template, error) {
+	base := template.New("").Funcs(t.cfg.funcs)
+
+	var shared []string
+	for _, pattern := range t.cfg.shared {
+		matches, err := fs.Glob(t.fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("httplib: template pattern %q matches no files", pattern)
+		}
+		shared = append(shared, matches...)
+	}
+
+	for _, name := range shared {
+		if err := parseTemplateFile(base, t.fsys, name); err != nil {
+			return nil, err
+		}
+	}
+
+	matches, err := fs.Glob(t.fsys, t.pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[string]*template.Template, len(matches))
+	for _, name := range matches {
+		if slices.Contains(shared, name) {
+			continue
+		}
+
+		page, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := parseTemplateFile(page, t.fsys, name); err != nil {
+			return nil, err
+		}
+		pages[name] = page
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("httplib: template pattern %q matches no pages", t.pattern)
+	}
+
+	return pages, nil
+}
+
+// parseTemplateFile parses the file as a template named by its path, so that files of the same base name do not conflict.
+func parseTemplateFile(t *template.Template, fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.New(name).Parse(string(data))
+	return err
+}
+
+func (t *Templates) lookup(name string) (*template.Template, error) {
+	if t.cfg.reload {
+		pages, err := t.parse()
+		if err != nil {
+			return nil, err
+		}
+
+		t.mu.Lock()
+		t.pages = pages
+		t.mu.Unlock()
+	}
+
+	t.mu.RLock()
+	page, ok := t.pages[path.Clean(name)]
+	t.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrTemplateNotFound, name)
+	}
+	return page, nil
+}
+
+// Execute executes the page of the name with data and writes the result to w.
+//
+// If WithTemplateLayout is given, the layout template of the page is executed instead of the page itself.
+// Since the output may be partially written on errors, w should be a buffer. HTMLResponse does so.
+func (t *Templates) Execute(w io.Writer, name string, data any) error {
+	page, err := t.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	entry := path.Clean(name)
+	if t.cfg.layout != "" {
+		entry = t.cfg.layout
+	}
+
+	return page.ExecuteTemplate(w, entry, data)
+}
+
+// HTMLResponse returns a ResponseBodyRenderer that renders the page of the name in templates as HTML.
+//
+// The page is executed immediately into a buffer, so that template errors are returned by this function
+// instead of sending a half-written page, and the Content-Length header is set.
+// The error should be rendered by RenderInternalServerError, so that it is recorded in ResponseLog.Error.
+//
+// If the page does not exist, it returns ErrTemplateNotFound.
+func HTMLResponse(templates *Templates, name string, data any) (ResponseBodyRenderer, error) {
+	var buf bytes.Buffer
+	if err := templates.Execute(&buf, name, data); err != nil {
+		return nil, err
+	}
+
+	return &rawResponseBodyRenderer{b: buf.Bytes(), contentType: ContentTypeHTMLUTF8}, nil
+}
@@ -0,0 +1,197 @@
+package httplib_test
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTemplateFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/base.html":    {Data: []byte(`{{define "base"}}<title>{{block "title" .}}default{{end}}</title>{{template "content" .}}{{template "partials/footer.html" .}}{{end}}`)},
+		"partials/footer.html": {Data: []byte(`<footer>{{upper "footer"}}</footer>`)},
+		"pages/index.html":     {Data: []byte(`{{define "title"}}Index{{end}}{{define "content"}}<p>{{.}}</p>{{end}}`)},
+		"pages/about.html":     {Data: []byte(`{{define "content"}}<p>about</p>{{end}}`)},
+		"pages/broken.html":    {Data: []byte(`{{define "content"}}{{.Missing.Field}}{{end}}`)},
+	}
+}
+
+func newTestTemplates(t *testing.T, fsys fstest.MapFS, opts ...httplib.TemplateOption) *httplib.Templates {
+	opts = append([]httplib.TemplateOption{
+		httplib.WithTemplateShared("layouts/*.html", "partials/*.html"),
+		httplib.WithTemplateLayout("base"),
+		httplib.WithTemplateFuncs(template.FuncMap{"upper": strings.ToUpper}),
+	}, opts...)
+
+	templates, err := httplib.NewTemplates(fsys, "pages/*.html", opts...)
+	require.NoError(t, err)
+	return templates
+}
+
+func TestHTMLResponse(t *testing.T) {
+	templates := newTestTemplates(t, newTemplateFS())
+
+	tests := []struct {
+		name     string
+		page     string
+		data     any
+		wantData string
+	}{
+		{
+			name:     "layout with blocks",
+			page:     "pages/index.html",
+			data:     "<script>",
+			wantData: `<title>Index</title><p>&lt;script&gt;</p><footer>FOOTER</footer>`,
+		},
+		{
+			name:     "default block",
+			page:     "pages/about.html",
+			wantData: `<title>default</title><p>about</p><footer>FOOTER</footer>`,
+		},
+		{
+			name:     "unclean name",
+			page:     "pages/../pages/about.html",
+			wantData: `<title>default</title><p>about</p><footer>FOOTER</footer>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+
+			renderer, err := httplib.HTMLResponse(templates, tt.page, tt.data)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+
+			assert.NoError(t, renderer.RenderHeader(ctx, w.Header()))
+			assert.NoError(t, renderer.RenderBody(ctx, w))
+
+			assert.Equal(t, httplib.ContentTypeHTMLUTF8, w.Header().Get("Content-Type"))
+			assert.Equal(t, strconv.Itoa(len(tt.wantData)), w.Header().Get("Content-Length"))
+			assert.Equal(t, tt.wantData, w.Body.String())
+		})
+	}
+}
+
+func TestHTMLResponse_Error(t *testing.T) {
+	templates := newTestTemplates(t, newTemplateFS())
+
+	t.Run("not found", func(t *testing.T) {
+		renderer, err := httplib.HTMLResponse(templates, "pages/missing.html", nil)
+		assert.ErrorIs(t, err, httplib.ErrTemplateNotFound)
+		assert.Nil(t, renderer)
+	})
+
+	t.Run("execution error", func(t *testing.T) {
+		renderer, err := httplib.HTMLResponse(templates, "pages/broken.html", 1)
+		assert.Error(t, err)
+		assert.Nil(t, renderer)
+	})
+}
+
+func TestTemplates_WithoutLayout(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte(`<p>{{template "_item.html" .}}</p>`)},
+		"_item.html": {Data: []byte(`{{.}}`)},
+	}
+
+	templates, err := httplib.NewTemplates(fsys, "*.html", httplib.WithTemplateShared("_*.html"))
+	require.NoError(t, err)
+
+	var b strings.Builder
+	require.NoError(t, templates.Execute(&b, "index.html", "a"))
+	assert.Equal(t, "<p>a</p>", b.String())
+
+	assert.ErrorIs(t, templates.Execute(&b, "_item.html", "a"), httplib.ErrTemplateNotFound)
+}
+
+func TestTemplates_Reload(t *testing.T) {
+	fsys := newTemplateFS()
+
+	t.Run("reload", func(t *testing.T) {
+		templates := newTestTemplates(t, fsys, httplib.WithTemplateReload())
+		fsys["pages/about.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}<p>changed</p>{{end}}`)}
+		t.Cleanup(func() { fsys["pages/about.html"] = newTemplateFS()["pages/about.html"] })
+
+		var b strings.Builder
+		require.NoError(t, templates.Execute(&b, "pages/about.html", nil))
+		assert.Equal(t, `<title>default</title><p>changed</p><footer>FOOTER</footer>`, b.String())
+	})
+
+	t.Run("no reload", func(t *testing.T) {
+		templates := newTestTemplates(t, fsys)
+		fsys["pages/about.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}<p>changed</p>{{end}}`)}
+		t.Cleanup(func() { fsys["pages/about.html"] = newTemplateFS()["pages/about.html"] })
+
+		var b strings.Builder
+		require.NoError(t, templates.Execute(&b, "pages/about.html", nil))
+		assert.Equal(t, `<title>default</title><p>about</p><footer>FOOTER</footer>`, b.String())
+	})
+
+	t.Run("reload error", func(t *testing.T) {
+		templates := newTestTemplates(t, fsys, httplib.WithTemplateReload())
+		fsys["pages/about.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}`)}
+		t.Cleanup(func() { fsys["pages/about.html"] = newTemplateFS()["pages/about.html"] })
+
+		var b strings.Builder
+		assert.Error(t, templates.Execute(&b, "pages/about.html", nil))
+	})
+}
+
+func TestNewTemplates_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		pattern string
+		opts    []httplib.TemplateOption
+	}{
+		{
+			name:    "no pages",
+			fsys:    fstest.MapFS{"a.txt": {}},
+			pattern: "*.html",
+		},
+		{
+			name:    "only shared files",
+			fsys:    fstest.MapFS{"a.html": {}},
+			pattern: "*.html",
+			opts:    []httplib.TemplateOption{httplib.WithTemplateShared("*.html")},
+		},
+		{
+			name:    "shared pattern matches no files",
+			fsys:    fstest.MapFS{"a.html": {}},
+			pattern: "*.html",
+			opts:    []httplib.TemplateOption{httplib.WithTemplateShared("layouts/*.html")},
+		},
+		{
+			name:    "bad pattern",
+			fsys:    fstest.MapFS{"a.html": {}},
+			pattern: "[",
+		},
+		{
+			name:    "parse error",
+			fsys:    fstest.MapFS{"a.html": {Data: []byte(`{{if}}`)}},
+			pattern: "*.html",
+		},
+		{
+			name:    "undefined function",
+			fsys:    fstest.MapFS{"a.html": {Data: []byte(`{{upper .}}`)}},
+			pattern: "*.html",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := httplib.NewTemplates(tt.fsys, tt.pattern, tt.opts...)
+			assert.Error(t, err)
+			assert.Nil(t, templates)
+
+			assert.Panics(t, func() { httplib.MustNewTemplates(tt.fsys, tt.pattern, tt.opts...) })
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package httplib_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/Siroshun09/go-httplib"
@@ -207,3 +212,176 @@ func TestRawResponseWithContentType(t *testing.T) {
 		})
 	}
 }
+
+type benchmarkItem struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Tags  []string `json:"tags"`
+	Score float64  `json:"score"`
+}
+
+func newBenchmarkItems(n int) []benchmarkItem {
+	items := make([]benchmarkItem, n)
+	for i := range items {
+		items[i] = benchmarkItem{ID: i, Name: "name" + strconv.Itoa(i), Email: "user@example.com", Tags: []string{"a", "b"}, Score: 1.5}
+	}
+	return items
+}
+
+// BenchmarkJSONResponse compares JSONResponse, which encodes the value into a pooled buffer,
+// with marshaling the value by json.Marshal into a new slice and rendering it by RawResponseWithContentType.
+//
+//	go test -run '^$' -bench BenchmarkJSONResponse -benchmem
+func BenchmarkJSONResponse(b *testing.B) {
+	for _, size := range []int{1, 100} {
+		items := newBenchmarkItems(size)
+		ctx := b.Context()
+
+		b.Run("renderer/items="+strconv.Itoa(size), func(b *testing.B) {
+			b.ReportAllocs()
+			header := make(http.Header)
+			for b.Loop() {
+				renderer, err := httplib.JSONResponse(items)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := renderer.RenderHeader(ctx, header); err != nil {
+					b.Fatal(err)
+				}
+				if err := renderer.RenderBody(ctx, io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run("marshal/items="+strconv.Itoa(size), func(b *testing.B) {
+			b.ReportAllocs()
+			header := make(http.Header)
+			for b.Loop() {
+				data, err := json.Marshal(items)
+				if err != nil {
+					b.Fatal(err)
+				}
+				renderer := httplib.RawResponseWithContentType(data, httplib.ContentTypeJSONUTF8)
+				if err := renderer.RenderHeader(ctx, header); err != nil {
+					b.Fatal(err)
+				}
+				if err := renderer.RenderBody(ctx, io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONResponse_Release(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("body", func(t *testing.T) {
+		renderer, err := httplib.JSONResponse(map[string]int{"a": 1})
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		require.NoError(t, renderer.RenderHeader(ctx, w.Header()))
+		require.NoError(t, renderer.RenderBody(ctx, w))
+		assert.Equal(t, `{"a":1}`, w.Body.String())
+
+		// Another renderer reuses the buffer released by RenderBody, but the renderer marshals the value again.
+		next, err := httplib.JSONResponse(map[string]int{"b": 2})
+		require.NoError(t, err)
+		require.NoError(t, next.RenderBody(ctx, httptest.NewRecorder()))
+
+		w = httptest.NewRecorder()
+		require.NoError(t, renderer.RenderHeader(ctx, w.Header()))
+		require.NoError(t, renderer.RenderBody(ctx, w))
+		assert.Equal(t, "7", w.Header().Get("Content-Length"))
+		assert.Equal(t, `{"a":1}`, w.Body.String())
+	})
+
+	t.Run("head", func(t *testing.T) {
+		renderer, err := httplib.JSONResponse(map[string]int{"a": 1})
+		require.NoError(t, err)
+
+		headCtx := httplib.WithRequestMethod(ctx, http.MethodHead)
+		w := httptest.NewRecorder()
+		require.NoError(t, httplib.RenderOKWithBody(headCtx, w, renderer))
+		assert.Equal(t, "7", w.Header().Get("Content-Length"))
+		assert.Empty(t, w.Body.String())
+
+		w = httptest.NewRecorder()
+		require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+		assert.Equal(t, `{"a":1}`, w.Body.String())
+	})
+
+	t.Run("content digest", func(t *testing.T) {
+		renderer, err := httplib.JSONResponse(map[string]int{"a": 1})
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		require.NoError(t, httplib.RenderOKWithBody(ctx, w, httplib.ContentDigestResponse(renderer)))
+		assert.Equal(t, `{"a":1}`, w.Body.String())
+		assert.NotEmpty(t, w.Header().Get("Content-Digest"))
+	})
+
+	t.Run("large", func(t *testing.T) {
+		items := newBenchmarkItems(2000)
+		renderer, err := httplib.JSONResponse(items)
+		require.NoError(t, err)
+
+		want, err := json.Marshal(items)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		require.NoError(t, renderer.RenderHeader(ctx, w.Header()))
+		require.NoError(t, renderer.RenderBody(ctx, w))
+		assert.Equal(t, strconv.Itoa(len(want)), w.Header().Get("Content-Length"))
+		assert.Equal(t, want, w.Body.Bytes())
+	})
+}
+
+func TestJSONResponse_Reusable(t *testing.T) {
+	renderer, err := httplib.JSONResponse(map[string]int{"a": 1})
+	require.NoError(t, err)
+
+	render := func(ctx context.Context) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		if err := httplib.RenderOKWithBody(ctx, w, renderer); err != nil {
+			t.Error(err)
+		}
+		return w
+	}
+
+	t.Run("sequential", func(t *testing.T) {
+		for range 3 {
+			w := render(t.Context())
+			assert.Equal(t, "7", w.Header().Get("Content-Length"))
+			assert.Equal(t, `{"a":1}`, w.Body.String())
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		other, err := httplib.JSONResponse(map[string]string{"b": "other"})
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := range 20 {
+			wg.Go(func() {
+				if i%2 == 1 {
+					// Another renderer takes and releases pooled buffers concurrently.
+					_ = other.RenderBody(t.Context(), io.Discard)
+					return
+				}
+
+				ctx := t.Context()
+				if i%4 == 0 {
+					ctx = httplib.WithCodec(ctx, httplib.JSONCodec{DisableHTMLEscape: true})
+				}
+				w := render(ctx)
+				assert.Equal(t, "7", w.Header().Get("Content-Length"))
+				assert.Equal(t, `{"a":1}`, w.Body.String())
+			})
+		}
+		wg.Wait()
+	})
+}
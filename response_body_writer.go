@@ -1,7 +1,12 @@
 package httplib
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"maps"
 	"net/http"
+	"strconv"
 )
 
 // responseBodyWriter implements io.Writer using http.ResponseWriter and counts the number of bytes written.
@@ -15,3 +20,104 @@ func (w *responseBodyWriter) Write(b []byte) (int, error) {
 	w.responseSize += int64(n)
 	return n, err
 }
+
+// Flush sends any buffered data to the client, so that streaming renderers can flush the response as http.Flusher.
+// It does nothing if the underlying http.ResponseWriter does not support flushing.
+func (w *responseBodyWriter) Flush() {
+	_ = http.NewResponseController(w.w).Flush()
+}
+
+// ReadFrom copies r to the underlying http.ResponseWriter, so that it can use its io.ReaderFrom implementation
+// such as sendfile(2) for files. The number of bytes copied is counted in the same way as Write.
+func (w *responseBodyWriter) ReadFrom(r io.Reader) (int64, error) {
+	var n int64
+	var err error
+	if readerFrom, ok := w.w.(io.ReaderFrom); ok {
+		n, err = readerFrom.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.w, r)
+	}
+	w.responseSize += n
+	return n, err
+}
+
+// responseCaptureWriter writes the response to the client, and captures its status code, headers and body,
+// so that middlewares can store or share the response.
+type responseCaptureWriter struct {
+	http.ResponseWriter
+	maxBytes   int64 // the size limit of the captured body, or 0 for no limit
+	statusCode int
+	header     http.Header
+	body       bytes.Buffer
+	overflow   bool // true if the body exceeded maxBytes, in which case it is no longer captured
+}
+
+func (w *responseCaptureWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 && statusCode >= 200 {
+		w.statusCode = statusCode
+		w.header = w.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseCaptureWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.overflow {
+		if w.maxBytes > 0 && int64(w.body.Len()+len(b)) > w.maxBytes {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseCaptureWriter) Flush() {
+	if w.statusCode == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *responseCaptureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish writes 200 OK if the handler wrote nothing, as the server does after the handler returns.
+func (w *responseCaptureWriter) finish() {
+	if w.statusCode == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// capturedResponseRenderer renders the headers and the body captured by responseCaptureWriter.
+type capturedResponseRenderer struct {
+	header      http.Header
+	body        []byte
+	bodyOmitted bool // true if the response is to a HEAD request, whose Content-Length is not the length of body
+}
+
+func (r *capturedResponseRenderer) RenderHeader(_ context.Context, header http.Header) error {
+	maps.Copy(header, r.header.Clone())
+	if header.Get("Content-Length") == "" && !r.bodyOmitted && len(r.body) != 0 {
+		header.Set("Content-Length", strconv.Itoa(len(r.body)))
+	}
+	return nil
+}
+
+func (r *capturedResponseRenderer) RenderHead(_ context.Context) error {
+	return nil
+}
+
+func (r *capturedResponseRenderer) RenderBody(_ context.Context, w io.Writer) error {
+	_, err := w.Write(r.body)
+	return err
+}
+
+func (r *capturedResponseRenderer) bufferedBody() ([]byte, bool) {
+	return r.body, true
+}
@@ -2,7 +2,9 @@ package httplib_test
 
 import (
 	"errors"
+	"io"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/Siroshun09/go-httplib"
@@ -36,3 +38,25 @@ func Test_responseBodyWriter_Write(t *testing.T) {
 		assert.Zero(t, wrapped.ResponseSize())
 	})
 }
+
+type readerFromResponseWriter struct {
+	*httptest.ResponseRecorder
+	called bool
+}
+
+func (w *readerFromResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.called = true
+	return io.Copy(w.ResponseRecorder, r)
+}
+
+func Test_responseBodyWriter_ReadFrom(t *testing.T) {
+	w := &readerFromResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	wrapped := httplib.NewResponseBodyWriter(w)
+
+	size, err := wrapped.ReadFrom(strings.NewReader("0123456789"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, size)
+	assert.True(t, w.called)
+	assert.Equal(t, "0123456789", w.Body.String())
+	assert.EqualValues(t, 10, wrapped.ResponseSize())
+}
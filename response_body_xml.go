@@ -0,0 +1,127 @@
+package httplib
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+)
+
+// XMLOption configures XMLResponse and XMLStreamResponse.
+type XMLOption func(*xmlConfig)
+
+type xmlConfig struct {
+	header      bool
+	prefix      string
+	indent      string
+	contentType ContentType
+}
+
+func newXMLConfig(opts []XMLOption) xmlConfig {
+	cfg := xmlConfig{contentType: ContentTypeXMLUTF8}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	return cfg
+}
+
+func (cfg xmlConfig) newEncoder(w io.Writer) (*xml.Encoder, error) {
+	if cfg.header {
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return nil, err
+		}
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent(cfg.prefix, cfg.indent)
+	return encoder, nil
+}
+
+// WithXMLHeader writes the XML declaration (xml.Header) before the document.
+func WithXMLHeader() XMLOption {
+	return func(cfg *xmlConfig) {
+		cfg.header = true
+	}
+}
+
+// WithXMLIndent indents the document as xml.Encoder.Indent does.
+func WithXMLIndent(prefix, indent string) XMLOption {
+	return func(cfg *xmlConfig) {
+		cfg.prefix = prefix
+		cfg.indent = indent
+	}
+}
+
+// WithXMLContentType sets the Content-Type of the response, such as ContentTypeTextXMLUTF8.
+//
+// The default is ContentTypeXMLUTF8. encoding/xml always writes UTF-8, so the charset should be utf-8 if present.
+// If contentType is empty, the option is ignored.
+func WithXMLContentType(contentType ContentType) XMLOption {
+	return func(cfg *xmlConfig) {
+		if contentType != "" {
+			cfg.contentType = contentType
+		}
+	}
+}
+
+// XMLResponse returns a ResponseBodyRenderer that renders v as XML marshaled by encoding/xml.
+//
+// The value is marshaled immediately, so that marshaling errors are returned by this function,
+// and the Content-Length header is set. Use XMLStreamResponse for large documents.
+func XMLResponse(v any, opts ...XMLOption) (ResponseBodyRenderer, error) {
+	cfg := newXMLConfig(opts)
+
+	var buf bytes.Buffer
+	encoder, err := cfg.newEncoder(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return &rawResponseBodyRenderer{b: buf.Bytes(), contentType: cfg.contentType}, nil
+}
+
+// XMLStreamResponse returns a ResponseBodyRenderer that renders an XML document written by the write function.
+//
+// The write function is called in RenderBody with an xml.Encoder writing directly to the response,
+// so the whole document is never held in memory. It can write tokens by xml.Encoder.EncodeToken
+// or elements by xml.Encoder.EncodeElement. The encoder is flushed after write returns.
+//
+// The Content-Length header is not set. Since the status code has already been written when write is called,
+// errors returned by write are only returned from RenderBody, and the response may be truncated.
+func XMLStreamResponse(write func(encoder *xml.Encoder) error, opts ...XMLOption) ResponseBodyRenderer {
+	return &xmlStreamResponseBodyRenderer{write: write, cfg: newXMLConfig(opts)}
+}
+
+type xmlStreamResponseBodyRenderer struct {
+	write func(encoder *xml.Encoder) error
+	cfg   xmlConfig
+}
+
+func (r *xmlStreamResponseBodyRenderer) RenderHeader(_ context.Context, header http.Header) error {
+	header.Set("Content-Type", r.cfg.contentType)
+	return nil
+}
+
+func (r *xmlStreamResponseBodyRenderer) RenderHead(_ context.Context) error {
+	return nil
+}
+
+func (r *xmlStreamResponseBodyRenderer) RenderBody(_ context.Context, w io.Writer) error {
+	encoder, err := r.cfg.newEncoder(w)
+	if err != nil {
+		return err
+	}
+
+	if err := r.write(encoder); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
@@ -0,0 +1,130 @@
+package httplib_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type xmlItem struct {
+	XMLName xml.Name `xml:"item"`
+	ID      int      `xml:"id,attr"`
+	Name    string   `xml:"name"`
+}
+
+func TestXMLResponse(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            any
+		opts            []httplib.XMLOption
+		wantData        string
+		wantContentType string
+	}{
+		{
+			name:            "default",
+			data:            xmlItem{ID: 1, Name: "a&b"},
+			wantData:        `<item id="1"><name>a&amp;b</name></item>`,
+			wantContentType: httplib.ContentTypeXMLUTF8,
+		},
+		{
+			name:            "header and indent",
+			data:            xmlItem{ID: 1, Name: "a"},
+			opts:            []httplib.XMLOption{httplib.WithXMLHeader(), httplib.WithXMLIndent("", "  ")},
+			wantData:        xml.Header + "<item id=\"1\">\n  <name>a</name>\n</item>",
+			wantContentType: httplib.ContentTypeXMLUTF8,
+		},
+		{
+			name:            "content type",
+			data:            xmlItem{ID: 1},
+			opts:            []httplib.XMLOption{httplib.WithXMLContentType(httplib.ContentTypeTextXMLUTF8), httplib.WithXMLContentType(""), nil},
+			wantData:        `<item id="1"><name></name></item>`,
+			wantContentType: httplib.ContentTypeTextXMLUTF8,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+
+			renderer, err := httplib.XMLResponse(tt.data, tt.opts...)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+
+			assert.NoError(t, renderer.RenderHeader(ctx, w.Header()))
+			assert.NoError(t, renderer.RenderBody(ctx, w))
+
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, strconv.Itoa(len(tt.wantData)), w.Header().Get("Content-Length"))
+			assert.Equal(t, tt.wantData, w.Body.String())
+		})
+	}
+}
+
+func TestXMLResponse_UnsupportedType(t *testing.T) {
+	renderer, err := httplib.XMLResponse(map[string]string{"a": "b"})
+	assert.Error(t, err)
+	assert.Nil(t, renderer)
+}
+
+func TestXMLStreamResponse(t *testing.T) {
+	ctx := t.Context()
+
+	renderer := httplib.XMLStreamResponse(func(encoder *xml.Encoder) error {
+		start := xml.StartElement{Name: xml.Name{Local: "items"}}
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+
+		for i := range 2 {
+			if err := encoder.Encode(xmlItem{ID: i, Name: "n" + strconv.Itoa(i)}); err != nil {
+				return err
+			}
+		}
+
+		return encoder.EncodeToken(start.End())
+	}, httplib.WithXMLHeader())
+
+	w := httptest.NewRecorder()
+
+	assert.NoError(t, renderer.RenderHeader(ctx, w.Header()))
+	assert.NoError(t, renderer.RenderBody(ctx, w))
+
+	assert.Equal(t, httplib.ContentTypeXMLUTF8, w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Content-Length"))
+	assert.Equal(t, xml.Header+`<items><item id="0"><name>n0</name></item><item id="1"><name>n1</name></item></items>`, w.Body.String())
+}
+
+func TestXMLStreamResponse_Error(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("write error", func(t *testing.T) {
+		writeErr := errors.New("write error")
+		renderer := httplib.XMLStreamResponse(func(encoder *xml.Encoder) error {
+			return writeErr
+		})
+		assert.ErrorIs(t, renderer.RenderBody(ctx, httptest.NewRecorder()), writeErr)
+	})
+
+	t.Run("unclosed element", func(t *testing.T) {
+		renderer := httplib.XMLStreamResponse(func(encoder *xml.Encoder) error {
+			return encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "items"}})
+		})
+		assert.Error(t, renderer.RenderBody(ctx, httptest.NewRecorder()))
+	})
+
+	t.Run("response writer error", func(t *testing.T) {
+		err := errors.New("response writer error")
+		w := &errorResponseWriter{ResponseWriter: httptest.NewRecorder(), err: err}
+
+		renderer := httplib.XMLStreamResponse(func(encoder *xml.Encoder) error {
+			return encoder.Encode(xmlItem{})
+		}, httplib.WithXMLHeader())
+		assert.ErrorIs(t, renderer.RenderBody(ctx, w), err)
+	})
+}
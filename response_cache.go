@@ -0,0 +1,564 @@
+package httplib
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CacheStatusHit is ResponseLog.CacheStatus of a response served from the cache while it is fresh.
+	CacheStatusHit = "hit"
+	// CacheStatusStale is ResponseLog.CacheStatus of a stale response served from the cache while it is revalidated.
+	CacheStatusStale = "stale"
+	// CacheStatusMiss is ResponseLog.CacheStatus of a response rendered by the handler because it is not in the cache.
+	CacheStatusMiss = "miss"
+	// CacheStatusBypass is ResponseLog.CacheStatus of a response to a request whose method is not cacheable.
+	CacheStatusBypass = "bypass"
+)
+
+// ErrResponseCacheRefreshPanicked is wrapped by the error of a background refresh of ResponseCache
+// whose handler panicked. The panic value is included in the message.
+var ErrResponseCacheRefreshPanicked = errors.New("httplib: handler of the cache refresh panicked")
+
+// defaultResponseCacheMaxBytes is the default size limit of ResponseCache.
+const defaultResponseCacheMaxBytes = 64 << 20
+
+// ResponseCacheOption configures NewResponseCache.
+type ResponseCacheOption func(*responseCacheConfig)
+
+type responseCacheConfig struct {
+	maxBytes       int64
+	maxTTL         time.Duration
+	name           string
+	onRefreshError func(ctx context.Context, err error)
+}
+
+// WithResponseCacheMaxBytes sets the size limit of the cached responses, including their headers.
+// The least recently used responses are evicted when the limit is exceeded,
+// and responses larger than the limit are not stored. The default is 64 MiB.
+func WithResponseCacheMaxBytes(maxBytes int64) ResponseCacheOption {
+	return func(cfg *responseCacheConfig) {
+		cfg.maxBytes = maxBytes
+	}
+}
+
+// WithResponseCacheMaxTTL caps the freshness lifetime given by the Cache-Control header of responses.
+func WithResponseCacheMaxTTL(maxTTL time.Duration) ResponseCacheOption {
+	return func(cfg *responseCacheConfig) {
+		cfg.maxTTL = maxTTL
+	}
+}
+
+// WithResponseCacheName sets the cache name in the Cache-Status header. The default is "httplib".
+func WithResponseCacheName(name string) ResponseCacheOption {
+	return func(cfg *responseCacheConfig) {
+		cfg.name = name
+	}
+}
+
+// WithResponseCacheRefreshErrorHandler sets the function called with the error of a background refresh,
+// such as ErrResponseCacheRefreshPanicked. The context is the one of the background request,
+// whose ResponseLog has the error in ResponseLog.Error.
+func WithResponseCacheRefreshErrorHandler(onError func(ctx context.Context, err error)) ResponseCacheOption {
+	return func(cfg *responseCacheConfig) {
+		cfg.onRefreshError = onError
+	}
+}
+
+// ResponseCache is an in-memory shared cache of responses (RFC 9111), used by its Middleware.
+//
+// Responses to GET and HEAD requests are stored if their Cache-Control header has "s-maxage" or "max-age",
+// and has none of "no-store", "no-cache" and "private". Responses with Set-Cookie, trailers or "Vary: *" are not stored.
+// The responses are keyed by the method, the host and the URL of the request,
+// and the request headers listed in the Vary header of the response.
+//
+// ResponseCache is safe for concurrent use.
+type ResponseCache struct {
+	cfg responseCacheConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	resources map[string]*cachedResource
+	lru       *list.List // of *cachedResponse, the most recently used first
+	size      int64
+}
+
+// cachedResource holds the cached variants of a resource, which are selected by the request headers listed in vary.
+type cachedResource struct {
+	vary     []string
+	variants map[string]*list.Element
+}
+
+type cachedResponse struct {
+	resourceKey string
+	variantKey  string
+	size        int64
+
+	statusCode  int
+	header      http.Header
+	body        []byte
+	bodyOmitted bool // true if the response is to a HEAD request
+	handlerInfo HandlerInfo
+
+	storedAt             time.Time
+	ttl                  time.Duration
+	staleWhileRevalidate time.Duration
+	refreshing           bool
+}
+
+// NewResponseCache returns an empty ResponseCache.
+func NewResponseCache(opts ...ResponseCacheOption) *ResponseCache {
+	cfg := responseCacheConfig{maxBytes: defaultResponseCacheMaxBytes, name: "httplib"}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	return &ResponseCache{cfg: cfg, now: time.Now, resources: make(map[string]*cachedResource), lru: list.New()}
+}
+
+// Middleware returns a middleware that serves the cached responses without invoking the handler,
+// and stores the responses rendered by the handler.
+//
+// Every response has the Cache-Status header (RFC 9211), and the result is recorded in ResponseLog.CacheStatus.
+// Cached responses are rendered by the Render functions of this package, with the HandlerInfo of the handler
+// that rendered them, and the Age header.
+//
+// If the response has the "stale-while-revalidate" directive, the stale response is served
+// within the duration after it expires, while the handler is invoked in the background to refresh it.
+// Only one refresh runs at a time for each response. The background request has a context
+// that is not canceled with the original request, and a separate ResponseLog.
+// If the handler panics in the background, the panic is recovered and the stale response is kept,
+// since http.Server recovers panics only in its own goroutines. The error is recorded in ResponseLog.Error
+// of the background request, and passed to the function set by WithResponseCacheRefreshErrorHandler.
+//
+// Requests with the "no-cache" or "max-age=0" directive in the Cache-Control header, or with the Authorization header,
+// are not served from the cache. Responses to requests with the Authorization header are stored
+// only if they have the "public" or "s-maxage" directive.
+func (c *ResponseCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithRequestMethod(r.Context(), r.Method)
+		r = r.WithContext(ctx)
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Cache-Status", c.cfg.name+"; fwd=method")
+			next.ServeHTTP(w, r)
+			setCacheStatus(ctx, CacheStatusBypass)
+			return
+		}
+
+		fwd := "request"
+		if !requestBypassesCache(r) {
+			entry, age, status, refresh := c.lookup(r)
+			if entry != nil {
+				if refresh {
+					go c.refresh(next, r.Clone(context.WithoutCancel(ctx)), entry)
+				}
+				c.serve(ctx, w, entry, age, status)
+				return
+			}
+			fwd = status
+		}
+
+		cw := &responseCacheWriter{ResponseWriter: w, cache: c, r: r, fwd: fwd}
+		next.ServeHTTP(cw, r)
+		c.store(r, cw, GetResponseLogPtrFromContext(ctx))
+		setCacheStatus(ctx, CacheStatusMiss)
+	})
+}
+
+func setCacheStatus(ctx context.Context, status string) {
+	if resPtr := GetResponseLogPtrFromContext(ctx); resPtr != nil {
+		resPtr.CacheStatus = status
+	}
+}
+
+func requestBypassesCache(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return true
+	}
+
+	directives := parseCacheControl(r.Header.Values("Cache-Control"))
+	if _, ok := directives["no-cache"]; ok {
+		return true
+	}
+	maxAge, ok := directives["max-age"]
+	return ok && maxAge == "0"
+}
+
+// lookup returns the cached response of the request and its age with CacheStatusHit or CacheStatusStale.
+// If the response is not cached, it returns nil with the "fwd" parameter of the Cache-Status header.
+// It reports true only to the first caller that gets the stale response, which is responsible for refreshing it.
+func (c *ResponseCache) lookup(r *http.Request) (*cachedResponse, time.Duration, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resource, ok := c.resources[cacheResourceKey(r)]
+	if !ok {
+		return nil, 0, "uri-miss", false
+	}
+
+	elem, ok := resource.variants[cacheVariantKey(r.Header, resource.vary)]
+	if !ok {
+		return nil, 0, "vary-miss", false
+	}
+
+	entry := elem.Value.(*cachedResponse)
+	age := c.now().Sub(entry.storedAt)
+	switch {
+	case age < entry.ttl:
+		c.lru.MoveToFront(elem)
+		return entry, age, CacheStatusHit, false
+	case age < entry.ttl+entry.staleWhileRevalidate:
+		c.lru.MoveToFront(elem)
+		refresh := !entry.refreshing
+		entry.refreshing = true
+		return entry, age, CacheStatusStale, refresh
+	default:
+		c.remove(elem)
+		return nil, 0, "stale", false
+	}
+}
+
+func (c *ResponseCache) serve(ctx context.Context, w http.ResponseWriter, entry *cachedResponse, age time.Duration, status string) {
+	cacheStatus := c.cfg.name + "; hit; ttl=" + strconv.FormatInt(int64((entry.ttl-age)/time.Second), 10)
+	if status == CacheStatusStale {
+		cacheStatus += "; fwd=stale"
+	}
+
+	// The error can only be the failure of writing the body to the client, which cannot be reported anymore.
+	_ = renderWithBody(ctx, w, entry.statusCode, &cachedResponseRenderer{entry: entry, age: age, cacheStatus: cacheStatus}, nil)
+
+	if resPtr := GetResponseLogPtrFromContext(ctx); resPtr != nil {
+		resPtr.CacheStatus = status
+		resPtr.HandlerInfo = entry.handlerInfo
+	}
+}
+
+// refresh invokes the handler with the request to replace the stale response.
+func (c *ResponseCache) refresh(next http.Handler, r *http.Request, stale *cachedResponse) {
+	defer func() {
+		c.mu.Lock()
+		stale.refreshing = false
+		c.mu.Unlock()
+	}()
+
+	var res ResponseLog
+	r = r.WithContext(WithResponseLogPtr(r.Context(), &res))
+
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			err := fmt.Errorf("%w: %v", ErrResponseCacheRefreshPanicked, rvr)
+			res.Error = errors.Join(res.Error, err)
+			if c.cfg.onRefreshError != nil {
+				c.cfg.onRefreshError(r.Context(), err)
+			}
+		}
+	}()
+
+	cw := &responseCacheWriter{ResponseWriter: &discardResponseWriter{header: make(http.Header)}, cache: c, r: r, fwd: "stale"}
+	next.ServeHTTP(cw, r)
+	c.store(r, cw, &res)
+}
+
+// cacheability returns the freshness lifetime and the stale-while-revalidate duration of the response,
+// or false if it cannot be stored.
+func (c *ResponseCache) cacheability(r *http.Request, statusCode int, header http.Header) (time.Duration, time.Duration, bool) {
+	switch statusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent, http.StatusMovedPermanently,
+		http.StatusPermanentRedirect, http.StatusNotFound, http.StatusGone:
+	default:
+		return 0, 0, false
+	}
+
+	if header.Get("Set-Cookie") != "" || header.Get("Trailer") != "" || headerListContains(header.Values("Vary"), "*") {
+		return 0, 0, false
+	}
+
+	directives := parseCacheControl(header.Values("Cache-Control"))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[directive]; ok {
+			return 0, 0, false
+		}
+	}
+
+	_, public := directives["public"]
+	sharedMaxAge, hasSharedMaxAge := directives["s-maxage"]
+	if r.Header.Get("Authorization") != "" && !public && !hasSharedMaxAge {
+		return 0, 0, false
+	}
+
+	maxAge := sharedMaxAge
+	if !hasSharedMaxAge {
+		maxAge = directives["max-age"]
+	}
+
+	ttl, ok := parseDeltaSeconds(maxAge)
+	if !ok || ttl <= 0 {
+		return 0, 0, false
+	}
+	if c.cfg.maxTTL > 0 {
+		ttl = min(ttl, c.cfg.maxTTL)
+	}
+
+	staleWhileRevalidate, _ := parseDeltaSeconds(directives["stale-while-revalidate"])
+	return ttl, staleWhileRevalidate, true
+}
+
+// store stores the response captured by the writer if it can be stored.
+func (c *ResponseCache) store(r *http.Request, cw *responseCacheWriter, resPtr *ResponseLog) {
+	if !cw.storable || cw.overflow {
+		return
+	}
+
+	header := cw.header
+	vary := cacheVaryFields(header)
+	entry := &cachedResponse{
+		resourceKey:          cacheResourceKey(r),
+		variantKey:           cacheVariantKey(r.Header, vary),
+		statusCode:           cw.statusCode,
+		header:               header,
+		body:                 cw.body.Bytes(),
+		bodyOmitted:          r.Method == http.MethodHead,
+		storedAt:             c.now(),
+		ttl:                  cw.ttl,
+		staleWhileRevalidate: cw.staleWhileRevalidate,
+	}
+	if resPtr != nil {
+		entry.handlerInfo = resPtr.HandlerInfo
+	}
+
+	entry.size = int64(len(entry.resourceKey) + len(entry.variantKey) + len(entry.body))
+	for name, values := range header {
+		for _, value := range values {
+			entry.size += int64(len(name) + len(value))
+		}
+	}
+	if entry.size > c.cfg.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resource, ok := c.resources[entry.resourceKey]
+	if ok && !slices.Equal(resource.vary, vary) {
+		// The variants selected by the other headers are outdated.
+		for _, elem := range resource.variants {
+			c.remove(elem)
+		}
+		ok = false
+	}
+	if !ok {
+		resource = &cachedResource{vary: vary, variants: make(map[string]*list.Element)}
+	}
+	if elem, ok := resource.variants[entry.variantKey]; ok {
+		c.remove(elem)
+	}
+
+	resource.variants[entry.variantKey] = c.lru.PushFront(entry)
+	c.resources[entry.resourceKey] = resource
+	c.size += entry.size
+
+	for c.size > c.cfg.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove removes the element from the cache. c.mu must be held.
+func (c *ResponseCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cachedResponse)
+	c.size -= entry.size
+
+	resource := c.resources[entry.resourceKey]
+	if resource == nil || resource.variants[entry.variantKey] != elem {
+		return
+	}
+
+	delete(resource.variants, entry.variantKey)
+	if len(resource.variants) == 0 {
+		delete(c.resources, entry.resourceKey)
+	}
+}
+
+func cacheResourceKey(r *http.Request) string {
+	return r.Method + " " + r.Host + r.URL.RequestURI()
+}
+
+// cacheVariantKey returns the values of the request headers listed in the Vary header of the response.
+func cacheVariantKey(header http.Header, vary []string) string {
+	var b strings.Builder
+	for _, name := range vary {
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(header.Values(name), ","))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func cacheVaryFields(header http.Header) []string {
+	var fields []string
+	for _, value := range header.Values("Vary") {
+		for _, field := range splitHeaderList(value) {
+			fields = append(fields, http.CanonicalHeaderKey(field))
+		}
+	}
+	return fields
+}
+
+// headerListContains reports whether the header values have the element, ignoring case.
+func headerListContains(values []string, target string) bool {
+	for _, value := range values {
+		for _, element := range splitHeaderList(value) {
+			if strings.EqualFold(element, target) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseCacheControl parses the Cache-Control header values into the directives in lower case and their unquoted values.
+func parseCacheControl(values []string) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range values {
+		for _, element := range splitHeaderList(value) {
+			name, arg, _ := strings.Cut(element, "=")
+			directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return directives
+}
+
+func parseDeltaSeconds(value string) (time.Duration, bool) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(min(seconds, int64(1<<31))) * time.Second, true
+}
+
+// cachedResponseRenderer renders a cached response with the Age and Cache-Status headers.
+type cachedResponseRenderer struct {
+	entry       *cachedResponse
+	age         time.Duration
+	cacheStatus string
+}
+
+func (r *cachedResponseRenderer) RenderHeader(_ context.Context, header http.Header) error {
+	maps.Copy(header, r.entry.header.Clone())
+	header.Set("Age", strconv.FormatInt(int64(r.age/time.Second), 10))
+	header.Set("Cache-Status", r.cacheStatus)
+	if header.Get("Content-Length") == "" && !r.entry.bodyOmitted && r.entry.statusCode != http.StatusNoContent {
+		header.Set("Content-Length", strconv.Itoa(len(r.entry.body)))
+	}
+	return nil
+}
+
+func (r *cachedResponseRenderer) RenderHead(_ context.Context) error {
+	return nil
+}
+
+func (r *cachedResponseRenderer) RenderBody(_ context.Context, w io.Writer) error {
+	_, err := w.Write(r.entry.body)
+	return err
+}
+
+func (r *cachedResponseRenderer) bufferedBody() ([]byte, bool) {
+	return r.entry.body, true
+}
+
+// responseCacheWriter writes the response to the client, and captures it to be stored by ResponseCache.
+type responseCacheWriter struct {
+	http.ResponseWriter
+	cache *ResponseCache
+	r     *http.Request
+	fwd   string
+
+	wroteHeader          bool
+	statusCode           int
+	header               http.Header
+	storable             bool
+	ttl                  time.Duration
+	staleWhileRevalidate time.Duration
+	body                 bytes.Buffer
+	overflow             bool
+}
+
+func (w *responseCacheWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader || statusCode < 200 {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	w.wroteHeader = true
+	w.statusCode = statusCode
+
+	header := w.Header()
+	w.ttl, w.staleWhileRevalidate, w.storable = w.cache.cacheability(w.r, statusCode, header)
+
+	cacheStatus := w.cache.cfg.name + "; fwd=" + w.fwd
+	if w.storable {
+		w.header = header.Clone()
+		w.header.Del("Cache-Status")
+		cacheStatus += "; stored"
+	}
+	header.Set("Cache-Status", cacheStatus)
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseCacheWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.storable && !w.overflow {
+		if int64(w.body.Len()+len(b)) > w.cache.cfg.maxBytes {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseCacheWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *responseCacheWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// discardResponseWriter is the http.ResponseWriter of background refreshes, which have no client.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(int) {}
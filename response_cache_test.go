@@ -0,0 +1,376 @@
+package httplib_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheTestHandler renders "<body>-<number of calls>" with the headers.
+type cacheTestHandler struct {
+	body       string
+	statusCode int
+	header     http.Header
+	calls      atomic.Int32
+}
+
+func (h *cacheTestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := h.calls.Add(1)
+	for name, values := range h.header {
+		w.Header()[name] = values
+	}
+
+	statusCode := h.statusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	body := h.body + "-" + strconv.Itoa(int(n)) + r.Header.Get("Accept-Language")
+	if statusCode == http.StatusOK {
+		_ = httplib.RenderOKWithBody(r.Context(), w, httplib.RawResponseWithContentType([]byte(body), httplib.ContentTypeTextPlain))
+	} else {
+		httplib.RenderInternalServerError(r.Context(), w, nil)
+	}
+}
+
+type cacheTestClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *cacheTestClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *cacheTestClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestResponseCache(opts ...httplib.ResponseCacheOption) (*httplib.ResponseCache, *cacheTestClock) {
+	clock := &cacheTestClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := httplib.NewResponseCache(opts...)
+	httplib.SetResponseCacheClock(cache, clock.Now)
+	return cache, clock
+}
+
+func serveCached(t *testing.T, handler http.Handler, method, target string, header http.Header) (*httptest.ResponseRecorder, *httplib.ResponseLog) {
+	t.Helper()
+
+	ctx, res := newHeadContext(t.Context(), method)
+	r := httptest.NewRequestWithContext(ctx, method, target, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w, res
+}
+
+func TestResponseCache_Middleware(t *testing.T) {
+	cache, clock := newTestResponseCache()
+	h := &cacheTestHandler{body: "v", header: http.Header{"Cache-Control": {"public, max-age=60"}}}
+	handler := cache.Middleware(h)
+
+	w, res := serveCached(t, handler, http.MethodGet, "/items?page=1", nil)
+	assert.Equal(t, "v-1", w.Body.String())
+	assert.Equal(t, "httplib; fwd=uri-miss; stored", w.Header().Get("Cache-Status"))
+	assert.Equal(t, httplib.CacheStatusMiss, res.CacheStatus)
+	handlerInfo := res.HandlerInfo
+
+	clock.Advance(10 * time.Second)
+
+	w, res = serveCached(t, handler, http.MethodGet, "/items?page=1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "v-1", w.Body.String())
+	assert.Equal(t, "httplib; hit; ttl=50", w.Header().Get("Cache-Status"))
+	assert.Equal(t, "10", w.Header().Get("Age"))
+	assert.Equal(t, "3", w.Header().Get("Content-Length"))
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	assert.Equal(t, httplib.CacheStatusHit, res.CacheStatus)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int64(3), res.ResponseSize)
+	assert.Equal(t, handlerInfo, res.HandlerInfo)
+	assert.Equal(t, int32(1), h.calls.Load())
+
+	w, _ = serveCached(t, handler, http.MethodGet, "/items?page=2", nil)
+	assert.Equal(t, "v-2", w.Body.String())
+
+	clock.Advance(50 * time.Second)
+
+	w, res = serveCached(t, handler, http.MethodGet, "/items?page=1", nil)
+	assert.Equal(t, "v-3", w.Body.String())
+	assert.Equal(t, "httplib; fwd=stale; stored", w.Header().Get("Cache-Status"))
+	assert.Equal(t, httplib.CacheStatusMiss, res.CacheStatus)
+}
+
+func TestResponseCache_Middleware_NotStored(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		statusCode      int
+		responseHeader  http.Header
+		requestHeader   http.Header
+		wantCacheStatus string
+		wantLogStatus   string
+	}{
+		{
+			name:            "no Cache-Control",
+			method:          http.MethodGet,
+			wantCacheStatus: "httplib; fwd=uri-miss",
+			wantLogStatus:   httplib.CacheStatusMiss,
+		},
+		{
+			name:            "no-store",
+			method:          http.MethodGet,
+			responseHeader:  http.Header{"Cache-Control": {"no-store"}},
+			wantCacheStatus: "httplib; fwd=uri-miss",
+			wantLogStatus:   httplib.CacheStatusMiss,
+		},
+		{
+			name:            "private",
+			method:          http.MethodGet,
+			responseHeader:  http.Header{"Cache-Control": {"private, max-age=60"}},
+			wantCacheStatus: "httplib; fwd=uri-miss",
+			wantLogStatus:   httplib.CacheStatusMiss,
+		},
+		{
+			name:            "no-cache",
+			method:          http.MethodGet,
+			responseHeader:  http.Header{"Cache-Control": {"no-cache, max-age=60"}},
+			wantCacheStatus: "httplib; fwd=uri-miss",
+			wantLogStatus:   httplib.CacheStatusMiss,
+		},
+		{
+			name:            "Set-Cookie",
+			method:          http.MethodGet,
+			responseHeader:  http.Header{"Cache-Control": {"max-age=60"}, "Set-Cookie": {"id=1"}},
+			wantCacheStatus: "httplib; fwd=uri-miss",
+			wantLogStatus:   httplib.CacheStatusMiss,
+		},
+		{
+			name:            "Vary: *",
+			method:          http.MethodGet,
+			responseHeader:  http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"*"}},
+			wantCacheStatus: "httplib; fwd=uri-miss",
+			wantLogStatus:   httplib.CacheStatusMiss,
+		},
+		{
+			name:            "internal server error",
+			method:          http.MethodGet,
+			statusCode:      http.StatusInternalServerError,
+			responseHeader:  http.Header{"Cache-Control": {"max-age=60"}},
+			wantCacheStatus: "httplib; fwd=uri-miss",
+			wantLogStatus:   httplib.CacheStatusMiss,
+		},
+		{
+			name:            "Authorization without public",
+			method:          http.MethodGet,
+			responseHeader:  http.Header{"Cache-Control": {"max-age=60"}},
+			requestHeader:   http.Header{"Authorization": {"Bearer token"}},
+			wantCacheStatus: "httplib; fwd=request",
+			wantLogStatus:   httplib.CacheStatusMiss,
+		},
+		{
+			name:            "POST",
+			method:          http.MethodPost,
+			responseHeader:  http.Header{"Cache-Control": {"max-age=60"}},
+			wantCacheStatus: "httplib; fwd=method",
+			wantLogStatus:   httplib.CacheStatusBypass,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, _ := newTestResponseCache()
+			h := &cacheTestHandler{body: "v", statusCode: tt.statusCode, header: tt.responseHeader}
+			handler := cache.Middleware(h)
+
+			for range 2 {
+				w, res := serveCached(t, handler, tt.method, "/", tt.requestHeader)
+				assert.Equal(t, tt.wantCacheStatus, w.Header().Get("Cache-Status"))
+				assert.Equal(t, tt.wantLogStatus, res.CacheStatus)
+			}
+			assert.Equal(t, int32(2), h.calls.Load())
+		})
+	}
+}
+
+func TestResponseCache_Middleware_RequestNoCache(t *testing.T) {
+	cache, _ := newTestResponseCache()
+	h := &cacheTestHandler{body: "v", header: http.Header{"Cache-Control": {"max-age=60"}}}
+	handler := cache.Middleware(h)
+
+	serveCached(t, handler, http.MethodGet, "/", nil)
+
+	w, _ := serveCached(t, handler, http.MethodGet, "/", http.Header{"Cache-Control": {"no-cache"}})
+	assert.Equal(t, "v-2", w.Body.String())
+	assert.Equal(t, "httplib; fwd=request; stored", w.Header().Get("Cache-Status"))
+
+	w, _ = serveCached(t, handler, http.MethodGet, "/", nil)
+	assert.Equal(t, "v-2", w.Body.String())
+	assert.Equal(t, "httplib; hit; ttl=60", w.Header().Get("Cache-Status"))
+}
+
+func TestResponseCache_Middleware_Vary(t *testing.T) {
+	cache, _ := newTestResponseCache(httplib.WithResponseCacheName("edge"))
+	h := &cacheTestHandler{body: "v", header: http.Header{"Cache-Control": {"s-maxage=60"}, "Vary": {"accept-language"}}}
+	handler := cache.Middleware(h)
+
+	w, _ := serveCached(t, handler, http.MethodGet, "/", http.Header{"Accept-Language": {"en"}})
+	assert.Equal(t, "v-1en", w.Body.String())
+
+	w, _ = serveCached(t, handler, http.MethodGet, "/", http.Header{"Accept-Language": {"ja"}})
+	assert.Equal(t, "v-2ja", w.Body.String())
+	assert.Equal(t, "edge; fwd=vary-miss; stored", w.Header().Get("Cache-Status"))
+
+	w, _ = serveCached(t, handler, http.MethodGet, "/", http.Header{"Accept-Language": {"en"}})
+	assert.Equal(t, "v-1en", w.Body.String())
+	assert.Equal(t, "edge; hit; ttl=60", w.Header().Get("Cache-Status"))
+	assert.Equal(t, "accept-language", w.Header().Get("Vary"))
+}
+
+func TestResponseCache_Middleware_StaleWhileRevalidate(t *testing.T) {
+	cache, clock := newTestResponseCache()
+	h := &cacheTestHandler{body: "v", header: http.Header{"Cache-Control": {"max-age=60, stale-while-revalidate=30"}}}
+	handler := cache.Middleware(h)
+
+	serveCached(t, handler, http.MethodGet, "/", nil)
+	clock.Advance(70 * time.Second)
+
+	for range 3 {
+		w, res := serveCached(t, handler, http.MethodGet, "/", nil)
+		assert.Equal(t, "v-1", w.Body.String())
+		assert.Equal(t, "httplib; hit; ttl=-10; fwd=stale", w.Header().Get("Cache-Status"))
+		assert.Equal(t, httplib.CacheStatusStale, res.CacheStatus)
+	}
+
+	require.Eventually(t, func() bool {
+		w, _ := serveCached(t, handler, http.MethodGet, "/", nil)
+		return w.Body.String() == "v-2"
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), h.calls.Load())
+
+	w, res := serveCached(t, handler, http.MethodGet, "/", nil)
+	assert.Equal(t, "httplib; hit; ttl=60", w.Header().Get("Cache-Status"))
+	assert.Equal(t, httplib.CacheStatusHit, res.CacheStatus)
+
+	clock.Advance(91 * time.Second)
+
+	w, _ = serveCached(t, handler, http.MethodGet, "/", nil)
+	assert.Equal(t, "v-3", w.Body.String())
+	assert.Equal(t, "httplib; fwd=stale; stored", w.Header().Get("Cache-Status"))
+}
+
+func TestResponseCache_Middleware_RefreshPanic(t *testing.T) {
+	errs := make(chan error, 10)
+	cache, clock := newTestResponseCache(httplib.WithResponseCacheRefreshErrorHandler(func(ctx context.Context, err error) {
+		assert.ErrorIs(t, httplib.GetResponseLogPtrFromContext(ctx).Error, err)
+		select {
+		case errs <- err:
+		default:
+		}
+	}))
+
+	var calls atomic.Int32
+	handler := cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) > 1 {
+			panic("refresh failed")
+		}
+		w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=30")
+		_ = httplib.RenderOKWithBody(r.Context(), w, httplib.RawResponseWithContentType([]byte("v"), httplib.ContentTypeTextPlain))
+	}))
+
+	serveCached(t, handler, http.MethodGet, "/", nil)
+	clock.Advance(70 * time.Second)
+
+	w, _ := serveCached(t, handler, http.MethodGet, "/", nil)
+	assert.Equal(t, "v", w.Body.String())
+
+	select {
+	case err := <-errs:
+		require.ErrorIs(t, err, httplib.ErrResponseCacheRefreshPanicked)
+		assert.ErrorContains(t, err, "refresh failed")
+	case <-time.After(time.Second):
+		require.FailNow(t, "the refresh error is not reported")
+	}
+
+	// The stale response is kept, and is refreshed again by the next request.
+	require.Eventually(t, func() bool {
+		w, res := serveCached(t, handler, http.MethodGet, "/", nil)
+		return w.Body.String() == "v" && res.CacheStatus == httplib.CacheStatusStale && calls.Load() > 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestResponseCache_Middleware_MaxTTL(t *testing.T) {
+	cache, clock := newTestResponseCache(httplib.WithResponseCacheMaxTTL(10 * time.Second))
+	h := &cacheTestHandler{body: "v", header: http.Header{"Cache-Control": {"max-age=60"}}}
+	handler := cache.Middleware(h)
+
+	serveCached(t, handler, http.MethodGet, "/", nil)
+	clock.Advance(10 * time.Second)
+
+	w, _ := serveCached(t, handler, http.MethodGet, "/", nil)
+	assert.Equal(t, "v-2", w.Body.String())
+}
+
+func TestResponseCache_Middleware_Eviction(t *testing.T) {
+	// Each entry is about 70 bytes: the keys, the body and the headers.
+	cache, _ := newTestResponseCache(httplib.WithResponseCacheMaxBytes(200))
+	h := &cacheTestHandler{body: "v", header: http.Header{"Cache-Control": {"max-age=60"}}}
+	handler := cache.Middleware(h)
+
+	serveCached(t, handler, http.MethodGet, "/a", nil)
+	serveCached(t, handler, http.MethodGet, "/b", nil)
+	serveCached(t, handler, http.MethodGet, "/a", nil) // /a becomes the most recently used
+	serveCached(t, handler, http.MethodGet, "/c", nil) // /b is evicted
+	require.Equal(t, int32(3), h.calls.Load())
+
+	w, _ := serveCached(t, handler, http.MethodGet, "/a", nil)
+	assert.Equal(t, "httplib; hit; ttl=60", w.Header().Get("Cache-Status"))
+
+	w, _ = serveCached(t, handler, http.MethodGet, "/b", nil)
+	assert.Equal(t, "httplib; fwd=uri-miss; stored", w.Header().Get("Cache-Status"))
+}
+
+func TestResponseCache_Middleware_TooLarge(t *testing.T) {
+	cache, _ := newTestResponseCache(httplib.WithResponseCacheMaxBytes(50))
+	h := &cacheTestHandler{body: "a long body exceeding the size limit of the cache", header: http.Header{"Cache-Control": {"max-age=60"}}}
+	handler := cache.Middleware(h)
+
+	serveCached(t, handler, http.MethodGet, "/", nil)
+	w, _ := serveCached(t, handler, http.MethodGet, "/", nil)
+	assert.Equal(t, h.body+"-2", w.Body.String())
+	assert.Equal(t, int32(2), h.calls.Load())
+}
+
+func TestResponseCache_Middleware_Head(t *testing.T) {
+	cache, _ := newTestResponseCache()
+	h := &cacheTestHandler{body: "v", header: http.Header{"Cache-Control": {"max-age=60"}}}
+	handler := cache.Middleware(h)
+
+	serveCached(t, handler, http.MethodHead, "/", nil)
+
+	w, res := serveCached(t, handler, http.MethodHead, "/", nil)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, "3", w.Header().Get("Content-Length"))
+	assert.Equal(t, "httplib; hit; ttl=60", w.Header().Get("Cache-Status"))
+	assert.True(t, res.BodyOmitted)
+	assert.Equal(t, int32(1), h.calls.Load())
+
+	w, _ = serveCached(t, handler, http.MethodGet, "/", nil)
+	assert.Equal(t, "v-2", w.Body.String())
+}
@@ -2,7 +2,11 @@ package httplib
 
 import (
 	"log/slog"
+	"maps"
+	"net/http"
 	"runtime"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -17,6 +21,32 @@ type ResponseLog struct {
 	// A value of -1 indicates that the size is unknown or not applicable.
 	ResponseSize int64
 
+	// BodyOmitted reports whether the body was not written because the request was a HEAD request.
+	// ResponseSize is 0 in that case, even though the headers such as Content-Length describe the full body.
+	BodyOmitted bool
+
+	// Trailers are the trailers sent after the body by TrailerResponseBodyRenderer. It is nil if no trailer is sent.
+	Trailers http.Header
+
+	// CacheControl is the Cache-Control header of the response, such as the one applied by CachePolicy,
+	// for auditing the caching of responses. It is empty if the header is not set.
+	CacheControl string
+
+	// CacheStatus is the result of ResponseCache, such as CacheStatusHit and CacheStatusMiss.
+	// It is empty if the response is not handled by ResponseCache.
+	CacheStatus string
+
+	// IdempotencyReplayed reports whether the response is a replay of the stored response by IdempotencyMiddleware.
+	IdempotencyReplayed bool
+
+	// Coalesced reports whether the response is shared from the handler execution of a concurrent request
+	// by CoalesceMiddleware.
+	Coalesced bool
+
+	// SessionIDHash is the hash of the ID of the session saved by SessionManager.Middleware, including a session
+	// started or regenerated by the request. It is empty if no session is saved.
+	SessionIDHash string
+
 	// Error is any error that occurred during request processing.
 	Error error
 
@@ -30,6 +60,13 @@ type ResponseLog struct {
 //   - latency: request processing time in milliseconds
 //   - status_code: HTTP status code
 //   - response_size: response body size in bytes
+//   - body_omitted: true (included only if BodyOmitted is true)
+//   - trailers: trailer names and their values joined by ", " (included only if Trailers is not empty)
+//   - cache_control: Cache-Control header (included only if CacheControl is not empty)
+//   - cache_status: result of ResponseCache (included only if CacheStatus is not empty)
+//   - idempotency_replayed: true (included only if IdempotencyReplayed is true)
+//   - coalesced: true (included only if Coalesced is true)
+//   - session_id_hash: hash of the saved session ID (included only if SessionIDHash is not empty)
 //   - error: error message (included only if Error is not nil)
 //   - handler: handler information (included only if HandlerInfo.FuncName is not empty)
 //
@@ -39,7 +76,7 @@ func (r *ResponseLog) ToAttr(latency time.Duration) slog.Attr {
 		return slog.Attr{}
 	}
 
-	attrs := make([]slog.Attr, 0, 4)
+	attrs := make([]slog.Attr, 0, 12)
 
 	attrs = append(
 		attrs,
@@ -48,6 +85,38 @@ func (r *ResponseLog) ToAttr(latency time.Duration) slog.Attr {
 		slog.Int64("response_size", r.ResponseSize),
 	)
 
+	if r.BodyOmitted {
+		attrs = append(attrs, slog.Bool("body_omitted", true))
+	}
+
+	if len(r.Trailers) != 0 {
+		trailerAttrs := make([]slog.Attr, 0, len(r.Trailers))
+		for _, name := range slices.Sorted(maps.Keys(r.Trailers)) {
+			trailerAttrs = append(trailerAttrs, slog.String(name, strings.Join(r.Trailers[name], ", ")))
+		}
+		attrs = append(attrs, slog.GroupAttrs("trailers", trailerAttrs...))
+	}
+
+	if r.CacheControl != "" {
+		attrs = append(attrs, slog.String("cache_control", r.CacheControl))
+	}
+
+	if r.CacheStatus != "" {
+		attrs = append(attrs, slog.String("cache_status", r.CacheStatus))
+	}
+
+	if r.IdempotencyReplayed {
+		attrs = append(attrs, slog.Bool("idempotency_replayed", true))
+	}
+
+	if r.Coalesced {
+		attrs = append(attrs, slog.Bool("coalesced", true))
+	}
+
+	if r.SessionIDHash != "" {
+		attrs = append(attrs, slog.String("session_id_hash", r.SessionIDHash))
+	}
+
 	if r.Error != nil {
 		attrs = append(attrs, slog.String("error", r.Error.Error()))
 	}
@@ -45,6 +45,37 @@ func TestResponseLog_ToAttr(t *testing.T) {
 				),
 			),
 		},
+		{
+			name: "body omitted",
+			Response: &httplib.ResponseLog{
+				StatusCode:  http.StatusOK,
+				BodyOmitted: true,
+			},
+			latency: 1 * time.Millisecond,
+			want: slog.GroupAttrs("http_response",
+				slog.Int64("latency", 1),
+				slog.Int("status_code", http.StatusOK),
+				slog.Int64("response_size", 0),
+				slog.Bool("body_omitted", true),
+			),
+		},
+		{
+			name: "trailers",
+			Response: &httplib.ResponseLog{
+				StatusCode: http.StatusOK,
+				Trailers:   http.Header{"X-Status": {"done"}, "X-Checksum": {"a", "b"}},
+			},
+			latency: 1 * time.Millisecond,
+			want: slog.GroupAttrs("http_response",
+				slog.Int64("latency", 1),
+				slog.Int("status_code", http.StatusOK),
+				slog.Int64("response_size", 0),
+				slog.GroupAttrs("trailers",
+					slog.String("X-Checksum", "a, b"),
+					slog.String("X-Status", "done"),
+				),
+			),
+		},
 		{
 			name: "status code is StatusInternalServerError",
 			Response: &httplib.ResponseLog{
@@ -147,6 +178,29 @@ func TestResponseLog_ToAttr(t *testing.T) {
 				slog.String("error", "internal server error"),
 			),
 		},
+		{
+			name: "CacheControl, CacheStatus, IdempotencyReplayed, Coalesced and SessionIDHash",
+			Response: &httplib.ResponseLog{
+				StatusCode:          http.StatusOK,
+				ResponseSize:        100,
+				CacheControl:        "public, max-age=60",
+				CacheStatus:         httplib.CacheStatusHit,
+				IdempotencyReplayed: true,
+				Coalesced:           true,
+				SessionIDHash:       "0123456789abcdef",
+			},
+			latency: 123 * time.Millisecond,
+			want: slog.GroupAttrs("http_response",
+				slog.Int64("latency", 123),
+				slog.Int("status_code", http.StatusOK),
+				slog.Int64("response_size", 100),
+				slog.String("cache_control", "public, max-age=60"),
+				slog.String("cache_status", "hit"),
+				slog.Bool("idempotency_replayed", true),
+				slog.Bool("coalesced", true),
+				slog.String("session_id_hash", "0123456789abcdef"),
+			),
+		},
 		{
 			name:    "nil",
 			latency: 123,
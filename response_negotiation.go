@@ -0,0 +1,164 @@
+package httplib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// NotAcceptableError is returned by NegotiateMediaType and NegotiatedResponse
+// when none of the available media types is acceptable for the client.
+type NotAcceptableError struct {
+	// Available is the list of the media types the server can produce.
+	Available []ContentType
+}
+
+func (e *NotAcceptableError) Error() string {
+	return fmt.Sprintf("httplib: not acceptable: available media types are %s", strings.Join(e.Available, ", "))
+}
+
+// Representation is a representation of a resource that NegotiatedResponse can choose.
+type Representation struct {
+	// MediaType is the media type of the representation, such as ContentTypeJSONUTF8.
+	// It is used for the Content-Type header of the response.
+	MediaType ContentType
+
+	// Renderer returns the ResponseBodyRenderer of the representation.
+	// It is called only if the representation is chosen.
+	Renderer func() (ResponseBodyRenderer, error)
+}
+
+// NegotiateMediaType chooses the media type to respond with from available according to the Accept header of r.
+//
+// Each available media type gets the quality of the most specific media range that matches it,
+// and the one with the highest quality is chosen. Ties are broken by the order of available,
+// so the server's preferred media type should come first. If the request has no valid Accept header,
+// the first available media type is chosen.
+//
+// If no media type is acceptable, it returns NotAcceptableError.
+// If an available media type cannot be parsed, it returns an error wrapping ErrInvalidMediaType.
+func NegotiateMediaType(r *http.Request, available ...ContentType) (ContentType, error) {
+	index, err := negotiate(r, available)
+	if err != nil {
+		return "", err
+	}
+	return available[index], nil
+}
+
+func negotiate(r *http.Request, available []ContentType) (int, error) {
+	ranges := ParseAccept(r.Header.Values("Accept")...)
+
+	best, bestQuality := -1, 0.0
+	for i, contentType := range available {
+		mediaType, err := ParseMediaType(contentType)
+		if err != nil {
+			return -1, err
+		}
+
+		if len(ranges) == 0 {
+			return i, nil
+		}
+
+		quality, specificity := 0.0, -1
+		for _, mediaRange := range ranges {
+			if mediaRange.Matches(mediaType) && mediaRange.Specificity() > specificity {
+				quality, specificity = mediaRange.Quality, mediaRange.Specificity()
+			}
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = i, quality
+		}
+	}
+
+	if best < 0 {
+		return -1, &NotAcceptableError{Available: available}
+	}
+	return best, nil
+}
+
+// NegotiatedResponse returns a ResponseBodyRenderer of the representation chosen by NegotiateMediaType.
+//
+// The returned renderer sets the Content-Type header to the MediaType of the representation,
+// and adds "Accept" to the Vary header.
+//
+// If no representation is acceptable, it returns NotAcceptableError, which can be rendered by RenderNotAcceptable.
+// If the Renderer of the chosen representation returns an error, it returns the error.
+func NegotiatedResponse(r *http.Request, representations ...Representation) (ResponseBodyRenderer, error) {
+	available := make([]ContentType, len(representations))
+	for i, representation := range representations {
+		available[i] = representation.MediaType
+	}
+
+	index, err := negotiate(r, available)
+	if err != nil {
+		return nil, err
+	}
+
+	renderer, err := representations[index].Renderer()
+	if err != nil {
+		return nil, err
+	}
+
+	return &negotiatedResponseBodyRenderer{renderer: renderer, contentType: available[index]}, nil
+}
+
+type negotiatedResponseBodyRenderer struct {
+	renderer    ResponseBodyRenderer
+	contentType ContentType
+}
+
+func (r *negotiatedResponseBodyRenderer) RenderHeader(ctx context.Context, header http.Header) error {
+	err := r.renderer.RenderHeader(ctx, header)
+	header.Set("Content-Type", r.contentType)
+	addVary(header, "Accept")
+	return err
+}
+
+func (r *negotiatedResponseBodyRenderer) bufferedBody() ([]byte, bool) {
+	if buffered, ok := r.renderer.(bufferedBodyRenderer); ok {
+		return buffered.bufferedBody()
+	}
+	return nil, false
+}
+
+func (r *negotiatedResponseBodyRenderer) RenderHead(ctx context.Context) error {
+	return renderHead(ctx, r.renderer)
+}
+
+func (r *negotiatedResponseBodyRenderer) RenderBody(ctx context.Context, w io.Writer) error {
+	return r.renderer.RenderBody(ctx, w)
+}
+
+// addVary adds the field name to the Vary header unless it is already listed.
+func addVary(header http.Header, field string) {
+	for _, value := range header.Values("Vary") {
+		for _, listed := range splitHeaderList(value) {
+			if listed == "*" || strings.EqualFold(listed, field) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
+}
+
+// RenderNotAcceptable renders a response with status code http.StatusNotAcceptable.
+//
+// If cause is NotAcceptableError, the response has a text/plain body listing the available media types, one per line,
+// and "Accept" is added to the Vary header. Otherwise, the response has no body.
+//
+// The cause error will be used for ResponseLog.Error. The returned error is the error of writing the body.
+func RenderNotAcceptable(ctx context.Context, w http.ResponseWriter, cause error) error {
+	var notAcceptableErr *NotAcceptableError
+	if !errors.As(cause, &notAcceptableErr) {
+		renderStatusCode(ctx, w, http.StatusNotAcceptable, cause)
+		return nil
+	}
+
+	addVary(w.Header(), "Accept")
+	body := RawResponseWithContentType([]byte(strings.Join(notAcceptableErr.Available, "\n")+"\n"), ContentTypeTextPlainUTF8)
+	return renderWithBody(ctx, w, http.StatusNotAcceptable, body, cause)
+}
@@ -0,0 +1,198 @@
+package httplib_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateMediaType(t *testing.T) {
+	available := []httplib.ContentType{httplib.ContentTypeJSONUTF8, "application/xml", "text/csv"}
+
+	tests := []struct {
+		name         string
+		accept       []string
+		available    []httplib.ContentType
+		want         httplib.ContentType
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "no Accept header",
+			available:    available,
+			want:         httplib.ContentTypeJSONUTF8,
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "any",
+			accept:       []string{"*/*"},
+			available:    available,
+			want:         httplib.ContentTypeJSONUTF8,
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "exact",
+			accept:       []string{"application/xml"},
+			available:    available,
+			want:         "application/xml",
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "highest quality",
+			accept:       []string{"application/json;q=0.5, text/csv;q=0.8, */*;q=0.1"},
+			available:    available,
+			want:         "text/csv",
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "most specific range wins",
+			accept:       []string{"application/*;q=0.9, application/json;q=0.1"},
+			available:    available,
+			want:         "application/xml",
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "explicitly rejected",
+			accept:       []string{"*/*, application/json;q=0"},
+			available:    available,
+			want:         "application/xml",
+			errAssertion: assert.NoError,
+		},
+		{
+			name:         "invalid Accept header",
+			accept:       []string{"invalid"},
+			available:    available,
+			want:         httplib.ContentTypeJSONUTF8,
+			errAssertion: assert.NoError,
+		},
+		{
+			name:      "not acceptable",
+			accept:    []string{"text/html"},
+			available: available,
+			want:      "",
+			errAssertion: func(t assert.TestingT, err error, i ...interface{}) bool {
+				var notAcceptableErr *httplib.NotAcceptableError
+				return assert.ErrorAs(t, err, &notAcceptableErr) && assert.Equal(t, available, notAcceptableErr.Available)
+			},
+		},
+		{
+			name:         "invalid available media type",
+			accept:       []string{"*/*"},
+			available:    []httplib.ContentType{"json"},
+			want:         "",
+			errAssertion: assertErrorIsInvalidMediaType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, accept := range tt.accept {
+				r.Header.Add("Accept", accept)
+			}
+
+			got, err := httplib.NegotiateMediaType(r, tt.available...)
+			tt.errAssertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNegotiatedResponse(t *testing.T) {
+	called := map[string]bool{}
+	representations := []httplib.Representation{
+		{
+			MediaType: httplib.ContentTypeJSONUTF8,
+			Renderer: func() (httplib.ResponseBodyRenderer, error) {
+				called["json"] = true
+				return httplib.JSONResponse(map[string]string{"name": "a"})
+			},
+		},
+		{
+			MediaType: "text/csv; charset=utf-8",
+			Renderer: func() (httplib.ResponseBodyRenderer, error) {
+				called["csv"] = true
+				return httplib.RawResponse([]byte("name\na\n")), nil
+			},
+		},
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/csv")
+
+	renderer, err := httplib.NegotiatedResponse(r, representations...)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	w.Header().Set("Vary", "accept-encoding, accept")
+	require.NoError(t, httplib.RenderOKWithBody(t.Context(), w, renderer))
+
+	assert.Equal(t, map[string]bool{"csv": true}, called)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, []string{"accept-encoding, accept"}, w.Header().Values("Vary"))
+	assert.Equal(t, "name\na\n", w.Body.String())
+}
+
+func TestNegotiatedResponse_Error(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/json")
+
+	rendererErr := errors.New("renderer error")
+	renderer, err := httplib.NegotiatedResponse(r, httplib.Representation{
+		MediaType: httplib.ContentTypeJSON,
+		Renderer: func() (httplib.ResponseBodyRenderer, error) {
+			return nil, rendererErr
+		},
+	})
+	assert.ErrorIs(t, err, rendererErr)
+	assert.Nil(t, renderer)
+
+	r.Header.Set("Accept", "application/xml")
+	renderer, err = httplib.NegotiatedResponse(r, httplib.Representation{MediaType: httplib.ContentTypeJSON})
+	var notAcceptableErr *httplib.NotAcceptableError
+	assert.ErrorAs(t, err, &notAcceptableErr)
+	assert.Nil(t, renderer)
+}
+
+func TestRenderNotAcceptable(t *testing.T) {
+	tests := []struct {
+		name      string
+		cause     error
+		wantBody  string
+		wantVary  string
+		wantCType string
+	}{
+		{
+			name:      "NotAcceptableError",
+			cause:     &httplib.NotAcceptableError{Available: []httplib.ContentType{httplib.ContentTypeJSON, "application/xml"}},
+			wantBody:  "application/json\napplication/xml\n",
+			wantVary:  "Accept",
+			wantCType: httplib.ContentTypeTextPlainUTF8,
+		},
+		{
+			name:  "other error",
+			cause: errors.New("not acceptable"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res httplib.ResponseLog
+			ctx := httplib.WithResponseLogPtr(t.Context(), &res)
+
+			w := httptest.NewRecorder()
+			require.NoError(t, httplib.RenderNotAcceptable(ctx, w, tt.cause))
+
+			assert.Equal(t, http.StatusNotAcceptable, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.wantVary, w.Header().Get("Vary"))
+			assert.Equal(t, tt.wantCType, w.Header().Get("Content-Type"))
+
+			assert.Equal(t, http.StatusNotAcceptable, res.StatusCode)
+			assert.Equal(t, tt.cause, res.Error)
+			assert.Equal(t, "github.com/Siroshun09/go-httplib_test.TestRenderNotAcceptable.func1", res.HandlerInfo.FuncName)
+		})
+	}
+}
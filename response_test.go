@@ -3,8 +3,10 @@ package httplib_test
 import (
 	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 
@@ -229,6 +231,12 @@ func Test_RenderError(t *testing.T) {
 			f:              httplib.RenderConflict,
 			wantStatusCode: http.StatusConflict,
 		},
+		{
+			name:           "UnprocessableEntity",
+			cause:          errors.New("unprocessable entity"),
+			f:              httplib.RenderUnprocessableEntity,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
 		{
 			name:           "InternalServerError",
 			cause:          errors.New("internal server error"),
@@ -252,6 +260,19 @@ func Test_RenderError(t *testing.T) {
 	}
 }
 
+func Test_RenderMethodNotAllowed(t *testing.T) {
+	ctx := httplib.WithResponseLogPtr(t.Context(), &httplib.ResponseLog{})
+	w := httptest.NewRecorder()
+	cause := errors.New("method not allowed")
+
+	httplib.RenderMethodNotAllowed(ctx, w, cause, http.MethodGet, http.MethodHead)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+	assert.Empty(t, w.Body.Bytes())
+	assertResponseLog(ctx, t, http.StatusMethodNotAllowed, 0, cause)
+}
+
 func Test_RenderErrorWithBody(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -265,6 +286,12 @@ func Test_RenderErrorWithBody(t *testing.T) {
 			f:              httplib.RenderBadRequestWithBody,
 			wantStatusCode: http.StatusBadRequest,
 		},
+		{
+			name:           "UnprocessableEntity",
+			cause:          errors.New("unprocessable entity"),
+			f:              httplib.RenderUnprocessableEntityWithBody,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -477,3 +504,381 @@ func Test_Render_WithoutResponseLogHolder(t *testing.T) {
 		})
 	}
 }
+
+func newHeadContext(ctx context.Context, method string) (context.Context, *httplib.ResponseLog) {
+	var res httplib.ResponseLog
+	ctx = httplib.WithRequestLog(ctx, httplib.RequestLog{Method: method})
+	return httplib.WithResponseLogPtr(ctx, &res), &res
+}
+
+type bodyOnlyRenderer struct {
+	called bool
+}
+
+func (r *bodyOnlyRenderer) RenderHeader(_ context.Context, header http.Header) error {
+	header.Set("Content-Type", httplib.ContentTypeTextPlain)
+	return nil
+}
+
+func (r *bodyOnlyRenderer) RenderBody(_ context.Context, w io.Writer) error {
+	r.called = true
+	_, err := io.WriteString(w, "body")
+	return err
+}
+
+func TestRender_Head(t *testing.T) {
+	ctx, res := newHeadContext(t.Context(), http.MethodHead)
+
+	renderer, err := httplib.JSONResponse(map[string]int{"a": 1})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, httplib.ContentTypeJSONUTF8, w.Header().Get("Content-Type"))
+	assert.Equal(t, "7", w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Zero(t, res.ResponseSize)
+	assert.True(t, res.BodyOmitted)
+}
+
+func TestRender_Head_StreamingRenderers(t *testing.T) {
+	generated := false
+	rows := func(yield func([]string) bool) {
+		generated = true
+		yield([]string{"a"})
+	}
+
+	source := &closeRecorder{Reader: strings.NewReader("data")}
+
+	negotiated, err := httplib.NegotiatedResponse(httptest.NewRequest(http.MethodHead, "/", nil), httplib.Representation{
+		MediaType: httplib.ContentTypeCSV,
+		Renderer: func() (httplib.ResponseBodyRenderer, error) {
+			return httplib.CSVResponse(rows), nil
+		},
+	})
+	require.NoError(t, err)
+
+	renderers := map[string]httplib.ResponseBodyRenderer{
+		"csv":    negotiated,
+		"reader": httplib.ReaderResponse(source, httplib.WithFileContentType(httplib.ContentTypeTextPlain)),
+	}
+	for name, renderer := range renderers {
+		t.Run(name, func(t *testing.T) {
+			ctx, res := newHeadContext(t.Context(), http.MethodHead)
+
+			w := httptest.NewRecorder()
+			require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+
+			assert.Empty(t, w.Body.String())
+			assert.NotEmpty(t, w.Header().Get("Content-Type"))
+			assert.Zero(t, res.ResponseSize)
+			assert.True(t, res.BodyOmitted)
+		})
+	}
+
+	assert.False(t, generated)
+	assert.True(t, source.closed)
+}
+
+func TestRender_Head_NotHeadRenderer(t *testing.T) {
+	ctx, res := newHeadContext(t.Context(), http.MethodHead)
+	renderer := &bodyOnlyRenderer{}
+
+	w := httptest.NewRecorder()
+	require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+
+	assert.True(t, renderer.called)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, httplib.ContentTypeTextPlain, w.Header().Get("Content-Type"))
+	assert.Zero(t, res.ResponseSize)
+	assert.True(t, res.BodyOmitted)
+}
+
+func TestRender_Head_Error(t *testing.T) {
+	ctx, res := newHeadContext(t.Context(), http.MethodHead)
+	err := errors.New("close error")
+	source := &closeRecorder{Reader: strings.NewReader("data"), err: err}
+
+	w := httptest.NewRecorder()
+	assert.ErrorIs(t, httplib.RenderOKWithBody(ctx, w, httplib.ReaderResponse(source)), err)
+	assert.True(t, res.BodyOmitted)
+}
+
+func TestRender_Get(t *testing.T) {
+	ctx, res := newHeadContext(t.Context(), http.MethodGet)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, httplib.RenderOKWithBody(ctx, w, &bodyOnlyRenderer{}))
+
+	assert.Equal(t, "body", w.Body.String())
+	assert.EqualValues(t, 4, res.ResponseSize)
+	assert.False(t, res.BodyOmitted)
+}
+
+func TestRender_Head_RequestMethod(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          func(ctx context.Context) context.Context
+		wantOmitted  bool
+		wantRendered bool
+	}{
+		{
+			name: "method without request log",
+			ctx: func(ctx context.Context) context.Context {
+				return httplib.WithRequestMethod(ctx, http.MethodHead)
+			},
+			wantOmitted: true,
+		},
+		{
+			name: "method takes precedence over request log",
+			ctx: func(ctx context.Context) context.Context {
+				ctx = httplib.WithRequestLog(ctx, httplib.RequestLog{Method: http.MethodHead})
+				return httplib.WithRequestMethod(ctx, http.MethodGet)
+			},
+			wantRendered: true,
+		},
+		{
+			name:         "no method",
+			ctx:          func(ctx context.Context) context.Context { return ctx },
+			wantRendered: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res httplib.ResponseLog
+			ctx := httplib.WithResponseLogPtr(tt.ctx(t.Context()), &res)
+			source := &closeRecorder{Reader: strings.NewReader("data")}
+
+			w := httptest.NewRecorder()
+			require.NoError(t, httplib.RenderOKWithBody(ctx, w, httplib.ReaderResponse(source)))
+
+			assert.Equal(t, tt.wantOmitted, res.BodyOmitted)
+			if tt.wantRendered {
+				assert.Equal(t, "data", w.Body.String())
+			} else {
+				assert.Empty(t, w.Body.String())
+			}
+			assert.True(t, source.closed)
+		})
+	}
+}
+
+func TestRender_Head_WithoutBody(t *testing.T) {
+	ctx, res := newHeadContext(t.Context(), http.MethodHead)
+
+	httplib.RenderNoContent(ctx, httptest.NewRecorder())
+	assert.False(t, res.BodyOmitted)
+}
+
+type partialHeaderRenderer struct {
+	errorResponseBodyRenderer
+}
+
+func (r partialHeaderRenderer) RenderHeader(_ context.Context, header http.Header) error {
+	header.Set("X-Partial", "1")
+	return r.headerErr
+}
+
+func Test_RenderTransactional(t *testing.T) {
+	headerErr := errors.New("header error")
+	cause := errors.New("cause")
+	fallback := httplib.RawResponseWithContentType([]byte("error"), httplib.ContentTypeTextPlainUTF8)
+
+	tests := []struct {
+		name            string
+		fallback        httplib.ResponseBodyRenderer
+		f               func(ctx context.Context, w http.ResponseWriter, bodyRenderer httplib.ResponseBodyRenderer) error
+		renderer        httplib.ResponseBodyRenderer
+		wantErr         []error
+		wantStatusCode  int
+		wantBody        string
+		wantPartial     string
+		wantContentType string
+		wantCause       error
+	}{
+		{
+			name:            "success",
+			fallback:        fallback,
+			f:               httplib.RenderOKWithBody,
+			renderer:        partialHeaderRenderer{},
+			wantStatusCode:  http.StatusOK,
+			wantPartial:     "1",
+			wantContentType: "",
+		},
+		{
+			name:            "header error with fallback renderer",
+			fallback:        fallback,
+			f:               httplib.RenderOKWithBody,
+			renderer:        partialHeaderRenderer{errorResponseBodyRenderer{headerErr: headerErr}},
+			wantErr:         []error{headerErr},
+			wantStatusCode:  http.StatusInternalServerError,
+			wantBody:        "error",
+			wantContentType: httplib.ContentTypeTextPlainUTF8,
+			wantCause:       headerErr,
+		},
+		{
+			name:           "header error without fallback renderer",
+			fallback:       nil,
+			f:              httplib.RenderCreatedWithBody,
+			renderer:       partialHeaderRenderer{errorResponseBodyRenderer{headerErr: headerErr}},
+			wantErr:        []error{headerErr},
+			wantStatusCode: http.StatusInternalServerError,
+			wantCause:      headerErr,
+		},
+		{
+			name:     "header error with cause",
+			fallback: nil,
+			f: func(ctx context.Context, w http.ResponseWriter, bodyRenderer httplib.ResponseBodyRenderer) error {
+				return httplib.RenderBadRequestWithBody(ctx, w, bodyRenderer, cause)
+			},
+			renderer:       partialHeaderRenderer{errorResponseBodyRenderer{headerErr: headerErr}},
+			wantErr:        []error{headerErr},
+			wantStatusCode: http.StatusInternalServerError,
+			wantCause:      errors.Join(cause, headerErr),
+		},
+		{
+			name:            "body error of buffered renderer",
+			fallback:        fallback,
+			f:               httplib.RenderOKWithBody,
+			renderer:        httplib.BufferedResponse(errorResponseBodyRenderer{bodyErr: headerErr}),
+			wantErr:         []error{headerErr},
+			wantStatusCode:  http.StatusInternalServerError,
+			wantBody:        "error",
+			wantContentType: httplib.ContentTypeTextPlainUTF8,
+			wantCause:       headerErr,
+		},
+		{
+			name:           "fallback renderer error",
+			fallback:       errorResponseBodyRenderer{headerErr: cause},
+			f:              httplib.RenderOKWithBody,
+			renderer:       errorResponseBodyRenderer{headerErr: headerErr},
+			wantErr:        []error{headerErr, cause},
+			wantStatusCode: http.StatusInternalServerError,
+			wantCause:      headerErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := httplib.WithTransactionalRendering(t.Context(), tt.fallback)
+			ctx = httplib.WithResponseLogPtr(ctx, &httplib.ResponseLog{})
+
+			w := httptest.NewRecorder()
+			err := tt.f(ctx, w, tt.renderer)
+
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+			}
+			for _, wantErr := range tt.wantErr {
+				assert.ErrorIs(t, err, wantErr)
+			}
+
+			assert.Equal(t, tt.wantStatusCode, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.wantPartial, w.Header().Get("X-Partial"))
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			assertResponseLog(ctx, t, tt.wantStatusCode, int64(len(tt.wantBody)), tt.wantCause)
+		})
+	}
+}
+
+func Test_RenderTransactional_ReleasesRenderer(t *testing.T) {
+	ctx := httplib.WithTransactionalRendering(t.Context(), nil)
+	source := &closeRecorder{Reader: strings.NewReader("data")}
+	renderer := httplib.ReaderResponse(errorReaderCloser{closeRecorder: source})
+
+	err := httplib.RenderOKWithBody(ctx, httptest.NewRecorder(), renderer)
+	assert.Error(t, err)
+	assert.True(t, source.closed)
+}
+
+// headerErrorBodyOnlyRenderer fails in RenderHeader, and records RenderBody.
+type headerErrorBodyOnlyRenderer struct {
+	bodyOnlyRenderer
+	err error
+}
+
+func (r *headerErrorBodyOnlyRenderer) RenderHeader(_ context.Context, _ http.Header) error {
+	return r.err
+}
+
+func Test_RenderTransactional_SkipsBody(t *testing.T) {
+	headerErr := errors.New("header error")
+
+	tests := []struct {
+		name     string
+		renderer func(inner httplib.ResponseBodyRenderer) httplib.ResponseBodyRenderer
+	}{
+		{
+			name:     "renderer",
+			renderer: func(inner httplib.ResponseBodyRenderer) httplib.ResponseBodyRenderer { return inner },
+		},
+		{
+			name:     "buffered renderer",
+			renderer: httplib.BufferedResponse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := httplib.WithTransactionalRendering(t.Context(), nil)
+			inner := &headerErrorBodyOnlyRenderer{err: headerErr}
+
+			w := httptest.NewRecorder()
+			assert.ErrorIs(t, httplib.RenderOKWithBody(ctx, w, tt.renderer(inner)), headerErr)
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.False(t, inner.called)
+		})
+	}
+}
+
+// errorReaderCloser fails to be read for sniffing, and records Close.
+type errorReaderCloser struct {
+	*closeRecorder
+}
+
+func (r errorReaderCloser) Read(_ []byte) (int, error) {
+	return 0, errors.New("read error")
+}
+
+func TestBufferedResponse(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("success", func(t *testing.T) {
+		renderer := httplib.BufferedResponse(httplib.CSVResponse(slices.Values([][]string{{"a", "b"}})))
+
+		w := httptest.NewRecorder()
+		require.NoError(t, renderer.RenderHeader(ctx, w.Header()))
+		require.NoError(t, renderer.RenderBody(ctx, w))
+
+		assert.Equal(t, httplib.ContentTypeCSVUTF8, w.Header().Get("Content-Type"))
+		assert.Equal(t, "4", w.Header().Get("Content-Length"))
+		assert.Equal(t, "a,b\n", w.Body.String())
+	})
+
+	t.Run("header error", func(t *testing.T) {
+		source := &closeRecorder{Reader: strings.NewReader("data")}
+		renderer := httplib.BufferedResponse(httplib.ReaderResponse(errorReaderCloser{closeRecorder: source}))
+
+		w := httptest.NewRecorder()
+		assert.Error(t, renderer.RenderHeader(ctx, w.Header()))
+		assert.NoError(t, renderer.RenderBody(ctx, w))
+		assert.Empty(t, w.Body.String())
+		assert.True(t, source.closed)
+	})
+
+	t.Run("head", func(t *testing.T) {
+		ctx, res := newHeadContext(ctx, http.MethodHead)
+		renderer := httplib.BufferedResponse(httplib.CSVResponse(slices.Values([][]string{{"a", "b"}})))
+
+		w := httptest.NewRecorder()
+		require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+
+		assert.Equal(t, "4", w.Header().Get("Content-Length"))
+		assert.Empty(t, w.Body.String())
+		assert.True(t, res.BodyOmitted)
+	})
+}
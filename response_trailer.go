@@ -0,0 +1,156 @@
+package httplib
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// TrailerResponseBodyRenderer is a ResponseBodyRenderer that sends trailers after the body,
+// such as a checksum or the final status of a streamed body.
+//
+// The Render functions call TrailerNames after RenderHeader, and announce the names in the Trailer header
+// before the status code is written. The Content-Length header is removed at the same time,
+// since HTTP/1.1 can send trailers only in a chunked response. After RenderBody, RenderTrailer is called
+// with an empty http.Header, and the values of the announced names are sent as trailers.
+// The other values are ignored. The sent trailers are stored in ResponseLog.Trailers.
+//
+// Trailers are not sent for HEAD requests, and clients may ignore them.
+type TrailerResponseBodyRenderer interface {
+	ResponseBodyRenderer
+	TrailerNames() []string
+	RenderTrailer(ctx context.Context, trailer http.Header) error
+}
+
+// announceTrailers sets the Trailer header to the trailer names of the renderer, and returns the names.
+func announceTrailers(header http.Header, renderer ResponseBodyRenderer) []string {
+	trailerRenderer, ok := renderer.(TrailerResponseBodyRenderer)
+	if !ok {
+		return nil
+	}
+
+	names := trailerRenderer.TrailerNames()
+	if len(names) == 0 {
+		return nil
+	}
+
+	header.Add("Trailer", strings.Join(names, ", "))
+	header.Del("Content-Length")
+	return names
+}
+
+// renderTrailers calls RenderTrailer of the renderer, and sets the values of the announced names to the header,
+// which are sent as trailers by net/http since the status code has already been written.
+func renderTrailers(ctx context.Context, header http.Header, renderer ResponseBodyRenderer, names []string) (http.Header, error) {
+	trailer := make(http.Header, len(names))
+	err := renderer.(TrailerResponseBodyRenderer).RenderTrailer(ctx, trailer)
+
+	var sent http.Header
+	for _, name := range names {
+		values := trailer.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		name = http.CanonicalHeaderKey(name)
+		header[name] = values
+		if sent == nil {
+			sent = make(http.Header, len(names))
+		}
+		sent[name] = values
+	}
+
+	return sent, err
+}
+
+// SHA256TrailerResponse returns a ResponseBodyRenderer that sends the SHA-256 digest of the body
+// written by the renderer as a trailer of the name, in lowercase hexadecimal.
+//
+// The trailers of the renderer are sent as well if it implements TrailerResponseBodyRenderer.
+// Since the digest is computed while the body is written, the body is never held in memory.
+// Each RenderBody computes the digest by a new hash, so the renderer can be rendered again or concurrently
+// if the renderer can, and RenderTrailer sends the digest of the last rendered body.
+func SHA256TrailerResponse(renderer ResponseBodyRenderer, name string) ResponseBodyRenderer {
+	return &hashTrailerResponseBodyRenderer{
+		renderer: renderer,
+		name:     name,
+		newHash:  sha256.New,
+		encode:   hex.EncodeToString,
+	}
+}
+
+type hashTrailerResponseBodyRenderer struct {
+	renderer ResponseBodyRenderer
+	name     string
+	newHash  func() hash.Hash
+	encode   func(sum []byte) string
+
+	mu  sync.Mutex
+	sum []byte // the digest of the last rendered body, or nil if the body has not been rendered
+}
+
+func (r *hashTrailerResponseBodyRenderer) RenderHeader(ctx context.Context, header http.Header) error {
+	return r.renderer.RenderHeader(ctx, header)
+}
+
+func (r *hashTrailerResponseBodyRenderer) RenderHead(ctx context.Context) error {
+	return renderHead(ctx, r.renderer)
+}
+
+func (r *hashTrailerResponseBodyRenderer) RenderBody(ctx context.Context, w io.Writer) error {
+	h := r.newHash()
+	err := r.renderer.RenderBody(ctx, &hashWriter{w: w, hash: h})
+
+	r.mu.Lock()
+	r.sum = h.Sum(nil)
+	r.mu.Unlock()
+	return err
+}
+
+func (r *hashTrailerResponseBodyRenderer) TrailerNames() []string {
+	names := []string{r.name}
+	if trailerRenderer, ok := r.renderer.(TrailerResponseBodyRenderer); ok {
+		names = append(names, trailerRenderer.TrailerNames()...)
+	}
+	return names
+}
+
+func (r *hashTrailerResponseBodyRenderer) RenderTrailer(ctx context.Context, trailer http.Header) error {
+	var err error
+	if trailerRenderer, ok := r.renderer.(TrailerResponseBodyRenderer); ok {
+		err = trailerRenderer.RenderTrailer(ctx, trailer)
+	}
+
+	r.mu.Lock()
+	sum := r.sum
+	r.mu.Unlock()
+	if sum == nil {
+		sum = r.newHash().Sum(nil)
+	}
+
+	trailer.Set(r.name, r.encode(sum))
+	return err
+}
+
+// hashWriter writes to w and the hash, and forwards Flush to w, so that streaming renderers can still flush.
+type hashWriter struct {
+	w    io.Writer
+	hash io.Writer // a hash.Hash, or an io.MultiWriter of them
+}
+
+func (w *hashWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.hash.Write(b[:n])
+	return n, err
+}
+
+func (w *hashWriter) Flush() {
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,133 @@
+package httplib_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type statusTrailerRenderer struct {
+	httplib.ResponseBodyRenderer
+	status string
+	err    error
+}
+
+func (r *statusTrailerRenderer) TrailerNames() []string {
+	return []string{"x-status"}
+}
+
+func (r *statusTrailerRenderer) RenderTrailer(_ context.Context, trailer http.Header) error {
+	trailer.Set("X-Status", r.status)
+	trailer.Set("X-Not-Announced", "ignored")
+	return r.err
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSHA256TrailerResponse(t *testing.T) {
+	body := "a,b\nc,d\n"
+	rows := slices.Values([][]string{{"a", "b"}, {"c", "d"}})
+
+	var res httplib.ResponseLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := httplib.WithResponseLogPtr(r.Context(), &res)
+		renderer := httplib.SHA256TrailerResponse(&statusTrailerRenderer{ResponseBodyRenderer: httplib.CSVResponse(rows), status: "done"}, "X-Checksum")
+		assert.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+	}))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, body, string(data))
+	assert.Equal(t, int64(-1), resp.ContentLength)
+	assert.Equal(t, http.Header{"X-Checksum": {sha256Hex(body)}, "X-Status": {"done"}}, resp.Trailer)
+
+	assert.Equal(t, http.Header{"X-Checksum": {sha256Hex(body)}, "X-Status": {"done"}}, res.Trailers)
+	assert.EqualValues(t, len(body), res.ResponseSize)
+}
+
+func TestSHA256TrailerResponse_Reusable(t *testing.T) {
+	renderer := httplib.SHA256TrailerResponse(httplib.RawResponse([]byte("raw")), "X-Checksum")
+
+	render := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		if err := httplib.RenderOKWithBody(t.Context(), w, renderer); err != nil {
+			t.Error(err)
+		}
+		return w
+	}
+
+	t.Run("sequential", func(t *testing.T) {
+		for range 3 {
+			w := render()
+			assert.Equal(t, "raw", w.Body.String())
+			assert.Equal(t, sha256Hex("raw"), w.Result().Trailer.Get("X-Checksum"))
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				w := render()
+				assert.Equal(t, sha256Hex("raw"), w.Result().Trailer.Get("X-Checksum"))
+			})
+		}
+		wg.Wait()
+	})
+}
+
+func TestSHA256TrailerResponse_RemovesContentLength(t *testing.T) {
+	ctx := t.Context()
+	renderer := httplib.SHA256TrailerResponse(httplib.RawResponse([]byte("raw")), "X-Checksum")
+
+	w := httptest.NewRecorder()
+	require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+
+	assert.Equal(t, "X-Checksum", w.Header().Get("Trailer"))
+	assert.Empty(t, w.Result().Header.Get("Content-Length"))
+	assert.Equal(t, sha256Hex("raw"), w.Result().Trailer.Get("X-Checksum"))
+}
+
+func TestTrailerResponseBodyRenderer_Error(t *testing.T) {
+	err := errors.New("trailer error")
+	ctx := httplib.WithResponseLogPtr(t.Context(), &httplib.ResponseLog{})
+	renderer := &statusTrailerRenderer{ResponseBodyRenderer: httplib.RawResponse([]byte("raw")), status: "failed", err: err}
+
+	w := httptest.NewRecorder()
+	assert.ErrorIs(t, httplib.RenderOKWithBody(ctx, w, renderer), err)
+
+	assert.Equal(t, "failed", w.Result().Trailer.Get("X-Status"))
+	assert.Equal(t, http.Header{"X-Status": {"failed"}}, httplib.GetResponseLogPtrFromContext(ctx).Trailers)
+}
+
+func TestTrailerResponseBodyRenderer_Head(t *testing.T) {
+	ctx, res := newHeadContext(t.Context(), http.MethodHead)
+	renderer := httplib.SHA256TrailerResponse(httplib.RawResponse([]byte("raw")), "X-Checksum")
+
+	w := httptest.NewRecorder()
+	require.NoError(t, httplib.RenderOKWithBody(ctx, w, renderer))
+
+	assert.Empty(t, w.Header().Get("Trailer"))
+	assert.Equal(t, "3", w.Header().Get("Content-Length"))
+	assert.Nil(t, res.Trailers)
+}
@@ -0,0 +1,542 @@
+package httplib
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound is returned by SessionStore.Load when the session does not exist or has expired.
+var ErrSessionNotFound = errors.New("httplib: session not found")
+
+const (
+	defaultSessionCookieName      = "session"
+	defaultSessionIdleTimeout     = 30 * time.Minute
+	defaultSessionAbsoluteTimeout = 24 * time.Hour
+
+	// cookieSessionName is the name that CookieSessionStore binds to the encoded sessions,
+	// so that the values of the other cookies encoded by the same CookieCodec cannot be used as sessions.
+	cookieSessionName = "httplib-session"
+)
+
+// SessionData is the state of a session saved in SessionStore.
+type SessionData struct {
+	// ID is the random identifier of the session, which is changed by Session.Regenerate.
+	ID string `json:"id"`
+
+	// Values are the values set by Session.Set.
+	Values map[string]string `json:"values,omitempty"`
+
+	// Flashes are the values added by Session.AddFlash, which are removed when they are read.
+	Flashes []string `json:"flashes,omitempty"`
+
+	// CreatedAt is the time when the session is created, which the absolute timeout is measured from.
+	CreatedAt time.Time `json:"created_at"`
+
+	// LastAccessedAt is the time of the last request of the session, which the idle timeout is measured from.
+	LastAccessedAt time.Time `json:"last_accessed_at"`
+}
+
+// SessionStore saves the sessions of SessionManager. It must be safe for concurrent use.
+type SessionStore interface {
+	// Load returns the session of the cookie value returned by Save.
+	// If the session does not exist or has expired, it returns ErrSessionNotFound.
+	Load(ctx context.Context, value string) (*SessionData, error)
+
+	// Save saves the session until expiresAt, and returns the cookie value that identifies it.
+	Save(ctx context.Context, data *SessionData, expiresAt time.Time) (string, error)
+
+	// Delete deletes the session of the ID.
+	Delete(ctx context.Context, id string) error
+}
+
+// CookieSessionStore is a SessionStore that saves the sessions in the cookie itself, encoded by CookieCodec.
+//
+// No state is kept on the server, so that the sessions are shared by servers without a storage.
+// On the other hand, a session cannot be revoked before it expires: Delete only lets SessionManager remove the cookie,
+// and a copy of the cookie value is still valid. Use a CookieCodec of NewEncryptedCookieCodec
+// to prevent clients from reading the values, and keep the values small to fit in a cookie.
+type CookieSessionStore struct {
+	codec *CookieCodec
+}
+
+// NewCookieSessionStore returns a CookieSessionStore that encodes the sessions by the codec.
+func NewCookieSessionStore(codec *CookieCodec) *CookieSessionStore {
+	return &CookieSessionStore{codec: codec}
+}
+
+func (s *CookieSessionStore) Load(_ context.Context, value string) (*SessionData, error) {
+	b, err := s.codec.Decode(cookieSessionName, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSessionNotFound, err)
+	}
+
+	var data SessionData
+	if err := json.Unmarshal(b, &data); err != nil || data.ID == "" {
+		return nil, ErrSessionNotFound
+	}
+	return &data, nil
+}
+
+func (s *CookieSessionStore) Save(_ context.Context, data *SessionData, expiresAt time.Time) (string, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return s.codec.Encode(cookieSessionName, b, expiresAt)
+}
+
+func (s *CookieSessionStore) Delete(context.Context, string) error {
+	return nil
+}
+
+// MemorySessionStore is a SessionStore that keeps the sessions in memory, and uses the session ID as the cookie value.
+//
+// The sessions are not shared by servers, and are lost when the server restarts.
+// Expired sessions are not returned by Load, and are removed by Sweep, which should be run periodically by RunSweeper.
+type MemorySessionStore struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// NewMemorySessionStore returns an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{now: time.Now, sessions: make(map[string]memorySession)}
+}
+
+func (s *MemorySessionStore) Load(_ context.Context, value string) (*SessionData, error) {
+	s.mu.Lock()
+	session, ok := s.sessions[value]
+	s.mu.Unlock()
+
+	if !ok || !s.now().Before(session.expiresAt) {
+		return nil, ErrSessionNotFound
+	}
+
+	var data SessionData
+	if err := json.Unmarshal(session.data, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (s *MemorySessionStore) Save(_ context.Context, data *SessionData, expiresAt time.Time) (string, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.sessions[data.ID] = memorySession{data: b, expiresAt: expiresAt}
+	s.mu.Unlock()
+	return data.ID, nil
+}
+
+func (s *MemorySessionStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Sweep removes the expired sessions.
+func (s *MemorySessionStore) Sweep() {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, session := range s.sessions {
+		if !now.Before(session.expiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// RunSweeper calls Sweep every interval until ctx is done. It blocks, so that it should be called in a goroutine.
+func (s *MemorySessionStore) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sweep()
+		}
+	}
+}
+
+// SessionOption configures NewSessionManager.
+type SessionOption func(*sessionConfig)
+
+type sessionConfig struct {
+	cookieName      string
+	idleTimeout     time.Duration
+	absoluteTimeout time.Duration
+	cookieOpts      []CookieOption
+}
+
+// WithSessionCookieName sets the name of the session cookie. The default is "session".
+func WithSessionCookieName(name string) SessionOption {
+	return func(cfg *sessionConfig) {
+		cfg.cookieName = name
+	}
+}
+
+// WithSessionIdleTimeout sets the duration after which a session expires without requests. The default is 30 minutes.
+func WithSessionIdleTimeout(timeout time.Duration) SessionOption {
+	return func(cfg *sessionConfig) {
+		cfg.idleTimeout = timeout
+	}
+}
+
+// WithSessionAbsoluteTimeout sets the duration after which a session expires regardless of requests.
+// The default is 24 hours.
+func WithSessionAbsoluteTimeout(timeout time.Duration) SessionOption {
+	return func(cfg *sessionConfig) {
+		cfg.absoluteTimeout = timeout
+	}
+}
+
+// WithSessionCookieOptions sets the options of the session cookie, such as WithCookiePath and WithCookieDomain.
+// The cookie has the defaults of CookieCodec.SetCookie, and its Max-Age is always set by the timeouts.
+func WithSessionCookieOptions(opts ...CookieOption) SessionOption {
+	return func(cfg *sessionConfig) {
+		cfg.cookieOpts = append(cfg.cookieOpts, opts...)
+	}
+}
+
+// SessionManager manages the sessions of the requests by a cookie and SessionStore.
+type SessionManager struct {
+	store SessionStore
+	cfg   sessionConfig
+	now   func() time.Time
+}
+
+// NewSessionManager returns a SessionManager that saves the sessions in the store.
+func NewSessionManager(store SessionStore, opts ...SessionOption) *SessionManager {
+	cfg := sessionConfig{
+		cookieName:      defaultSessionCookieName,
+		idleTimeout:     defaultSessionIdleTimeout,
+		absoluteTimeout: defaultSessionAbsoluteTimeout,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	return &SessionManager{store: store, cfg: cfg, now: time.Now}
+}
+
+// Middleware returns a middleware that loads the session of the request into the context, which is returned by GetSession,
+// and saves it before the response headers are written.
+//
+// A new session is started if the request has no session, or its session has exceeded the idle or absolute timeout.
+// New sessions are saved only if they are modified, so that requests without sessions do not get a cookie.
+// The sessions of the requests are saved every time to extend the idle timeout.
+//
+// The hash of the loaded session ID is set to RequestLog.SessionIDHash of the context, so that the logs of the handler
+// can be correlated by the session without leaking the ID. After the handler returns, the hash of the saved session ID,
+// which is the new ID of a session started or regenerated by the request, is set to ResponseLog.SessionIDHash,
+// so that it is also seen by the middlewares wrapping this one, such as access loggers. If the store fails to load the session,
+// the middleware renders 500 Internal Server Error. If it fails to save the session, the error is added to ResponseLog.Error.
+func (m *SessionManager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		session, err := m.load(r)
+		if err != nil {
+			RenderInternalServerError(ctx, w, err)
+			return
+		}
+
+		ctx = WithRequestMethod(context.WithValue(ctx, contextKeySession, session), r.Method)
+		if session.loadedID != "" {
+			requestLog := GetRequestLogFromContext(ctx)
+			requestLog.SessionIDHash = hashSessionID(session.loadedID)
+			ctx = WithRequestLog(ctx, requestLog)
+		}
+
+		sw := &sessionResponseWriter{ResponseWriter: w, commit: func() error { return m.commit(ctx, w, session) }}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		err = sw.commitOnce()
+		if resPtr := GetResponseLogPtrFromContext(ctx); resPtr != nil {
+			if err != nil {
+				resPtr.Error = errors.Join(resPtr.Error, err)
+			}
+			if id := session.savedSessionID(); id != "" {
+				resPtr.SessionIDHash = hashSessionID(id)
+			}
+		}
+	})
+}
+
+func (m *SessionManager) load(r *http.Request) (*Session, error) {
+	cookie, err := r.Cookie(m.cfg.cookieName)
+	if err != nil {
+		return m.newSession(false), nil
+	}
+
+	data, err := m.store.Load(r.Context(), cookie.Value)
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		return m.newSession(true), nil
+	case err != nil:
+		return nil, err
+	}
+
+	now := m.now()
+	if !now.Before(data.LastAccessedAt.Add(m.cfg.idleTimeout)) || !now.Before(data.CreatedAt.Add(m.cfg.absoluteTimeout)) {
+		if err := m.store.Delete(r.Context(), data.ID); err != nil {
+			return nil, err
+		}
+		return m.newSession(true), nil
+	}
+
+	if data.Values == nil {
+		data.Values = make(map[string]string)
+	}
+	return &Session{data: data, now: m.now, loadedID: data.ID, cookiePresent: true}, nil
+}
+
+func (m *SessionManager) newSession(cookiePresent bool) *Session {
+	now := m.now()
+	return &Session{
+		data:          &SessionData{ID: rand.Text(), Values: make(map[string]string), CreatedAt: now, LastAccessedAt: now},
+		now:           m.now,
+		cookiePresent: cookiePresent,
+	}
+}
+
+// commit saves the session and sets the cookie, or deletes them if the session is destroyed.
+func (m *SessionManager) commit(ctx context.Context, w http.ResponseWriter, s *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.destroyed || (s.loadedID == "" && !s.modified) {
+		var err error
+		if s.loadedID != "" {
+			err = m.store.Delete(ctx, s.loadedID)
+		}
+		if s.cookiePresent {
+			m.deleteCookie(w)
+		}
+		return err
+	}
+
+	if s.loadedID != "" && s.loadedID != s.data.ID {
+		if err := m.store.Delete(ctx, s.loadedID); err != nil {
+			return err
+		}
+	}
+
+	now := m.now()
+	s.data.LastAccessedAt = now
+	expiresAt := now.Add(m.cfg.idleTimeout)
+	if absolute := s.data.CreatedAt.Add(m.cfg.absoluteTimeout); absolute.Before(expiresAt) {
+		expiresAt = absolute
+	}
+
+	value, err := m.store.Save(ctx, s.data, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	cookie := newCookie(m.cfg.cookieName, m.cfg.cookieOpts)
+	cookie.Value = value
+	cookie.MaxAge = max(int(expiresAt.Sub(now)/time.Second), 1)
+	if len(cookie.Name)+len(cookie.Value) > maxCookieSize {
+		return fmt.Errorf("%w: %q is %d bytes", ErrCookieTooLarge, cookie.Name, len(cookie.Name)+len(cookie.Value))
+	}
+
+	http.SetCookie(w, cookie)
+	s.savedID = s.data.ID
+	return nil
+}
+
+func (m *SessionManager) deleteCookie(w http.ResponseWriter) {
+	cookie := newCookie(m.cfg.cookieName, m.cfg.cookieOpts)
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+}
+
+// hashSessionID returns the first 8 bytes of the SHA-256 hash of the session ID in hex.
+func hashSessionID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+// GetSession returns the session of the request loaded by SessionManager.Middleware.
+//
+// If the context does not contain a session, it returns nil.
+func GetSession(ctx context.Context) *Session {
+	session, _ := ctx.Value(contextKeySession).(*Session)
+	return session
+}
+
+// Session is the session of a request. It is safe for concurrent use.
+//
+// The changes are saved before the response headers are written, so that they must be made before rendering the response.
+type Session struct {
+	mu   sync.Mutex
+	data *SessionData
+	now  func() time.Time
+
+	loadedID      string // the ID of the session in the store, or empty if the session is new
+	savedID       string // the ID of the session saved by SessionManager.commit, or empty if it is not saved
+	cookiePresent bool   // whether the request has the session cookie, which must be deleted if the session is not saved
+	modified      bool
+	destroyed     bool
+}
+
+// savedSessionID returns the ID of the session saved by SessionManager.commit, or empty if it is not saved.
+func (s *Session) savedSessionID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.savedID
+}
+
+// IsNew reports whether the session is started by the request.
+func (s *Session) IsNew() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadedID == ""
+}
+
+// Get returns the value of the key.
+func (s *Session) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.data.Values[key]
+	return value, ok
+}
+
+// Values returns a copy of the values of the session.
+func (s *Session) Values() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.data.Values)
+}
+
+// Set sets the value of the key.
+func (s *Session) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Values[key] = value
+	s.modified = true
+}
+
+// Delete deletes the value of the key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data.Values, key)
+	s.modified = true
+}
+
+// AddFlash adds a value that is kept until it is read by Flashes, such as a message shown after a redirect.
+func (s *Session) AddFlash(value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Flashes = append(s.data.Flashes, value)
+	s.modified = true
+}
+
+// Flashes returns the values added by AddFlash, and removes them from the session.
+func (s *Session) Flashes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flashes := s.data.Flashes
+	if len(flashes) != 0 {
+		s.data.Flashes = nil
+		s.modified = true
+	}
+	return flashes
+}
+
+// Regenerate changes the session ID and restarts the absolute timeout, keeping the values,
+// and deletes the session of the old ID from the store.
+//
+// It must be called when the privilege of the session changes, such as on login,
+// to prevent session fixation attacks.
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.ID = rand.Text()
+	s.data.CreatedAt = s.now()
+	s.modified = true
+	s.destroyed = false
+}
+
+// Destroy deletes the values of the session, the session in the store, and the session cookie, such as on logout.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.data.Values)
+	s.data.Flashes = nil
+	s.destroyed = true
+}
+
+// sessionResponseWriter commits the session before the response headers are written.
+type sessionResponseWriter struct {
+	http.ResponseWriter
+	commit    func() error
+	committed bool
+	err       error
+}
+
+func (w *sessionResponseWriter) commitOnce() error {
+	if !w.committed {
+		w.committed = true
+		w.err = w.commit()
+	}
+	return w.err
+}
+
+func (w *sessionResponseWriter) WriteHeader(statusCode int) {
+	if statusCode >= 200 {
+		_ = w.commitOnce() // the error is reported after the handler returns
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *sessionResponseWriter) Write(b []byte) (int, error) {
+	_ = w.commitOnce()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sessionResponseWriter) Flush() {
+	_ = w.commitOnce()
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *sessionResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,444 @@
+package httplib_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveSession serves a GET request with the cookie to the handler wrapped by the manager,
+// and returns the response, the ResponseLog and the RequestLog seen by the handler.
+func serveSession(t *testing.T, m *httplib.SessionManager, cookie *http.Cookie, handler func(s *httplib.Session)) (*httptest.ResponseRecorder, *httplib.ResponseLog, httplib.RequestLog) {
+	t.Helper()
+
+	var requestLog httplib.RequestLog
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestLog = httplib.GetRequestLogFromContext(r.Context())
+		if handler != nil {
+			handler(httplib.GetSession(r.Context()))
+		}
+		httplib.RenderNoContent(r.Context(), w)
+	}))
+
+	var res httplib.ResponseLog
+	ctx := httplib.WithRequestLog(t.Context(), httplib.RequestLog{Method: http.MethodGet})
+	r := httptest.NewRequestWithContext(httplib.WithResponseLogPtr(ctx, &res), http.MethodGet, "/", nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w, &res, requestLog
+}
+
+func sessionCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	return cookies[0]
+}
+
+func newTestSessionStores(t *testing.T) map[string]httplib.SessionStore {
+	t.Helper()
+
+	codec, err := httplib.NewEncryptedCookieCodec(testEncryptionKey)
+	require.NoError(t, err)
+
+	return map[string]httplib.SessionStore{
+		"memory": httplib.NewMemorySessionStore(),
+		"cookie": httplib.NewCookieSessionStore(codec),
+	}
+}
+
+func TestSessionManager(t *testing.T) {
+	for name, store := range newTestSessionStores(t) {
+		t.Run(name, func(t *testing.T) {
+			m := httplib.NewSessionManager(store, httplib.WithSessionCookieName("sid"), httplib.WithSessionCookieOptions(httplib.WithCookiePath("/app")))
+
+			w, _, requestLog := serveSession(t, m, nil, func(s *httplib.Session) {
+				assert.True(t, s.IsNew())
+				s.Set("user", "alice")
+				s.Set("theme", "dark")
+				s.Delete("theme")
+			})
+			assert.Empty(t, requestLog.SessionIDHash)
+
+			cookie := sessionCookie(t, w)
+			assert.Equal(t, "sid", cookie.Name)
+			assert.Equal(t, "/app", cookie.Path)
+			assert.True(t, cookie.HttpOnly)
+			assert.True(t, cookie.Secure)
+			assert.Equal(t, 1800, cookie.MaxAge)
+			assert.NotContains(t, cookie.Value, "alice")
+
+			w, _, requestLog = serveSession(t, m, cookie, func(s *httplib.Session) {
+				assert.False(t, s.IsNew())
+				value, ok := s.Get("user")
+				assert.True(t, ok)
+				assert.Equal(t, "alice", value)
+				assert.Equal(t, map[string]string{"user": "alice"}, s.Values())
+			})
+			assert.NotEmpty(t, requestLog.SessionIDHash)
+			assert.NotContains(t, cookie.Value, requestLog.SessionIDHash)
+			assert.Equal(t, "sid", sessionCookie(t, w).Name)
+		})
+	}
+}
+
+func TestSessionManager_NewSessionNotSaved(t *testing.T) {
+	m := httplib.NewSessionManager(httplib.NewMemorySessionStore())
+
+	w, _, _ := serveSession(t, m, nil, func(s *httplib.Session) {
+		_, ok := s.Get("user")
+		assert.False(t, ok)
+	})
+	assert.Empty(t, w.Header().Values("Set-Cookie"))
+}
+
+func TestSessionManager_SessionIDHash(t *testing.T) {
+	m := httplib.NewSessionManager(httplib.NewMemorySessionStore())
+
+	w, _, _ := serveSession(t, m, nil, func(s *httplib.Session) { s.Set("user", "alice") })
+	cookie := sessionCookie(t, w)
+
+	_, _, requestLog := serveSession(t, m, cookie, nil)
+	// MemorySessionStore uses the session ID as the cookie value.
+	assert.Equal(t, httplib.HashSessionID(cookie.Value), requestLog.SessionIDHash)
+	assert.Len(t, requestLog.SessionIDHash, 16)
+	assert.NotContains(t, requestLog.ToAttr().String(), cookie.Value)
+}
+
+func TestSessionManager_SessionIDHash_AccessLog(t *testing.T) {
+	m := httplib.NewSessionManager(httplib.NewMemorySessionStore())
+
+	// serveLogged serves the request through an access-log middleware wrapping the session middleware,
+	// and returns the session ID hash in the log emitted by it, and the session cookie of the response.
+	serveLogged := func(t *testing.T, cookie *http.Cookie, handler func(s *httplib.Session)) (string, *http.Cookie) {
+		t.Helper()
+
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, nil))
+		accessLog := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var res httplib.ResponseLog
+				requestLog := httplib.NewRequestLog(r, time.Now())
+				ctx := httplib.WithResponseLogPtr(httplib.WithRequestLog(r.Context(), requestLog), &res)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				logger.InfoContext(ctx, "access", requestLog.ToAttr(), res.ToAttr(0))
+			})
+		}
+
+		h := accessLog(m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if handler != nil {
+				handler(httplib.GetSession(r.Context()))
+			}
+			httplib.RenderNoContent(r.Context(), w)
+		})))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		var entry struct {
+			Response struct {
+				SessionIDHash string `json:"session_id_hash"`
+			} `json:"http_response"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+		cookies := w.Result().Cookies()
+		if len(cookies) == 0 {
+			return entry.Response.SessionIDHash, nil
+		}
+		return entry.Response.SessionIDHash, cookies[0]
+	}
+
+	// MemorySessionStore uses the session ID as the cookie value.
+	hash, cookie := serveLogged(t, nil, func(s *httplib.Session) { s.Set("user", "alice") })
+	require.NotNil(t, cookie)
+	assert.Equal(t, httplib.HashSessionID(cookie.Value), hash, "new session")
+
+	hash, _ = serveLogged(t, cookie, nil)
+	assert.Equal(t, httplib.HashSessionID(cookie.Value), hash, "loaded session")
+
+	hash, newCookie := serveLogged(t, cookie, func(s *httplib.Session) { s.Regenerate() })
+	require.NotNil(t, newCookie)
+	assert.NotEqual(t, cookie.Value, newCookie.Value)
+	assert.Equal(t, httplib.HashSessionID(newCookie.Value), hash, "regenerated session")
+
+	hash, _ = serveLogged(t, nil, nil)
+	assert.Empty(t, hash, "session not saved")
+
+	hash, _ = serveLogged(t, newCookie, func(s *httplib.Session) { s.Destroy() })
+	assert.Empty(t, hash, "destroyed session")
+}
+
+func TestSession_Flashes(t *testing.T) {
+	m := httplib.NewSessionManager(httplib.NewMemorySessionStore())
+
+	w, _, _ := serveSession(t, m, nil, func(s *httplib.Session) {
+		s.AddFlash("saved")
+		s.AddFlash("welcome")
+	})
+	cookie := sessionCookie(t, w)
+
+	serveSession(t, m, cookie, func(s *httplib.Session) {
+		assert.Equal(t, []string{"saved", "welcome"}, s.Flashes())
+		assert.Empty(t, s.Flashes())
+	})
+	serveSession(t, m, cookie, func(s *httplib.Session) {
+		assert.Empty(t, s.Flashes())
+	})
+}
+
+func TestSession_Regenerate(t *testing.T) {
+	m := httplib.NewSessionManager(httplib.NewMemorySessionStore())
+
+	w, _, _ := serveSession(t, m, nil, func(s *httplib.Session) { s.Set("cart", "1") })
+	oldCookie := sessionCookie(t, w)
+
+	w, _, _ = serveSession(t, m, oldCookie, func(s *httplib.Session) {
+		s.Regenerate()
+		s.Set("user", "alice")
+	})
+	newCookie := sessionCookie(t, w)
+	assert.NotEqual(t, oldCookie.Value, newCookie.Value)
+
+	serveSession(t, m, newCookie, func(s *httplib.Session) {
+		assert.False(t, s.IsNew())
+		assert.Equal(t, map[string]string{"cart": "1", "user": "alice"}, s.Values())
+	})
+
+	w, _, _ = serveSession(t, m, oldCookie, func(s *httplib.Session) {
+		assert.True(t, s.IsNew())
+		assert.Empty(t, s.Values())
+	})
+	assert.Equal(t, -1, sessionCookie(t, w).MaxAge)
+}
+
+func TestSession_Destroy(t *testing.T) {
+	m := httplib.NewSessionManager(httplib.NewMemorySessionStore())
+
+	w, _, _ := serveSession(t, m, nil, func(s *httplib.Session) { s.Set("user", "alice") })
+	cookie := sessionCookie(t, w)
+
+	w, _, _ = serveSession(t, m, cookie, func(s *httplib.Session) {
+		s.Destroy()
+		assert.Empty(t, s.Values())
+	})
+	deleted := sessionCookie(t, w)
+	assert.Equal(t, -1, deleted.MaxAge)
+	assert.Empty(t, deleted.Value)
+
+	serveSession(t, m, cookie, func(s *httplib.Session) {
+		assert.True(t, s.IsNew())
+	})
+}
+
+func TestSessionManager_Timeouts(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		requests []time.Duration // the elapsed times of the requests after the session is created
+		wantNew  bool
+	}{
+		{name: "within idle timeout", requests: []time.Duration{29 * time.Minute}},
+		{name: "idle timeout", requests: []time.Duration{30 * time.Minute}, wantNew: true},
+		{name: "extended by requests", requests: []time.Duration{20 * time.Minute, 40 * time.Minute, 60 * time.Minute}},
+		{name: "absolute timeout", requests: []time.Duration{25 * time.Minute, 50 * time.Minute, 75 * time.Minute, 100 * time.Minute, 125 * time.Minute}, wantNew: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := httplib.NewMemorySessionStore()
+			m := httplib.NewSessionManager(store, httplib.WithSessionIdleTimeout(30*time.Minute), httplib.WithSessionAbsoluteTimeout(2*time.Hour))
+
+			clock := now
+			httplib.SetSessionManagerClock(m, func() time.Time { return clock })
+			httplib.SetMemorySessionStoreClock(store, func() time.Time { return clock })
+
+			w, _, _ := serveSession(t, m, nil, func(s *httplib.Session) { s.Set("user", "alice") })
+			cookie := sessionCookie(t, w)
+
+			var isNew bool
+			for _, elapsed := range tt.requests {
+				clock = now.Add(elapsed)
+				serveSession(t, m, cookie, func(s *httplib.Session) { isNew = s.IsNew() })
+			}
+			assert.Equal(t, tt.wantNew, isNew)
+		})
+	}
+
+	t.Run("max age is limited by absolute timeout", func(t *testing.T) {
+		m := httplib.NewSessionManager(httplib.NewMemorySessionStore(), httplib.WithSessionIdleTimeout(time.Hour), httplib.WithSessionAbsoluteTimeout(90*time.Minute))
+
+		clock := time.Now()
+		httplib.SetSessionManagerClock(m, func() time.Time { return clock })
+
+		w, _, _ := serveSession(t, m, nil, func(s *httplib.Session) { s.Set("user", "alice") })
+		cookie := sessionCookie(t, w)
+		assert.Equal(t, 3600, cookie.MaxAge)
+
+		clock = clock.Add(45 * time.Minute)
+		w, _, _ = serveSession(t, m, cookie, nil)
+		assert.Equal(t, 2700, sessionCookie(t, w).MaxAge)
+	})
+}
+
+func TestSessionManager_InvalidCookie(t *testing.T) {
+	for name, store := range newTestSessionStores(t) {
+		t.Run(name, func(t *testing.T) {
+			m := httplib.NewSessionManager(store)
+
+			w, res, requestLog := serveSession(t, m, &http.Cookie{Name: "session", Value: "forged"}, func(s *httplib.Session) {
+				assert.True(t, s.IsNew())
+			})
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.NoError(t, res.Error)
+			assert.Empty(t, requestLog.SessionIDHash)
+			assert.Equal(t, -1, sessionCookie(t, w).MaxAge)
+		})
+	}
+}
+
+func TestSessionManager_CookieTooLarge(t *testing.T) {
+	m := httplib.NewSessionManager(newTestSessionStores(t)["cookie"])
+
+	w, res, _ := serveSession(t, m, nil, func(s *httplib.Session) { s.Set("data", strings.Repeat("a", 4096)) })
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.ErrorIs(t, res.Error, httplib.ErrCookieTooLarge)
+	assert.Empty(t, w.Header().Values("Set-Cookie"))
+}
+
+// failingSessionStore returns the errors for the operations.
+type failingSessionStore struct {
+	httplib.SessionStore
+	loadErr error
+	saveErr error
+}
+
+func (s *failingSessionStore) Load(ctx context.Context, value string) (*httplib.SessionData, error) {
+	if s.loadErr != nil {
+		return nil, s.loadErr
+	}
+	return s.SessionStore.Load(ctx, value)
+}
+
+func (s *failingSessionStore) Save(ctx context.Context, data *httplib.SessionData, expiresAt time.Time) (string, error) {
+	if s.saveErr != nil {
+		return "", s.saveErr
+	}
+	return s.SessionStore.Save(ctx, data, expiresAt)
+}
+
+func TestSessionManager_StoreError(t *testing.T) {
+	errStore := errors.New("store is unavailable")
+
+	t.Run("load", func(t *testing.T) {
+		m := httplib.NewSessionManager(&failingSessionStore{SessionStore: httplib.NewMemorySessionStore(), loadErr: errStore})
+
+		called := false
+		w, res, _ := serveSession(t, m, &http.Cookie{Name: "session", Value: "id"}, func(*httplib.Session) { called = true })
+		assert.False(t, called)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.ErrorIs(t, res.Error, errStore)
+	})
+
+	t.Run("save", func(t *testing.T) {
+		m := httplib.NewSessionManager(&failingSessionStore{SessionStore: httplib.NewMemorySessionStore(), saveErr: errStore})
+
+		w, res, _ := serveSession(t, m, nil, func(s *httplib.Session) { s.Set("user", "alice") })
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.ErrorIs(t, res.Error, errStore)
+		assert.Empty(t, w.Header().Values("Set-Cookie"))
+	})
+}
+
+func TestSessionManager_CommitBeforeWrite(t *testing.T) {
+	m := httplib.NewSessionManager(httplib.NewMemorySessionStore())
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := httplib.GetSession(r.Context())
+		s.Set("user", "alice")
+		_, _ = w.Write([]byte("ok"))
+		s.Set("theme", "dark") // too late to be saved
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "ok", w.Body.String())
+	cookie := sessionCookie(t, w)
+
+	serveSession(t, m, cookie, func(s *httplib.Session) {
+		assert.Equal(t, map[string]string{"user": "alice"}, s.Values())
+	})
+}
+
+func TestGetSession_NotInContext(t *testing.T) {
+	assert.Nil(t, httplib.GetSession(t.Context()))
+}
+
+func TestMemorySessionStore_Sweep(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := httplib.NewMemorySessionStore()
+	httplib.SetMemorySessionStoreClock(store, func() time.Time { return now })
+
+	ctx := t.Context()
+	_, err := store.Save(ctx, &httplib.SessionData{ID: "expired"}, now)
+	require.NoError(t, err)
+	_, err = store.Save(ctx, &httplib.SessionData{ID: "active"}, now.Add(time.Minute))
+	require.NoError(t, err)
+
+	_, err = store.Load(ctx, "expired")
+	assert.ErrorIs(t, err, httplib.ErrSessionNotFound)
+
+	store.Sweep()
+
+	// The expired session is removed even if the clock goes back.
+	httplib.SetMemorySessionStoreClock(store, func() time.Time { return now.Add(-time.Hour) })
+	_, err = store.Load(ctx, "expired")
+	assert.ErrorIs(t, err, httplib.ErrSessionNotFound)
+
+	data, err := store.Load(ctx, "active")
+	require.NoError(t, err)
+	assert.Equal(t, "active", data.ID)
+
+	require.NoError(t, store.Delete(ctx, "active"))
+	_, err = store.Load(ctx, "active")
+	assert.ErrorIs(t, err, httplib.ErrSessionNotFound)
+}
+
+func TestMemorySessionStore_RunSweeper(t *testing.T) {
+	store := httplib.NewMemorySessionStore()
+	ctx, cancel := context.WithCancel(t.Context())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		store.RunSweeper(ctx, time.Millisecond)
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunSweeper did not return after the context was canceled")
+	}
+}
@@ -0,0 +1,313 @@
+package httplib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// staticImmutablePolicy is the cache policy of fingerprinted assets, which never change.
+	staticImmutablePolicy = MustNewCachePolicy(WithCachePublic(), WithCacheMaxAge(365*24*time.Hour), WithCacheImmutable(), WithCacheVary("Accept-Encoding"))
+	// staticRevalidatePolicy is the cache policy of the other files, which may change on deployment.
+	staticRevalidatePolicy = MustNewCachePolicy(WithCacheNoCache(), WithCacheVary("Accept-Encoding"))
+)
+
+// StaticOption configures NewStaticHandler.
+type StaticOption func(*staticConfig)
+
+type staticConfig struct {
+	fallback    string
+	fingerprint func(name string) bool
+}
+
+// WithStaticFallback serves the file of the name, such as "index.html", for paths that do not exist,
+// so that a single-page application can handle client-side routes.
+//
+// The fallback is used only for GET and HEAD requests to paths without an extension,
+// so that missing assets such as "/app.js" are still reported as 404 Not Found.
+func WithStaticFallback(name string) StaticOption {
+	return func(cfg *staticConfig) {
+		cfg.fallback = name
+	}
+}
+
+// WithStaticFingerprint replaces the function that reports whether the file of the name is fingerprinted,
+// that is, its name contains a hash of its content. Fingerprinted files are served with
+// "Cache-Control: public, max-age=31536000, immutable", and the others with "Cache-Control: no-cache".
+//
+// By default, IsFingerprintedName is used. If fingerprint is nil, no file is treated as fingerprinted.
+func WithStaticFingerprint(fingerprint func(name string) bool) StaticOption {
+	return func(cfg *staticConfig) {
+		cfg.fingerprint = fingerprint
+	}
+}
+
+// IsFingerprintedName reports whether the file name has a fingerprint generated by bundlers such as webpack or Vite,
+// like "main.3f2a9c1b.js" or "index-BX3f9_aZ.js": the last part of the name before the extension,
+// separated by '.' or '-', has at least 8 alphanumeric or '_' characters including a digit.
+func IsFingerprintedName(name string) bool {
+	base := path.Base(name)
+	base = strings.TrimSuffix(base, path.Ext(base))
+
+	hash := base[strings.LastIndexAny(base, ".-")+1:]
+	if len(hash) < 8 || len(hash) == len(base) {
+		return false
+	}
+
+	hasDigit := false
+	for _, c := range []byte(hash) {
+		switch {
+		case '0' <= c && c <= '9':
+			hasDigit = true
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', c == '_':
+		default:
+			return false
+		}
+	}
+	return hasDigit
+}
+
+// NewStaticHandler returns an http.Handler that serves the files in fsys, such as an embed.FS.
+//
+// The handler serves:
+//   - The file of the request path, with the Content-Type by its extension, and Content-Length, Last-Modified, and ETag
+//   - The "index.html" file of a directory. Directories are never listed, and are reported as 404 Not Found otherwise
+//   - The precompressed sibling of the file with the ".gz" suffix, if any, with "Content-Encoding: gzip"
+//     when the Accept-Encoding header of the request allows gzip
+//
+// The ETag is the hash of the content of the file, which is computed when the file is served for the first time,
+// so that files without modification times, such as the ones of an embed.FS, can also be revalidated.
+// Conditional requests with the If-None-Match or If-Modified-Since header are answered with 304 Not Modified
+// if the file has not been changed.
+//
+// Paths containing a segment starting with '.', such as "/.env" or "/.git/config", are reported as 404 Not Found.
+// Requests with methods other than GET and HEAD are reported as 405 Method Not Allowed.
+// Every response is rendered by the Render functions of this package, so that it is recorded in ResponseLog.
+//
+// Use http.StripPrefix to serve the files under a path prefix.
+func NewStaticHandler(fsys fs.FS, opts ...StaticOption) http.Handler {
+	cfg := staticConfig{fingerprint: IsFingerprintedName}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	return &staticHandler{fsys: fsys, cfg: cfg}
+}
+
+type staticHandler struct {
+	fsys  fs.FS
+	cfg   staticConfig
+	etags sync.Map // staticETagKey -> string
+}
+
+// staticETagKey identifies a version of a file, so that the ETag is computed again when the file is changed.
+type staticETagKey struct {
+	name    string
+	size    int64
+	modTime int64 // in Unix nanoseconds
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := WithRequestMethod(r.Context(), r.Method)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		RenderMethodNotAllowed(ctx, w, nil, http.MethodGet, http.MethodHead)
+		return
+	}
+
+	name, ok := staticFileName(r.URL.Path)
+	if !ok {
+		RenderNotFound(ctx, w, &fs.PathError{Op: "open", Path: r.URL.Path, Err: fs.ErrNotExist})
+		return
+	}
+
+	renderer, modTime, err := h.open(w, r, name)
+	if errors.Is(err, ErrIsDirectory) {
+		renderer, modTime, err = h.open(w, r, path.Join(name, "index.html"))
+	}
+	if errors.Is(err, fs.ErrNotExist) && h.cfg.fallback != "" && path.Ext(name) == "" {
+		renderer, modTime, err = h.open(w, r, h.cfg.fallback)
+	}
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, ErrIsDirectory):
+		RenderNotFound(ctx, w, err)
+		return
+	case err != nil:
+		RenderInternalServerError(ctx, w, err)
+		return
+	}
+
+	if notModified(r, w.Header().Get("ETag"), modTime) {
+		_ = renderHead(ctx, renderer) // only closes the file, since the body is not sent
+		w.Header().Del("Content-Encoding")
+		RenderNotModified(ctx, w)
+		return
+	}
+
+	// The error can only be the failure of writing the body to the client, which cannot be reported anymore.
+	_ = RenderOKWithBody(ctx, w, renderer)
+}
+
+// open returns the renderer of the file, preferring its precompressed sibling, and sets the headers for caching.
+// It also returns the modification time of the file, which is zero if unknown.
+func (h *staticHandler) open(w http.ResponseWriter, r *http.Request, name string) (ResponseBodyRenderer, time.Time, error) {
+	var renderer ResponseBodyRenderer
+	var err error
+
+	encoded := false
+	if acceptsEncoding(r.Header.Values("Accept-Encoding"), "gzip") {
+		renderer, err = FileResponse(h.fsys, name+".gz", WithFileContentType(contentTypeByExtension(name)))
+		encoded = err == nil
+	}
+
+	opened := name
+	if encoded {
+		opened = name + ".gz"
+	} else {
+		renderer, err = FileResponse(h.fsys, name)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	info, err := fs.Stat(h.fsys, opened)
+	if err != nil {
+		_ = renderHead(r.Context(), renderer)
+		return nil, time.Time{}, err
+	}
+
+	etag, err := h.etag(opened, info)
+	if err != nil {
+		_ = renderHead(r.Context(), renderer)
+		return nil, time.Time{}, err
+	}
+
+	w.Header().Set("ETag", etag)
+	if encoded {
+		w.Header().Set("Content-Encoding", "gzip")
+	}
+
+	if h.cfg.fingerprint != nil && h.cfg.fingerprint(name) {
+		staticImmutablePolicy.Apply(w.Header())
+	} else {
+		staticRevalidatePolicy.Apply(w.Header())
+	}
+
+	return renderer, info.ModTime(), nil
+}
+
+// etag returns the strong ETag of the file, which is the hash of its content.
+// The ETag is cached for the name, size, and modification time of the file.
+func (h *staticHandler) etag(name string, info fs.FileInfo) (string, error) {
+	key := staticETagKey{name: name, size: info.Size(), modTime: info.ModTime().UnixNano()}
+	if etag, ok := h.etags.Load(key); ok {
+		return etag.(string), nil
+	}
+
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	h.etags.Store(key, etag)
+	return etag, nil
+}
+
+// notModified reports whether the conditional request can be answered with 304 Not Modified, as RFC 9110 Section 13.2.2.
+// If-Modified-Since is evaluated only if the request has no If-None-Match header.
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if values := r.Header.Values("If-None-Match"); len(values) != 0 {
+		for _, value := range values {
+			for _, tag := range splitHeaderList(value) {
+				// The weak comparison is used, as RFC 9110 Section 13.1.2 requires for If-None-Match.
+				if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if modTime.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// Last-Modified has a precision of a second.
+	return !modTime.Truncate(time.Second).After(since)
+}
+
+// staticFileName converts the URL path to the name in fs.FS. It returns false if the path contains a dotfile.
+func staticFileName(urlPath string) (string, bool) {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		return ".", true
+	}
+
+	for segment := range strings.SplitSeq(name, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+func contentTypeByExtension(name string) ContentType {
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		return contentType
+	}
+	return ContentTypeOctetStream
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header values allow the content coding.
+func acceptsEncoding(values []string, coding string) bool {
+	accepted := false
+	for _, value := range values {
+		for _, element := range splitHeaderList(value) {
+			name, params, _ := strings.Cut(element, ";")
+			name = strings.TrimSpace(name)
+			if !strings.EqualFold(name, coding) && name != "*" {
+				continue
+			}
+
+			quality := 1.0
+			for param := range strings.SplitSeq(params, ";") {
+				key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(key, "q") {
+					if q, err := strconv.ParseFloat(value, 64); err == nil {
+						quality = q
+					}
+				}
+			}
+
+			if strings.EqualFold(name, coding) {
+				return quality > 0 // an explicit coding takes precedence over "*"
+			}
+			accepted = quality > 0
+		}
+	}
+	return accepted
+}
@@ -0,0 +1,344 @@
+package httplib_test
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStaticFS() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":                {Data: []byte("<html>index</html>")},
+		"assets/app.3f2a9c1b.js":    {Data: []byte("console.log(1)")},
+		"assets/app.3f2a9c1b.js.gz": {Data: []byte("gzipped js")},
+		"assets/style.css":          {Data: []byte("body{}")},
+		"docs/index.html":           {Data: []byte("<html>docs</html>")},
+		"empty/a.txt":               {Data: []byte("a")},
+		".env":                      {Data: []byte("SECRET=1")},
+		"assets/.hidden/a.js":       {Data: []byte("hidden")},
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	tests := []struct {
+		name                string
+		opts                []httplib.StaticOption
+		method              string
+		path                string
+		acceptEncoding      string
+		wantStatusCode      int
+		wantBody            string
+		wantContentType     string
+		wantContentEncoding string
+		wantCacheControl    string
+	}{
+		{
+			name:             "root index",
+			path:             "/",
+			wantStatusCode:   http.StatusOK,
+			wantBody:         "<html>index</html>",
+			wantContentType:  httplib.ContentTypeHTMLUTF8,
+			wantCacheControl: "no-cache",
+		},
+		{
+			name:             "directory index",
+			path:             "/docs/",
+			wantStatusCode:   http.StatusOK,
+			wantBody:         "<html>docs</html>",
+			wantContentType:  httplib.ContentTypeHTMLUTF8,
+			wantCacheControl: "no-cache",
+		},
+		{
+			name:             "fingerprinted asset",
+			path:             "/assets/app.3f2a9c1b.js",
+			wantStatusCode:   http.StatusOK,
+			wantBody:         "console.log(1)",
+			wantContentType:  "text/javascript; charset=utf-8",
+			wantCacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			name:                "precompressed asset",
+			path:                "/assets/app.3f2a9c1b.js",
+			acceptEncoding:      "br, gzip;q=0.8",
+			wantStatusCode:      http.StatusOK,
+			wantBody:            "gzipped js",
+			wantContentType:     "text/javascript; charset=utf-8",
+			wantContentEncoding: "gzip",
+			wantCacheControl:    "public, max-age=31536000, immutable",
+		},
+		{
+			name:             "gzip refused",
+			path:             "/assets/app.3f2a9c1b.js",
+			acceptEncoding:   "*, gzip;q=0",
+			wantStatusCode:   http.StatusOK,
+			wantBody:         "console.log(1)",
+			wantContentType:  "text/javascript; charset=utf-8",
+			wantCacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			name:             "no precompressed sibling",
+			path:             "/assets/style.css",
+			acceptEncoding:   "gzip",
+			wantStatusCode:   http.StatusOK,
+			wantBody:         "body{}",
+			wantContentType:  "text/css; charset=utf-8",
+			wantCacheControl: "no-cache",
+		},
+		{
+			name:             "custom fingerprint",
+			opts:             []httplib.StaticOption{httplib.WithStaticFingerprint(nil)},
+			path:             "/assets/app.3f2a9c1b.js",
+			wantStatusCode:   http.StatusOK,
+			wantBody:         "console.log(1)",
+			wantContentType:  "text/javascript; charset=utf-8",
+			wantCacheControl: "no-cache",
+		},
+		{
+			name:             "fallback for client-side route",
+			opts:             []httplib.StaticOption{httplib.WithStaticFallback("index.html")},
+			path:             "/users/1",
+			wantStatusCode:   http.StatusOK,
+			wantBody:         "<html>index</html>",
+			wantContentType:  httplib.ContentTypeHTMLUTF8,
+			wantCacheControl: "no-cache",
+		},
+		{
+			name:           "no fallback for missing asset",
+			opts:           []httplib.StaticOption{httplib.WithStaticFallback("index.html")},
+			path:           "/assets/missing.js",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "not found",
+			path:           "/users/1",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "directory without index",
+			path:           "/empty/",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "dotfile",
+			opts:           []httplib.StaticOption{httplib.WithStaticFallback("index.html")},
+			path:           "/.env",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "file in dot directory",
+			path:           "/assets/.hidden/a.js",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "path traversal",
+			path:           "/assets/../../.env",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "method not allowed",
+			method:         http.MethodPost,
+			path:           "/",
+			wantStatusCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			var res httplib.ResponseLog
+			r := httptest.NewRequest(method, "/", nil)
+			r.URL.Path = tt.path
+			r = r.WithContext(httplib.WithResponseLogPtr(r.Context(), &res))
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+
+			w := httptest.NewRecorder()
+			httplib.NewStaticHandler(newStaticFS(), tt.opts...).ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantStatusCode, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantContentEncoding, w.Header().Get("Content-Encoding"))
+			assert.Equal(t, tt.wantCacheControl, w.Header().Get("Cache-Control"))
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+			assert.EqualValues(t, len(tt.wantBody), res.ResponseSize)
+			if tt.wantStatusCode == http.StatusOK {
+				assert.Equal(t, strconv.Itoa(len(tt.wantBody)), w.Header().Get("Content-Length"))
+				assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+				assert.NotEmpty(t, w.Header().Get("ETag"))
+				assert.NoError(t, res.Error)
+			} else {
+				assert.Empty(t, w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestStaticHandler_ConditionalRequest(t *testing.T) {
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"style.css":    {Data: []byte("body{}"), ModTime: modTime.Add(500 * time.Millisecond)},
+		"style.css.gz": {Data: []byte("gzipped css"), ModTime: modTime},
+		"app.js":       {Data: []byte("console.log(1)")},
+	}
+	handler := httplib.NewStaticHandler(fsys)
+
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		maps.Copy(r.Header, header)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	etag := serve("/style.css", nil).Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	gzipETag := serve("/style.css", http.Header{"Accept-Encoding": {"gzip"}}).Header().Get("ETag")
+	require.NotEmpty(t, gzipETag)
+	assert.NotEqual(t, etag, gzipETag)
+
+	tests := []struct {
+		name           string
+		path           string
+		header         http.Header
+		wantStatusCode int
+	}{
+		{
+			name:           "matching If-None-Match",
+			path:           "/style.css",
+			header:         http.Header{"If-None-Match": {`"other", ` + etag}},
+			wantStatusCode: http.StatusNotModified,
+		},
+		{
+			name:           "weak If-None-Match",
+			path:           "/style.css",
+			header:         http.Header{"If-None-Match": {"W/" + etag}},
+			wantStatusCode: http.StatusNotModified,
+		},
+		{
+			name:           "If-None-Match of any",
+			path:           "/style.css",
+			header:         http.Header{"If-None-Match": {"*"}},
+			wantStatusCode: http.StatusNotModified,
+		},
+		{
+			name:           "If-None-Match of precompressed sibling",
+			path:           "/style.css",
+			header:         http.Header{"If-None-Match": {gzipETag}, "Accept-Encoding": {"gzip"}},
+			wantStatusCode: http.StatusNotModified,
+		},
+		{
+			name:           "If-None-Match of another encoding",
+			path:           "/style.css",
+			header:         http.Header{"If-None-Match": {etag}, "Accept-Encoding": {"gzip"}},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "If-None-Match takes precedence over If-Modified-Since",
+			path:           "/style.css",
+			header:         http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {modTime.Format(http.TimeFormat)}},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "not modified since",
+			path:           "/style.css",
+			header:         http.Header{"If-Modified-Since": {modTime.Format(http.TimeFormat)}},
+			wantStatusCode: http.StatusNotModified,
+		},
+		{
+			name:           "modified since",
+			path:           "/style.css",
+			header:         http.Header{"If-Modified-Since": {modTime.Add(-time.Second).Format(http.TimeFormat)}},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "If-Modified-Since without modification time",
+			path:           "/app.js",
+			header:         http.Header{"If-Modified-Since": {modTime.Format(http.TimeFormat)}},
+			wantStatusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.path, tt.header)
+
+			assert.Equal(t, tt.wantStatusCode, w.Code)
+			assert.NotEmpty(t, w.Header().Get("ETag"))
+			assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+			if tt.wantStatusCode == http.StatusNotModified {
+				assert.Empty(t, w.Body.String())
+				assert.Empty(t, w.Header().Get("Content-Type"))
+				assert.Empty(t, w.Header().Get("Content-Length"))
+				assert.Empty(t, w.Header().Get("Content-Encoding"))
+			}
+		})
+	}
+
+	t.Run("changed file", func(t *testing.T) {
+		fsys["style.css"] = &fstest.MapFile{Data: []byte("body{color:red}"), ModTime: modTime.Add(time.Hour)}
+
+		w := serve("/style.css", http.Header{"If-None-Match": {etag}})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "body{color:red}", w.Body.String())
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	})
+}
+
+func TestStaticHandler_Head(t *testing.T) {
+	var res httplib.ResponseLog
+	r := httptest.NewRequest(http.MethodHead, "/assets/style.css", nil)
+	r = r.WithContext(httplib.WithResponseLogPtr(r.Context(), &res))
+
+	w := httptest.NewRecorder()
+	httplib.NewStaticHandler(newStaticFS()).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "6", w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+	assert.True(t, res.BodyOmitted)
+	assert.Zero(t, res.ResponseSize)
+}
+
+func TestStaticHandler_MethodNotAllowed(t *testing.T) {
+	w := httptest.NewRecorder()
+	httplib.NewStaticHandler(newStaticFS()).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/", nil))
+
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+}
+
+func TestIsFingerprintedName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "main.3f2a9c1b.js", want: true},
+		{name: "assets/index-BX3f9_aZ.js", want: true},
+		{name: "chunk.0123456789abcdef.css", want: true},
+		{name: "index.html", want: false},
+		{name: "index-Dashboard.js", want: false},
+		{name: "app.3f2a9c.js", want: false},
+		{name: "12345678.js", want: false},
+		{name: "main.3f2a-9c1b.js", want: false},
+		{name: "main.3f2a9c1b!.js", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, httplib.IsFingerprintedName(tt.name))
+		})
+	}
+}
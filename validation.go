@@ -0,0 +1,700 @@
+package httplib
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Validator is implemented by types that have custom validation rules.
+//
+// Validate calls the Validate method after checking the rules of the "validate" struct tags of the value.
+// If the method returns ValidationErrors or *ValidationError, their paths are treated as relative to the value.
+// Any other error is reported as a violation of the rule "custom" at the path of the value.
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError is a violation of a validation rule.
+type ValidationError struct {
+	// Path is the JSON Pointer (RFC 6901) to the invalid value, such as "/items/0/name".
+	//
+	// It is built from the names in the "json" struct tags, or the field names if the tag is absent.
+	// For JSONSchema, it is the location in the validated JSON document.
+	// An empty string means the whole value.
+	Path string
+
+	// Rule is the name of the violated rule, such as "required" or "max".
+	// For JSONSchema, it is the violated keyword, such as "minLength".
+	Rule string
+
+	// Param is the parameter of the rule, such as "10" for "max=10". It is empty if the rule has no parameter.
+	Param string
+
+	// Message is a human-readable description of the violation.
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// MarshalJSON encodes the ValidationError as a JSON object with "path", "rule", "param" and "message",
+// so that ValidationErrors can be rendered by JSONResponse. An empty "param" is omitted.
+func (e *ValidationError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path    string `json:"path"`
+		Rule    string `json:"rule"`
+		Param   string `json:"param,omitempty"`
+		Message string `json:"message"`
+	}{
+		Path:    e.Path,
+		Rule:    e.Rule,
+		Param:   e.Param,
+		Message: e.Message,
+	})
+}
+
+// ValidationErrors is a list of ValidationError returned by Validate.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Validate checks v against the rules in its "validate" struct tags and the Validator implementations.
+//
+// Nested structs are validated recursively, including structs in pointers, slices, arrays and maps.
+// The tag value is a comma-separated list of rules:
+//   - required: the value must not be the zero value, nil, or empty
+//   - omitempty: the other rules are skipped if the value is the zero value, nil, or empty
+//   - min=N, max=N, len=N: the length of strings (in runes), slices, arrays and maps, or the value of numbers
+//   - oneof=A B C: the value must be one of the space-separated values
+//   - email, url, uuid: the string must be in the format
+//   - eqfield=F, nefield=F, gtfield=F, gtefield=F, ltfield=F, ltefield=F: compares the value with the field F
+//     of the same struct (numbers, strings and time.Time)
+//   - dive: the rules after it are applied to each element of slices and arrays, or each value of maps
+//   - pattern=RE: the string must match the regular expression. Since RE may contain commas,
+//     the rest of the tag is used as RE, so this must be the last rule.
+//
+// For example:
+//
+//	type CreateUserRequest struct {
+//		Name     string   `json:"name" validate:"required,max=50"`
+//		Email    string   `json:"email" validate:"required,email"`
+//		Role     string   `json:"role" validate:"oneof=admin member"`
+//		Tags     []string `json:"tags" validate:"max=5,dive,min=1,pattern=^[a-z]+$"`
+//		Password string   `json:"password" validate:"min=8"`
+//		Confirm  string   `json:"confirm" validate:"eqfield=Password"`
+//	}
+//
+// Pointers are dereferenced before the rules other than required and omitempty are checked,
+// and nil pointers are skipped by them.
+//
+// Every violation is reported together as ValidationErrors, which can be rendered with JSONResponse
+// and RenderUnprocessableEntityWithBody. If a tag is invalid, Validate returns an error that is not ValidationErrors.
+func Validate(v any) error {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil
+	}
+
+	if rv.Kind() != reflect.Pointer { // make it addressable to call Validator with a pointer receiver
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		rv = ptr
+	}
+
+	vd := &validator{}
+	if err := vd.validateValue(rv, ""); err != nil {
+		return err
+	}
+
+	if len(vd.errs) != 0 {
+		return vd.errs
+	}
+
+	return nil
+}
+
+var validatorType = reflect.TypeFor[Validator]()
+
+type validator struct {
+	errs ValidationErrors
+}
+
+func (vd *validator) add(path string, rule string, param string, message string) {
+	vd.errs = append(vd.errs, &ValidationError{Path: path, Rule: rule, Param: param, Message: message})
+}
+
+// validateValue validates nested structs in v, and calls Validator implemented by v.
+func (vd *validator) validateValue(v reflect.Value, path string) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if err := vd.validateStruct(v, path); err != nil {
+			return err
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if err := vd.validateValue(v.Index(i), path+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range sortedMapKeys(v) {
+			if err := vd.validateValue(v.MapIndex(key), path+"/"+escapeJSONPointer(fmt.Sprint(key.Interface()))); err != nil {
+				return err
+			}
+		}
+	default:
+	}
+
+	vd.callValidator(v, path)
+	return nil
+}
+
+func (vd *validator) callValidator(v reflect.Value, path string) {
+	var custom Validator
+
+	switch {
+	case v.CanAddr() && v.Addr().Type().Implements(validatorType):
+		custom = v.Addr().Interface().(Validator)
+	case v.Type().Implements(validatorType) && v.CanInterface():
+		custom = v.Interface().(Validator)
+	default:
+		return
+	}
+
+	err := custom.Validate()
+	if err == nil {
+		return
+	}
+
+	var validationErrs ValidationErrors
+	var validationErr *ValidationError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, e := range validationErrs {
+			vd.add(path+e.Path, e.Rule, e.Param, e.Message)
+		}
+	case errors.As(err, &validationErr):
+		vd.add(path+validationErr.Path, validationErr.Rule, validationErr.Param, validationErr.Message)
+	default:
+		vd.add(path, "custom", "", err.Error())
+	}
+}
+
+func (vd *validator) validateStruct(v reflect.Value, path string) error {
+	if v.Type() == timeType {
+		return nil
+	}
+
+	fields, err := getValidationFields(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		fv := v.Field(field.index)
+		fieldPath := path
+		if field.name != "" {
+			fieldPath += "/" + escapeJSONPointer(field.name)
+		}
+
+		vd.applyRules(fv, v, fieldPath, field.rules)
+
+		if err := vd.validateValue(fv, fieldPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (vd *validator) applyRules(v reflect.Value, parent reflect.Value, path string, rules *ruleSet) {
+	if rules == nil {
+		return
+	}
+
+	if rules.omitempty && isEmptyValue(v) {
+		return
+	}
+
+	if rules.required && isEmptyValue(v) {
+		vd.add(path, "required", "", "is required")
+		return
+	}
+
+	v = indirectValue(v)
+	if !v.IsValid() {
+		return
+	}
+
+	for _, r := range rules.rules {
+		if message, ok := r.check(v, parent); !ok {
+			vd.add(path, r.name, r.param, message)
+		}
+	}
+
+	if rules.dive == nil {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			vd.applyRules(v.Index(i), parent, path+"/"+strconv.Itoa(i), rules.dive)
+		}
+	case reflect.Map:
+		for _, key := range sortedMapKeys(v) {
+			vd.applyRules(v.MapIndex(key), parent, path+"/"+escapeJSONPointer(fmt.Sprint(key.Interface())), rules.dive)
+		}
+	default:
+	}
+}
+
+// sortedMapKeys returns the keys of the map sorted by their formatted values,
+// so that the errors of the map values are reported in a deterministic order.
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+	})
+	return keys
+}
+
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escapeJSONPointer(s string) string {
+	return jsonPointerEscaper.Replace(s)
+}
+
+// validationField is a struct field and its validation rules.
+type validationField struct {
+	index int
+	name  string
+	rules *ruleSet
+}
+
+// ruleSet is the parsed rules of a "validate" struct tag.
+type ruleSet struct {
+	required  bool
+	omitempty bool
+	rules     []rule
+	dive      *ruleSet
+}
+
+type rule struct {
+	name  string
+	param string
+
+	// check reports whether v satisfies the rule. v is not a pointer, and parent is the struct that has the field.
+	check func(v reflect.Value, parent reflect.Value) (message string, ok bool)
+}
+
+var validationFieldsCache sync.Map // reflect.Type -> []validationField
+
+func getValidationFields(t reflect.Type) ([]validationField, error) {
+	if cached, ok := validationFieldsCache.Load(t); ok {
+		return cached.([]validationField), nil
+	}
+
+	var fields []validationField
+
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		field := validationField{
+			index: i,
+			name:  validationFieldName(sf),
+		}
+
+		if tag := sf.Tag.Get("validate"); tag != "" {
+			rules, err := parseRuleSet(tag, sf.Type, t)
+			if err != nil {
+				return nil, fmt.Errorf("httplib: invalid validate tag of field %s: %w", sf.Name, err)
+			}
+			field.rules = rules
+		}
+
+		fields = append(fields, field)
+	}
+
+	validationFieldsCache.Store(t, fields)
+	return fields, nil
+}
+
+// validationFieldName returns the name of the field in JSON Pointer paths.
+//
+// Fields tagged with "body" (see Bind) and untagged embedded structs are treated as the parent itself.
+func validationFieldName(sf reflect.StructField) string {
+	if _, ok := sf.Tag.Lookup("body"); ok {
+		return ""
+	}
+
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+
+	if sf.Anonymous {
+		return ""
+	}
+
+	return sf.Name
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func parseRuleSet(tag string, fieldType reflect.Type, parentType reflect.Type) (*ruleSet, error) {
+	rules := &ruleSet{}
+	t := derefType(fieldType)
+
+	for tag != "" {
+		var item string
+		if strings.HasPrefix(tag, "pattern=") {
+			item, tag = tag, ""
+		} else {
+			item, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, param, _ := strings.Cut(item, "=")
+
+		switch name {
+		case "":
+			continue
+		case "required":
+			rules.required = true
+			continue
+		case "omitempty":
+			rules.omitempty = true
+			continue
+		case "dive":
+			if t.Kind() != reflect.Slice && t.Kind() != reflect.Array && t.Kind() != reflect.Map {
+				return nil, fmt.Errorf("dive cannot be used for %s", t)
+			}
+
+			dive, err := parseRuleSet(tag, t.Elem(), parentType)
+			if err != nil {
+				return nil, err
+			}
+			rules.dive = dive
+			return rules, nil
+		}
+
+		r, err := newRule(name, param, t, parentType)
+		if err != nil {
+			return nil, err
+		}
+		rules.rules = append(rules.rules, r)
+	}
+
+	return rules, nil
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func newRule(name string, param string, t reflect.Type, parentType reflect.Type) (rule, error) {
+	r := rule{name: name, param: param}
+
+	switch name {
+	case "min", "max", "len":
+		check, err := newSizeCheck(name, param, t)
+		if err != nil {
+			return rule{}, err
+		}
+		r.check = check
+	case "oneof":
+		check, err := newOneOfCheck(param, t)
+		if err != nil {
+			return rule{}, err
+		}
+		r.check = check
+	case "pattern":
+		if t.Kind() != reflect.String {
+			return rule{}, fmt.Errorf("%s cannot be used for %s", name, t)
+		}
+
+		re, err := regexp.Compile(param)
+		if err != nil {
+			return rule{}, err
+		}
+
+		message := fmt.Sprintf("must match the pattern %q", param)
+		r.check = func(v reflect.Value, _ reflect.Value) (string, bool) {
+			return message, re.MatchString(v.String())
+		}
+	case "email", "url", "uuid":
+		if t.Kind() != reflect.String {
+			return rule{}, fmt.Errorf("%s cannot be used for %s", name, t)
+		}
+		r.check = newFormatCheck(name)
+	case "eqfield", "nefield", "gtfield", "gtefield", "ltfield", "ltefield":
+		check, err := newFieldCompareCheck(name, param, t, parentType)
+		if err != nil {
+			return rule{}, err
+		}
+		r.check = check
+	default:
+		return rule{}, fmt.Errorf("unknown rule %q", name)
+	}
+
+	return r, nil
+}
+
+func newSizeCheck(name string, param string, t reflect.Type) (func(v reflect.Value, _ reflect.Value) (string, bool), error) {
+	var unit string
+	var size func(v reflect.Value) float64
+
+	switch t.Kind() {
+	case reflect.String:
+		unit = "characters"
+		size = func(v reflect.Value) float64 { return float64(utf8.RuneCountInString(v.String())) }
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = "items"
+		size = func(v reflect.Value) float64 { return float64(v.Len()) }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = func(v reflect.Value) float64 { return float64(v.Int()) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = func(v reflect.Value) float64 { return float64(v.Uint()) }
+	case reflect.Float32, reflect.Float64:
+		size = func(v reflect.Value) float64 { return v.Float() }
+	default:
+		return nil, fmt.Errorf("%s cannot be used for %s", name, t)
+	}
+
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameter of %s: %q", name, param)
+	}
+
+	var message string
+	var ok func(n float64) bool
+
+	switch name {
+	case "min":
+		ok = func(n float64) bool { return n >= limit }
+		if unit == "" {
+			message = "must be greater than or equal to " + param
+		} else {
+			message = fmt.Sprintf("must have at least %s %s", param, unit)
+		}
+	case "max":
+		ok = func(n float64) bool { return n <= limit }
+		if unit == "" {
+			message = "must be less than or equal to " + param
+		} else {
+			message = fmt.Sprintf("must have at most %s %s", param, unit)
+		}
+	default: // len
+		ok = func(n float64) bool { return n == limit }
+		if unit == "" {
+			message = "must be " + param
+		} else {
+			message = fmt.Sprintf("must have exactly %s %s", param, unit)
+		}
+	}
+
+	return func(v reflect.Value, _ reflect.Value) (string, bool) {
+		return message, ok(size(v))
+	}, nil
+}
+
+func newOneOfCheck(param string, t reflect.Type) (func(v reflect.Value, _ reflect.Value) (string, bool), error) {
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return nil, fmt.Errorf("oneof cannot be used for %s", t)
+	}
+
+	values := strings.Fields(param)
+	if len(values) == 0 {
+		return nil, errors.New("oneof requires at least one value")
+	}
+
+	// The values are compared by the underlying kind, so that types implementing fmt.Stringer,
+	// such as enums, are compared by their values rather than their names.
+	var contains func(v reflect.Value) bool
+	switch t.Kind() {
+	case reflect.String:
+		contains = func(v reflect.Value) bool { return slices.Contains(values, v.String()) }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		allowed := make([]int64, len(values))
+		for i, value := range values {
+			n, err := strconv.ParseInt(value, 10, t.Bits())
+			if err != nil {
+				return nil, fmt.Errorf("invalid parameter of oneof: %q", value)
+			}
+			allowed[i] = n
+		}
+		contains = func(v reflect.Value) bool { return slices.Contains(allowed, v.Int()) }
+	default: // unsigned integers
+		allowed := make([]uint64, len(values))
+		for i, value := range values {
+			n, err := strconv.ParseUint(value, 10, t.Bits())
+			if err != nil {
+				return nil, fmt.Errorf("invalid parameter of oneof: %q", value)
+			}
+			allowed[i] = n
+		}
+		contains = func(v reflect.Value) bool { return slices.Contains(allowed, v.Uint()) }
+	}
+
+	// The values are listed in the order of the tag.
+	message := "must be one of " + strings.Join(values, ", ")
+	return func(v reflect.Value, _ reflect.Value) (string, bool) {
+		return message, contains(v)
+	}, nil
+}
+
+func newFormatCheck(name string) func(v reflect.Value, _ reflect.Value) (string, bool) {
+	switch name {
+	case "email":
+		return func(v reflect.Value, _ reflect.Value) (string, bool) {
+			addr, err := mail.ParseAddress(v.String())
+			return "must be a valid email address", err == nil && addr.Address == v.String()
+		}
+	case "url":
+		return func(v reflect.Value, _ reflect.Value) (string, bool) {
+			u, err := url.Parse(v.String())
+			return "must be a valid URL", err == nil && u.Scheme != "" && u.Host != ""
+		}
+	default: // uuid
+		return func(v reflect.Value, _ reflect.Value) (string, bool) {
+			return "must be a valid UUID", uuidPattern.MatchString(v.String())
+		}
+	}
+}
+
+func newFieldCompareCheck(name string, param string, t reflect.Type, parentType reflect.Type) (func(v reflect.Value, parent reflect.Value) (string, bool), error) {
+	other, ok := parentType.FieldByName(param)
+	if !ok || len(other.Index) != 1 {
+		return nil, fmt.Errorf("%s refers to unknown field %q", name, param)
+	}
+
+	if derefType(other.Type) != t {
+		return nil, fmt.Errorf("%s refers to field %q of different type %s", name, param, other.Type)
+	}
+
+	if _, ok := compareValues(reflect.Zero(t), reflect.Zero(t)); !ok {
+		return nil, fmt.Errorf("%s cannot be used for %s", name, t)
+	}
+
+	otherName := validationFieldName(other)
+	if otherName == "" {
+		otherName = other.Name
+	}
+
+	var message string
+	var satisfied func(result int) bool
+
+	switch name {
+	case "eqfield":
+		message, satisfied = "must be equal to "+otherName, func(result int) bool { return result == 0 }
+	case "nefield":
+		message, satisfied = "must not be equal to "+otherName, func(result int) bool { return result != 0 }
+	case "gtfield":
+		message, satisfied = "must be greater than "+otherName, func(result int) bool { return result > 0 }
+	case "gtefield":
+		message, satisfied = "must be greater than or equal to "+otherName, func(result int) bool { return result >= 0 }
+	case "ltfield":
+		message, satisfied = "must be less than "+otherName, func(result int) bool { return result < 0 }
+	default: // ltefield
+		message, satisfied = "must be less than or equal to "+otherName, func(result int) bool { return result <= 0 }
+	}
+
+	index := other.Index[0]
+	return func(v reflect.Value, parent reflect.Value) (string, bool) {
+		otherValue := indirectValue(parent.Field(index))
+		if !otherValue.IsValid() {
+			return message, true // nothing to compare with
+		}
+
+		result, _ := compareValues(v, otherValue)
+		return message, satisfied(result)
+	}, nil
+}
+
+// compareValues compares a and b of the same type, and reports whether the type is comparable.
+func compareValues(a reflect.Value, b reflect.Value) (int, bool) {
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time)), true
+	}
+
+	switch a.Kind() {
+	case reflect.String:
+		return strings.Compare(a.String(), b.String()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float()), true
+	default:
+		return 0, false
+	}
+}
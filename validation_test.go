@@ -0,0 +1,414 @@
+package httplib_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Siroshun09/go-httplib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validationAddress struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"omitempty,len=7,pattern=^[0-9]+$"`
+}
+
+type validationUser struct {
+	Name     string                       `json:"name" validate:"required,max=5"`
+	Email    string                       `json:"email" validate:"omitempty,email"`
+	Website  string                       `json:"website" validate:"omitempty,url"`
+	ID       string                       `json:"id" validate:"omitempty,uuid"`
+	Role     string                       `json:"role" validate:"oneof=admin member"`
+	Age      *int                         `json:"age" validate:"omitempty,min=0,max=150"`
+	Level    int                          `json:"level" validate:"oneof=1 2 3"`
+	Tags     []string                     `json:"tags" validate:"max=2,dive,min=1,pattern=^[a-z,]+$"`
+	Scores   map[string]float64           `json:"scores" validate:"dive,min=0"`
+	Address  *validationAddress           `json:"address" validate:"required"`
+	Others   []validationAddress          `json:"others"`
+	Named    map[string]validationAddress `json:"named"`
+	Password string                       `json:"password"`
+	Confirm  string                       `json:"confirm" validate:"eqfield=Password"`
+	Start    time.Time                    `json:"start"`
+	End      time.Time                    `json:"end" validate:"omitempty,gtfield=Start"`
+	NoJSON   string                       `validate:"len=1"`
+	Skipped  string                       `json:"-" validate:"required"`
+}
+
+func validUser() validationUser {
+	return validationUser{
+		Name:    "alice",
+		Role:    "admin",
+		Level:   1,
+		Address: &validationAddress{City: "Tokyo"},
+		NoJSON:  "a",
+		Skipped: "a",
+	}
+}
+
+func assertValidationErrorsFunc(expectedPaths ...string) assert.ErrorAssertionFunc {
+	return func(t assert.TestingT, err error, i ...interface{}) bool {
+		var validationErrs httplib.ValidationErrors
+		if !assert.ErrorAs(t, err, &validationErrs) {
+			return false
+		}
+
+		paths := make([]string, len(validationErrs))
+		for i, validationErr := range validationErrs {
+			paths[i] = validationErr.Path
+		}
+		return assert.Equal(t, expectedPaths, paths)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		modify       func(u *validationUser)
+		errAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "success: valid",
+			modify:       func(u *validationUser) {},
+			errAssertion: assert.NoError,
+		},
+		{
+			name: "success: all optional fields are valid",
+			modify: func(u *validationUser) {
+				u.Email = "alice@example.com"
+				u.Website = "https://example.com/path"
+				u.ID = "123e4567-e89b-12d3-a456-426614174000"
+				u.Age = toPtr(20)
+				u.Tags = []string{"a", "b,c"}
+				u.Scores = map[string]float64{"math": 0}
+				u.Address.Zip = "1234567"
+				u.Password, u.Confirm = "secret", "secret"
+				u.Start, u.End = time.Unix(0, 0), time.Unix(1, 0)
+			},
+			errAssertion: assert.NoError,
+		},
+		{
+			name: "failure: required",
+			modify: func(u *validationUser) {
+				u.Name = ""
+				u.Address = nil
+				u.Skipped = ""
+			},
+			errAssertion: assertValidationErrorsFunc("/name", "/address", "/Skipped"),
+		},
+		{
+			name: "failure: formats",
+			modify: func(u *validationUser) {
+				u.Email = "Alice <alice@example.com>"
+				u.Website = "example.com"
+				u.ID = "123e4567"
+			},
+			errAssertion: assertValidationErrorsFunc("/email", "/website", "/id"),
+		},
+		{
+			name: "failure: sizes and values",
+			modify: func(u *validationUser) {
+				u.Name = "alice!"
+				u.Age = toPtr(-1)
+				u.NoJSON = "ab"
+			},
+			errAssertion: assertValidationErrorsFunc("/name", "/age", "/NoJSON"),
+		},
+		{
+			name: "failure: oneof",
+			modify: func(u *validationUser) {
+				u.Role = "guest"
+				u.Level = 4
+			},
+			errAssertion: assertValidationErrorsFunc("/role", "/level"),
+		},
+		{
+			name: "failure: dive",
+			modify: func(u *validationUser) {
+				u.Tags = []string{"a", "", "B"}
+				u.Scores = map[string]float64{"a/b": -1}
+			},
+			errAssertion: assertValidationErrorsFunc("/tags", "/tags/1", "/tags/1", "/tags/2", "/scores/a~1b"),
+		},
+		{
+			name: "failure: nested structs",
+			modify: func(u *validationUser) {
+				u.Address.City = ""
+				u.Address.Zip = "12345ab"
+				u.Others = []validationAddress{{City: "Osaka"}, {}}
+				u.Named = map[string]validationAddress{"home": {}}
+			},
+			errAssertion: assertValidationErrorsFunc("/address/city", "/address/zip", "/others/1/city", "/named/home/city"),
+		},
+		{
+			name: "failure: cross fields",
+			modify: func(u *validationUser) {
+				u.Password, u.Confirm = "secret", "secreT"
+				u.Start, u.End = time.Unix(1, 0), time.Unix(1, 0)
+			},
+			errAssertion: assertValidationErrorsFunc("/confirm", "/end"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := validUser()
+			tt.modify(&u)
+			tt.errAssertion(t, httplib.Validate(u))
+			tt.errAssertion(t, httplib.Validate(&u))
+		})
+	}
+}
+
+func TestValidate_Messages(t *testing.T) {
+	u := validUser()
+	u.Name = ""
+	u.Tags = []string{"a", "b", "C"}
+	u.Level = 0
+	u.Confirm = "x"
+
+	err := httplib.Validate(u)
+	require.Error(t, err)
+
+	data, err := json.Marshal(err)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"path":"/name","rule":"required","message":"is required"},
+		{"path":"/level","rule":"oneof","param":"1 2 3","message":"must be one of 1, 2, 3"},
+		{"path":"/tags","rule":"max","param":"2","message":"must have at most 2 items"},
+		{"path":"/tags/2","rule":"pattern","param":"^[a-z,]+$","message":"must match the pattern \"^[a-z,]+$\""},
+		{"path":"/confirm","rule":"eqfield","param":"Password","message":"must be equal to password"}
+	]`, string(data))
+}
+
+// validationLevel is an enum whose String returns its name, which must not be used by oneof.
+type validationLevel int
+
+func (l validationLevel) String() string {
+	return [...]string{"low", "middle", "high"}[l]
+}
+
+func TestValidate_OneOf(t *testing.T) {
+	type enums struct {
+		Level  validationLevel            `json:"level" validate:"oneof=2 1"`
+		Small  uint8                      `json:"small" validate:"oneof=3 1 2"`
+		Levels map[string]validationLevel `json:"levels" validate:"dive,oneof=2"`
+	}
+
+	err := httplib.Validate(enums{
+		Level:  0,
+		Small:  4,
+		Levels: map[string]validationLevel{"d": 0, "a": 1, "c": 2, "b": 0},
+	})
+
+	for range 10 {
+		again := httplib.Validate(enums{Level: 0, Small: 4, Levels: map[string]validationLevel{"d": 0, "a": 1, "c": 2, "b": 0}})
+		assert.Equal(t, err, again)
+	}
+
+	data, err := json.Marshal(err)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"path":"/level","rule":"oneof","param":"2 1","message":"must be one of 2, 1"},
+		{"path":"/small","rule":"oneof","param":"3 1 2","message":"must be one of 3, 1, 2"},
+		{"path":"/levels/a","rule":"oneof","param":"2","message":"must be one of 2"},
+		{"path":"/levels/b","rule":"oneof","param":"2","message":"must be one of 2"},
+		{"path":"/levels/d","rule":"oneof","param":"2","message":"must be one of 2"}
+	]`, string(data))
+
+	assert.NoError(t, httplib.Validate(enums{Level: 1, Small: 2, Levels: map[string]validationLevel{"a": 2}}))
+}
+
+type customValidated struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+func (c *customValidated) Validate() error {
+	if c.Min > c.Max {
+		return httplib.ValidationErrors{{Path: "/min", Rule: "range", Message: "must not be greater than max"}}
+	}
+	return nil
+}
+
+type customValueValidated string
+
+func (c customValueValidated) Validate() error {
+	if c == "bad" {
+		return errors.New("is bad")
+	}
+	return nil
+}
+
+func TestValidate_Validator(t *testing.T) {
+	type wrapper struct {
+		Range  customValidated        `json:"range"`
+		Ranges []*customValidated     `json:"ranges"`
+		Value  customValueValidated   `json:"value"`
+		Values []customValueValidated `json:"values"`
+	}
+
+	err := httplib.Validate(wrapper{
+		Range:  customValidated{Min: 2, Max: 1},
+		Ranges: []*customValidated{{Min: 0, Max: 1}, {Min: 3, Max: 2}},
+		Value:  "bad",
+		Values: []customValueValidated{"good", "bad"},
+	})
+
+	assertValidationErrorsFunc("/range/min", "/ranges/1/min", "/value", "/values/1")(t, err)
+	assert.EqualError(t, err, "/range/min: must not be greater than max; /ranges/1/min: must not be greater than max; /value: is bad; /values/1: is bad")
+
+	assert.NoError(t, httplib.Validate(wrapper{}))
+	assertValidationErrorsFunc("")(t, httplib.Validate(customValueValidated("bad")))
+}
+
+func TestValidate_NonStruct(t *testing.T) {
+	assert.NoError(t, httplib.Validate(nil))
+	assert.NoError(t, httplib.Validate(1))
+	assert.NoError(t, httplib.Validate([]string{"a"}))
+	assert.NoError(t, httplib.Validate((*validationUser)(nil)))
+}
+
+func TestValidate_InvalidTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		wantErr string
+	}{
+		{
+			name: "unknown rule",
+			value: struct {
+				A string `validate:"unknown"`
+			}{},
+			wantErr: `httplib: invalid validate tag of field A: unknown rule "unknown"`,
+		},
+		{
+			name: "min for bool",
+			value: struct {
+				A bool `validate:"min=1"`
+			}{},
+			wantErr: "httplib: invalid validate tag of field A: min cannot be used for bool",
+		},
+		{
+			name: "invalid number",
+			value: struct {
+				A int `validate:"max=a"`
+			}{},
+			wantErr: `httplib: invalid validate tag of field A: invalid parameter of max: "a"`,
+		},
+		{
+			name: "invalid oneof number",
+			value: struct {
+				A int8 `validate:"oneof=1 high"`
+			}{},
+			wantErr: `httplib: invalid validate tag of field A: invalid parameter of oneof: "high"`,
+		},
+		{
+			name: "oneof number out of range",
+			value: struct {
+				A uint8 `validate:"oneof=1 256"`
+			}{},
+			wantErr: `httplib: invalid validate tag of field A: invalid parameter of oneof: "256"`,
+		},
+		{
+			name: "invalid pattern",
+			value: struct {
+				A string `validate:"pattern=["`
+			}{},
+			wantErr: "httplib: invalid validate tag of field A: error parsing regexp: missing closing ]: `[`",
+		},
+		{
+			name: "dive for string",
+			value: struct {
+				A string `validate:"dive,required"`
+			}{},
+			wantErr: "httplib: invalid validate tag of field A: dive cannot be used for string",
+		},
+		{
+			name: "unknown field",
+			value: struct {
+				A string `validate:"eqfield=B"`
+			}{},
+			wantErr: `httplib: invalid validate tag of field A: eqfield refers to unknown field "B"`,
+		},
+		{
+			name: "different type",
+			value: struct {
+				A string `validate:"eqfield=B"`
+				B int
+			}{},
+			wantErr: `httplib: invalid validate tag of field A: eqfield refers to field "B" of different type int`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := httplib.Validate(tt.value)
+			assert.EqualError(t, err, tt.wantErr)
+
+			var validationErrs httplib.ValidationErrors
+			assert.False(t, errors.As(err, &validationErrs))
+		})
+	}
+}
+
+type validatedBody struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func TestWithValidation(t *testing.T) {
+	t.Run("DecodeJSONRequestBody", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":""}`))
+		_, err := httplib.DecodeJSONRequestBody[validatedBody](r, httplib.WithValidation())
+		assertValidationErrorsFunc("/name")(t, err)
+
+		r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":""}`))
+		_, err = httplib.DecodeJSONRequestBody[validatedBody](r)
+		assert.NoError(t, err)
+	})
+
+	t.Run("DecodeJSONLines", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{\"name\":\"a\"}\n{\"name\":\"\"}\n"))
+		_, err := collectItems(httplib.DecodeJSONLines[validatedBody](r, httplib.WithValidation()))
+		assertItemErrorFunc(1)(t, err)
+		assertValidationErrorsFunc("/name")(t, err)
+	})
+
+	t.Run("DecodeJSONArray", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"name":""}]`))
+		_, err := collectItems(httplib.DecodeJSONArray[validatedBody](r, httplib.WithValidation()))
+		assertItemErrorFunc(0)(t, err)
+		assertValidationErrorsFunc("/name")(t, err)
+	})
+
+	t.Run("DecodeFormRequestBody", func(t *testing.T) {
+		type form struct {
+			Name string `form:"name" json:"name" validate:"max=1"`
+		}
+		_, err := httplib.DecodeFormRequestBody[form](newFormRequest("name=ab"), httplib.WithValidation())
+		assertValidationErrorsFunc("/name")(t, err)
+	})
+
+	t.Run("DecodeQuery", func(t *testing.T) {
+		type query struct {
+			Page int `query:"page" json:"page" validate:"min=1"`
+		}
+		r := httptest.NewRequest(http.MethodGet, "/?page=0", nil)
+		_, err := httplib.DecodeQuery[query](r, httplib.WithValidation())
+		assertValidationErrorsFunc("/page")(t, err)
+	})
+
+	t.Run("Bind", func(t *testing.T) {
+		type request struct {
+			ID   int64         `path:"id" validate:"min=1"`
+			Body validatedBody `body:""`
+		}
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":""}`))
+		r.SetPathValue("id", "0")
+		_, err := httplib.Bind[request](r, httplib.WithValidation())
+		assertValidationErrorsFunc("/ID", "/name")(t, err)
+	})
+}